@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+)
+
+const interestUsage = `usage: tiny-wallet [flags] interest <command>
+
+commands:
+  accrue [YYYY-MM-DD] accrue daily interest for the day (default yesterday)
+                      and pay it out to accounts whose compounding period ends on the day`
+
+// runInterest executes an interest subcommand
+func runInterest(ctx context.Context, job *wallet.InterestJob, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "accrue" || len(args) > 2 {
+		return fmt.Errorf("%s", interestUsage)
+	}
+
+	day := time.Now().UTC().AddDate(0, 0, -1)
+	if len(args) == 2 {
+		d, err := time.Parse("2006-01-02", args[1])
+		if err != nil {
+			return fmt.Errorf("invalid date %q\n%s", args[1], interestUsage)
+		}
+		day = d
+	}
+
+	res, err := job.Run(ctx, day)
+	if res != nil {
+		fmt.Fprintf(out, "accrued %d accounts for %s\n", res.Accrued, res.Date.Format("2006-01-02"))
+		for _, p := range res.Payouts {
+			fmt.Fprintf(out, "paid %s %s to %s\n", p.Currency.FormatDecimal(p.Amount), string(p.Currency), p.AccToID)
+		}
+	}
+	return err
+}
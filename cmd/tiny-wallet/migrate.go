@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/internal/database"
+)
+
+const migrateUsage = `usage: tiny-wallet [flags] migrate <command>
+
+commands:
+  up       apply all pending migrations
+  down [N] revert N latest migrations (default 1)
+  status   print migration status`
+
+// runMigrate executes a migration subcommand
+func runMigrate(ctx context.Context, m *database.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migration command required\n%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mg := range applied {
+			fmt.Fprintf(out, "applied %d_%s\n", mg.Version, mg.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q\n%s", args[1], migrateUsage)
+			}
+			steps = n
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mg := range reverted {
+			fmt.Fprintf(out, "reverted %d_%s\n", mg.Version, mg.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+		return err
+
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, st := range status {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migration command %q\n%s", args[0], migrateUsage)
+	}
+}
@@ -4,28 +4,44 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/ilyakaznacheev/cleanenv"
 	wallet "github.com/ilyakaznacheev/tiny-wallet"
 	"github.com/ilyakaznacheev/tiny-wallet/internal/config"
 	"github.com/ilyakaznacheev/tiny-wallet/internal/database"
+	"github.com/ilyakaznacheev/tiny-wallet/internal/tracing"
+	"github.com/ilyakaznacheev/tiny-wallet/migrations"
+	"github.com/ilyakaznacheev/tiny-wallet/pb"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
 )
 
 type args struct {
 	Config string
+	// Command is a subcommand with arguments, e.g. `migrate up`
+	Command []string
 }
 
 // run application
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
-	errs := make(chan error)
+	errs := make(chan error, 3)
 	go func() {
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 		exit := <-c
 		cancel()
@@ -51,24 +67,311 @@ func main() {
 			"password=%s dbname=%s sslmode=%s",
 			conf.Database.Host, conf.Database.Port, conf.Database.Username, conf.Database.Password, conf.Database.Database, conf.Database.SSL)
 	}
-	db, err := database.NewPostgresClient(ctx, dbConfigURL, conf.Database.ConnectionWait)
+	tracer, err := newTracer(ctx, conf.Tracing, log.With(logger, "component", "tracing"))
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(2)
 	}
 
-	s := wallet.NewWalletService(db)
+	db, err := database.NewPostgresClient(ctx, dbConfigURL, conf.Database.ConnectionWait, tracer)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	schemaMigrations, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	migrator := db.NewMigrator(schemaMigrations)
+
+	if len(a.Command) > 0 {
+		switch a.Command[0] {
+		case "migrate":
+			err = runMigrate(ctx, migrator, a.Command[1:], os.Stdout)
+		case "interest":
+			var types []model.AccountType
+			if err = registerCurrencies(ctx, conf.Currency, db); err != nil {
+				break
+			}
+			if types, err = newAccountTypes(conf.Interest); err == nil {
+				job := wallet.NewInterestJob(db, types, conf.Interest.ExpenseAccount)
+				err = runInterest(ctx, job, a.Command[1:], os.Stdout)
+			}
+		default:
+			err = fmt.Errorf("unknown command %q", a.Command[0])
+		}
+		db.Close()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if conf.Database.AutoMigrate {
+		applied, err := migrator.Up(ctx)
+		for _, mg := range applied {
+			logger.Log("migration", fmt.Sprintf("%d_%s", mg.Version, mg.Name), "status", "applied")
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+
+	// custom currencies are registered first, so they can be used in the exchange rate table
+	if err := registerCurrencies(ctx, conf.Currency, db); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	rates, err := newRates(conf.Rates)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := seedRates(ctx, rates, db); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
 
-	h := wallet.MakeHTTPHandler(s, log.With(logger, "component", "HTTP"))
+	types, err := newAccountTypes(conf.Interest)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
 
-	address := fmt.Sprintf("%s:%s", conf.Server.Host, conf.Server.Port)
+	rounding, err := currency.ParseRoundingMode(conf.Currency.Rounding)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	metrics := wallet.NewPrometheusMetrics()
+
+	s := wallet.NewWalletService(wallet.NewInstrumentingDatabase(db, metrics),
+		wallet.WithBaseCurrency(rates.Base()),
+		wallet.WithAccountTypes(types),
+		wallet.WithRounding(rounding),
+	)
+	s = wallet.NewInstrumentingService(s, metrics)
+	events := wallet.NewPaymentEvents()
+	s = wallet.NewEventsService(s, events)
+	s = wallet.NewTracingService(s, tracer)
+
+	drainer := wallet.NewDrainer()
+
+	h := wallet.MakeHTTPHandler(s, log.With(logger, "component", "HTTP"),
+		wallet.WithDrainer(drainer),
+		wallet.WithMetrics(metrics),
+		wallet.WithTracer(tracer),
+		wallet.WithReadinessChecks(conf.Server.ReadinessTimeout,
+			wallet.ReadinessCheck{Name: "database", Check: db.Ping},
+			wallet.ReadinessCheck{Name: "migrations", Check: migrator.CheckVersion},
+		),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/", h)
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", conf.Server.Host, conf.Server.Port),
+		Handler: mux,
+	}
 
 	go func() {
-		logger.Log("transport", "HTTP", "addr", address)
-		errs <- http.ListenAndServe(address, h)
+		logger.Log("transport", "HTTP", "addr", srv.Addr)
+		errs <- srv.ListenAndServe()
 	}()
 
+	var grpcSrv *grpc.Server
+	if conf.Server.GRPCPort != "" {
+		grpcAddr := fmt.Sprintf("%s:%s", conf.Server.Host, conf.Server.GRPCPort)
+		ln, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		var grpcOpts []grpc.ServerOption
+		if tracer != nil {
+			grpcOpts = append(grpcOpts,
+				grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor(
+					otelgrpc.WithTracerProvider(tracer.Provider()), otelgrpc.WithPropagators(tracing.Propagator))),
+				grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor(
+					otelgrpc.WithTracerProvider(tracer.Provider()), otelgrpc.WithPropagators(tracing.Propagator))),
+			)
+		}
+		grpcSrv = grpc.NewServer(grpcOpts...)
+		pb.RegisterWalletServer(grpcSrv, wallet.MakeGRPCServer(
+			wallet.MakeServerEndpoints(s),
+			log.With(logger, "component", "gRPC"),
+			wallet.WithPaymentEvents(events),
+		))
+
+		go func() {
+			logger.Log("transport", "gRPC", "addr", grpcAddr)
+			errs <- grpcSrv.Serve(ln)
+		}()
+	}
+
 	logger.Log("exit", <-errs)
+
+	// refuse new requests and fail the readiness probe, then stop components in order:
+	// wait for in-flight requests, end event streams, flush remaining spans, close the database pool
+	drainer.Drain()
+	logger.Log("shutdown", "draining", "delay", conf.Server.DrainDelay, "timeout", conf.Server.DrainTimeout)
+
+	// keep the listeners open until the load balancer notices the failed readiness probe
+	time.Sleep(conf.Server.DrainDelay)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), conf.Server.DrainTimeout)
+	defer shutdownCancel()
+
+	steps := []stopStep{
+		{"HTTP", srv.Shutdown},
+		{"payment events", events.Close},
+	}
+	if grpcSrv != nil {
+		steps = append(steps, stopStep{"gRPC", func(ctx context.Context) error { return stopGRPC(ctx, grpcSrv) }})
+	}
+	steps = append(steps,
+		stopStep{"tracing", tracer.Shutdown},
+		stopStep{"database", func(context.Context) error { return db.Close() }},
+	)
+	shutdown(shutdownCtx, logger, steps...)
+}
+
+// stopGRPC stops the gRPC server gracefully.
+//
+// If running calls don't finish until the context is done, they are cancelled
+func stopGRPC(ctx context.Context, srv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// stopStep is a named shutdown action of an application component
+type stopStep struct {
+	component string
+	stop      func(ctx context.Context) error
+}
+
+// shutdown stops application components one by one in the given order.
+//
+// All steps share the same deadline of the context
+func shutdown(ctx context.Context, logger log.Logger, steps ...stopStep) {
+	for _, step := range steps {
+		begin := time.Now()
+		err := step.stop(ctx)
+		if err != nil {
+			logger.Log("shutdown", step.component, "err", err)
+			continue
+		}
+		logger.Log("shutdown", step.component, "took", time.Since(begin))
+	}
+}
+
+// newTracer creates a tracer with the span exporter set in the configuration.
+//
+// Returns nil tracer if the tracing is disabled
+func newTracer(ctx context.Context, conf config.TracingConfig, logger log.Logger) (*tracing.Tracer, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch conf.Exporter {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		exporter, err = tracing.NewStdoutExporter(os.Stdout)
+	case "otlp":
+		exporter, err = tracing.NewOTLPExporter(ctx, conf.OTLPEndpoint)
+	default:
+		return nil, fmt.Errorf("unknown span exporter %q", conf.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Log("err", err)
+	}))
+	otel.SetTextMapPropagator(tracing.Propagator)
+	return tracing.NewTracer(tracing.NewProvider(exporter, conf.ServiceName)), nil
+}
+
+// newRates creates an exchange rate table from the configuration
+func newRates(conf config.RatesConfig) (*currency.Rates, error) {
+	rates := make(map[currency.Currency]float64, len(conf.Rates))
+	for c, rate := range conf.Rates {
+		rates[currency.Currency(strings.ToUpper(c))] = rate
+	}
+	return currency.NewRates(currency.Currency(strings.ToUpper(conf.Base)), rates)
+}
+
+// seedRateTime is a time of exchange rates from the configuration.
+//
+// The rates are stored as the oldest ones, so any rate saved through the API is used since its time
+var seedRateTime = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// seedRates stores the exchange rate table from the configuration, so all conversions use the stored rates.
+//
+// Only currency pairs without stored rates are seeded, so the rates saved through the API are never overwritten
+func seedRates(ctx context.Context, rates *currency.Rates, db *database.PostgresClient) error {
+	if _, err := db.SeedRates(ctx, rates.List(seedRateTime)); err != nil {
+		return fmt.Errorf("can't save exchange rates from the configuration: %w", err)
+	}
+	return nil
+}
+
+// registerCurrencies registers custom currencies from the configuration and the database
+func registerCurrencies(ctx context.Context, conf config.CurrencyConfig, db *database.PostgresClient) error {
+	list := make([]model.CustomCurrency, 0, len(conf.Custom))
+	for _, c := range conf.Custom {
+		list = append(list, model.CustomCurrency{Code: strings.ToUpper(c.Code), Name: c.Name, Decimals: c.Decimals})
+	}
+	stored, err := db.GetCustomCurrencies(ctx)
+	if err != nil {
+		return fmt.Errorf("can't load custom currencies: %w", err)
+	}
+	for _, c := range append(list, stored...) {
+		if _, err := currency.Register(c.Code, c.Name, c.Decimals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newAccountTypes creates interest-bearing account types from the configuration ordered by name
+func newAccountTypes(conf config.InterestConfig) ([]model.AccountType, error) {
+	types := make([]model.AccountType, 0, len(conf.Types))
+	for name, t := range conf.Types {
+		at := model.AccountType{
+			Name:        name,
+			AnnualRate:  t.Rate,
+			Compounding: t.Compounding,
+		}
+		if err := at.Validate(); err != nil {
+			return nil, err
+		}
+		types = append(types, at)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types, nil
 }
 
 func parseArgs(conf interface{}) args {
@@ -86,6 +389,7 @@ func parseArgs(conf interface{}) args {
 	}
 
 	f.Parse(os.Args[1:])
+	a.Command = f.Args()
 
 	return a
 }
@@ -0,0 +1,787 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+// command runs walletctl subcommands against the service
+type command struct {
+	s         wallet.Service
+	out       io.Writer
+	format    string
+	formatSet bool
+}
+
+// run executes a subcommand with arguments
+func (c *command) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("command required\n%s", usage)
+	}
+
+	switch cmd := args[0] + " " + arg(args, 1); cmd {
+	case "accounts list":
+		return c.accountsList(ctx, args[2:])
+	case "accounts get":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: walletctl accounts get <id>")
+		}
+		return c.accountsGet(ctx, args[2])
+	case "accounts create":
+		return c.accountsCreate(ctx, args[2:])
+	case "accounts update":
+		return c.accountsUpdate(ctx, args[2:])
+	case "accounts import":
+		return c.accountsImport(ctx, args[2:])
+	case "accounts interest":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: walletctl accounts interest <id>")
+		}
+		return c.accountsInterest(ctx, args[2])
+	case "wallets create":
+		return c.walletsCreate(ctx, args[2:])
+	case "wallets get":
+		return c.walletsGet(ctx, args[2:])
+	case "wallets convert":
+		if len(args) != 6 {
+			return fmt.Errorf("usage: walletctl wallets convert <id> <from> <to> <amount>")
+		}
+		return c.walletsConvert(ctx, args[2], args[3], args[4], args[5])
+	case "payments list":
+		return c.paymentsList(ctx, args[2:])
+	case "payments send":
+		return c.paymentsSend(ctx, args[2:])
+	case "payments split":
+		return c.paymentsSplit(ctx, args[2:])
+	case "rates set":
+		if len(args) != 5 && len(args) != 6 {
+			return fmt.Errorf("usage: walletctl rates set <base> <quote> <rate> [time]")
+		}
+		return c.ratesSet(ctx, args[2:])
+	case "rates get":
+		return c.ratesGet(ctx, args[2:])
+	case "rates import":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: walletctl rates import <file>")
+		}
+		return c.ratesImport(ctx, args[2])
+	}
+
+	switch args[0] {
+	case "statement":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: walletctl statement <id>")
+		}
+		return c.statement(ctx, args[1])
+	case "export":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: walletctl export accounts|payments")
+		}
+		return c.export(ctx, args[1])
+	case "trial-balance":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: walletctl trial-balance")
+		}
+		return c.trialBalance(ctx)
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0]+" "+arg(args, 1), usage)
+}
+
+// arg returns the i-th argument or an empty string
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+func (c *command) accountsList(ctx context.Context, args []string) error {
+	var filter model.AccountFilter
+	f := newFlagSet("walletctl accounts list")
+	f.StringVar(&filter.OwnerID, "owner", "", "list accounts of the owner")
+	f.Var((*stringsFlag)(&filter.Labels), "label", "list accounts with the label, can be repeated")
+	f.StringVar(&filter.ConvertTo, "convert-to", "", "show balances converted into the currency with the latest exchange rate")
+	if err := f.Parse(args); err != nil || f.NArg() != 0 {
+		return fmt.Errorf("usage: walletctl accounts list [-owner <id>] [-label <label>]... [-convert-to <currency>]")
+	}
+
+	accounts, err := c.s.GetAllAccounts(ctx, filter)
+	if isNotFound(err) {
+		accounts = nil
+	} else if err != nil {
+		return err
+	}
+	return c.print(c.format, accountsTable(accounts))
+}
+
+func (c *command) accountsGet(ctx context.Context, id string) error {
+	accounts, err := c.getAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	a, ok := findAccount(accounts, id)
+	if !ok {
+		return fmt.Errorf("account %s not found", id)
+	}
+	return c.print(c.format, accountsTable([]model.Account{a}))
+}
+
+func (c *command) accountsCreate(ctx context.Context, args []string) error {
+	var info model.AccountInfo
+	f := newFlagSet("walletctl accounts create")
+	f.StringVar(&info.OwnerID, "owner", "", "account owner id")
+	f.StringVar(&info.DisplayName, "name", "", "account display name")
+	f.Var((*stringsFlag)(&info.Labels), "label", "account label, can be repeated")
+	f.Var((*mapFlag)(&info.Metadata), "meta", "metadata `key=value`, can be repeated")
+	f.StringVar(&info.Type, "type", "", "interest-bearing account type")
+	if err := f.Parse(args); err != nil || (f.NArg() != 2 && f.NArg() != 3) {
+		return fmt.Errorf("usage: walletctl accounts create [-owner <id>] [-name <name>] [-label <label>]... [-meta <key=value>]... [-type <type>] <id> <currency> [balance]")
+	}
+	id, curr, balance := f.Arg(0), f.Arg(1), f.Arg(2)
+
+	var amount float64
+	if balance != "" {
+		var err error
+		if amount, err = strconv.ParseFloat(balance, 64); err != nil {
+			return fmt.Errorf("invalid balance %q", balance)
+		}
+	}
+	a, err := c.s.PostAccount(ctx, id, amount, curr, info)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, accountsTable([]model.Account{*a}))
+}
+
+// accountsUpdate changes account info, only the fields set with flags are changed
+func (c *command) accountsUpdate(ctx context.Context, args []string) error {
+	var (
+		patch    model.AccountPatch
+		labels   []string
+		metadata map[string]string
+	)
+	f := newFlagSet("walletctl accounts update")
+	owner := f.String("owner", "", "account owner id")
+	name := f.String("name", "", "account display name")
+	f.Var((*stringsFlag)(&labels), "label", "account label, can be repeated, replaces all labels")
+	f.Var((*mapFlag)(&metadata), "meta", "metadata `key=value`, can be repeated, replaces all metadata")
+	clearLabels := f.Bool("clear-labels", false, "remove all labels")
+	accType := f.String("type", "", "interest-bearing account type, empty for a plain account")
+	if err := f.Parse(args); err != nil || f.NArg() != 1 {
+		return fmt.Errorf("usage: walletctl accounts update [-owner <id>] [-name <name>] [-label <label>]... [-clear-labels] [-meta <key=value>]... [-type <type>] <id>")
+	}
+
+	f.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "owner":
+			patch.OwnerID = owner
+		case "name":
+			patch.DisplayName = name
+		case "label":
+			patch.Labels = &labels
+		case "clear-labels":
+			if *clearLabels {
+				labels = append([]string{}, labels...)
+				patch.Labels = &labels
+			}
+		case "meta":
+			patch.Metadata = &metadata
+		case "type":
+			patch.Type = accType
+		}
+	})
+
+	a, err := c.s.PatchAccount(ctx, f.Arg(0), patch)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, accountsTable([]model.Account{*a}))
+}
+
+// accountsInterest prints daily interest accrued on the account
+func (c *command) accountsInterest(ctx context.Context, id string) error {
+	accruals, err := c.s.GetInterestAccruals(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, accrualsTable(accruals))
+}
+
+// accountsImport creates accounts from a CSV or JSON Lines file, the format is chosen by the file extension
+func (c *command) accountsImport(ctx context.Context, args []string) error {
+	f := newFlagSet("walletctl accounts import")
+	dryRun := f.Bool("dry-run", false, "validate the file without creating accounts")
+	if err := f.Parse(args); err != nil || f.NArg() != 1 {
+		return fmt.Errorf("usage: walletctl accounts import [-dry-run] <file>")
+	}
+	path := f.Arg(0)
+
+	format := "csv"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		format = "jsonl"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := wallet.DecodeAccountImport(file, format)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.s.ImportAccounts(ctx, rows, *dryRun)
+	if err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		t := table{header: []string{"line", "id", "error"}}
+		for _, e := range res.Errors {
+			t.rows = append(t.rows, []string{strconv.Itoa(e.Line), e.ID, e.Error})
+		}
+		if err := c.print(c.format, t); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d of %d rows rejected, no accounts were created", len(res.Errors), len(rows))
+	}
+	if res.DryRun {
+		fmt.Fprintf(c.out, "%d accounts are valid, nothing was created in dry-run mode\n", res.Imported)
+		return nil
+	}
+	fmt.Fprintf(c.out, "%d accounts created\n", res.Imported)
+	return nil
+}
+
+func (c *command) walletsCreate(ctx context.Context, args []string) error {
+	f := newFlagSet("walletctl wallets create")
+	owner := f.String("owner", "", "wallet owner id")
+	if err := f.Parse(args); err != nil || f.NArg() < 2 {
+		return fmt.Errorf("usage: walletctl wallets create [-owner <id>] <id> <currency>...")
+	}
+	w, err := c.s.PostWallet(ctx, f.Arg(0), *owner, f.Args()[1:])
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, walletTable(*w))
+}
+
+func (c *command) walletsGet(ctx context.Context, args []string) error {
+	f := newFlagSet("walletctl wallets get")
+	total := f.String("total", "", "currency of the wallet total, the service base currency by default")
+	if err := f.Parse(args); err != nil || f.NArg() != 1 {
+		return fmt.Errorf("usage: walletctl wallets get [-total <currency>] <id>")
+	}
+	w, err := c.s.GetWallet(ctx, f.Arg(0), *total)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, walletTable(*w))
+}
+
+func (c *command) walletsConvert(ctx context.Context, id, from, to, amount string) error {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", amount)
+	}
+	p, err := c.s.ConvertFunds(ctx, id, from, to, value)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, paymentsTable([]model.Payment{*p}))
+}
+
+// ratesSet saves an exchange rate, it is set now if the time is omitted
+func (c *command) ratesSet(ctx context.Context, args []string) error {
+	value, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		return fmt.Errorf("invalid rate %q", args[2])
+	}
+	rate := currency.Rate{Base: currency.Currency(args[0]), Quote: currency.Currency(args[1]), Rate: value}
+	if len(args) == 4 {
+		if rate.Time, err = parseTime(args[3]); err != nil {
+			return err
+		}
+	}
+	if _, err := c.s.PostRates(ctx, []currency.Rate{rate}); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "1 rate saved")
+	return nil
+}
+
+func (c *command) ratesGet(ctx context.Context, args []string) error {
+	f := newFlagSet("walletctl rates get")
+	at := f.String("at", "", "time of the rate in RFC 3339 or date format, now by default")
+	if err := f.Parse(args); err != nil || f.NArg() != 2 {
+		return fmt.Errorf("usage: walletctl rates get [-at <time>] <base> <quote>")
+	}
+	var t time.Time
+	if *at != "" {
+		var err error
+		if t, err = parseTime(*at); err != nil {
+			return err
+		}
+	}
+	r, err := c.s.GetRate(ctx, f.Arg(0), f.Arg(1), t)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, ratesTable([]currency.Rate{*r}))
+}
+
+// ratesImport saves exchange rates from a CSV or JSON file, the format is chosen by the file extension
+func (c *command) ratesImport(ctx context.Context, path string) error {
+	format := "csv"
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		format = "json"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := wallet.DecodeRates(file, format)
+	if err != nil {
+		return err
+	}
+
+	rates := make([]currency.Rate, 0, len(rows))
+	for _, r := range rows {
+		rate := currency.Rate{Base: r.Base, Quote: r.Quote, Rate: r.Rate}
+		if r.Time != nil {
+			rate.Time = *r.Time
+		}
+		rates = append(rates, rate)
+	}
+	saved, err := c.s.PostRates(ctx, rates)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%d rates saved\n", saved)
+	return nil
+}
+
+// parseTime parses an RFC 3339 time or a date
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", s); err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or date", s)
+		}
+	}
+	return t, nil
+}
+
+func (c *command) paymentsList(ctx context.Context, args []string) error {
+	var filter model.PaymentFilter
+	f := newFlagSet("walletctl payments list")
+	f.StringVar(&filter.Reference, "ref", "", "list payments with the reference")
+	f.StringVar(&filter.Search, "q", "", "list payments with the text in the reference or description")
+	f.Var((*mapFlag)(&filter.Metadata), "meta", "list payments with metadata `key=value`, can be repeated")
+	f.IntVar(&filter.GroupID, "group", 0, "list payments of the split payment")
+	if err := f.Parse(args); err != nil || f.NArg() != 0 {
+		return fmt.Errorf("usage: walletctl payments list [-ref <reference>] [-q <text>] [-meta <key=value>]... [-group <id>]")
+	}
+
+	payments, err := c.s.GetAllPayments(ctx, filter)
+	if isNotFound(err) {
+		payments = nil
+	} else if err != nil {
+		return err
+	}
+	return c.print(c.format, paymentsTable(payments))
+}
+
+func (c *command) paymentsSend(ctx context.Context, args []string) error {
+	var info model.PaymentInfo
+	f := newFlagSet("walletctl payments send")
+	f.StringVar(&info.Reference, "ref", "", "payment reference, unique among payments of the payer")
+	f.StringVar(&info.Description, "desc", "", "payment description")
+	f.Var((*mapFlag)(&info.Metadata), "meta", "metadata `key=value`, can be repeated")
+	if err := f.Parse(args); err != nil || f.NArg() != 3 {
+		return fmt.Errorf("usage: walletctl payments send [-ref <reference>] [-desc <description>] [-meta <key=value>]... <from> <to> <amount>")
+	}
+	from, to, amount := f.Arg(0), f.Arg(1), f.Arg(2)
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", amount)
+	}
+	p, err := c.s.PostPayment(ctx, from, to, value, info)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, paymentsTable([]model.Payment{*p}))
+}
+
+// paymentsSplit sends money from one account to many receivers.
+//
+// Each receiver is `<id>=<amount>` or `<id>=<percent>%`
+func (c *command) paymentsSplit(ctx context.Context, args []string) error {
+	var info model.PaymentInfo
+	f := newFlagSet("walletctl payments split")
+	f.StringVar(&info.Reference, "ref", "", "split payment reference, unique among split payments of the payer")
+	f.StringVar(&info.Description, "desc", "", "payment description")
+	f.Var((*mapFlag)(&info.Metadata), "meta", "metadata `key=value`, can be repeated")
+	if err := f.Parse(args); err != nil || f.NArg() < 3 {
+		return fmt.Errorf("usage: walletctl payments split [-ref <reference>] [-desc <description>] [-meta <key=value>]... <from> <amount> <to>=<amount>|<to>=<percent>%%...")
+	}
+	from, amount := f.Arg(0), f.Arg(1)
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", amount)
+	}
+	receivers := make([]model.SplitReceiver, 0, f.NArg()-2)
+	for _, a := range f.Args()[2:] {
+		r, err := parseSplitReceiver(a)
+		if err != nil {
+			return err
+		}
+		receivers = append(receivers, r)
+	}
+
+	g, err := c.s.PostSplitPayment(ctx, from, value, receivers, info)
+	if err != nil {
+		return err
+	}
+	return c.print(c.format, paymentsTable(g.Payments))
+}
+
+// parseSplitReceiver parses a split payment receiver `<id>=<amount>` or `<id>=<percent>%`
+func parseSplitReceiver(a string) (model.SplitReceiver, error) {
+	kv := strings.SplitN(a, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return model.SplitReceiver{}, fmt.Errorf("invalid receiver %q, expected <id>=<amount> or <id>=<percent>%%", a)
+	}
+	r := model.SplitReceiver{AccountID: kv[0]}
+	share, isPercent := strings.TrimSuffix(kv[1], "%"), strings.HasSuffix(kv[1], "%")
+	value, err := strconv.ParseFloat(share, 64)
+	if err != nil {
+		return model.SplitReceiver{}, fmt.Errorf("invalid receiver %q share %q", kv[0], kv[1])
+	}
+	if isPercent {
+		r.Percent = value
+	} else {
+		r.Amount = value
+	}
+	return r, nil
+}
+
+// statement prints account payments in historical order with the balance after each payment
+func (c *command) statement(ctx context.Context, id string) error {
+	accounts, err := c.getAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	a, ok := findAccount(accounts, id)
+	if !ok {
+		return fmt.Errorf("account %s not found", id)
+	}
+	payments, err := c.getPayments(ctx)
+	if err != nil {
+		return err
+	}
+
+	var own []model.Payment
+	for _, p := range payments {
+		if p.AccFromID == id || p.AccToID == id {
+			own = append(own, p)
+		}
+	}
+	sort.SliceStable(own, func(i, j int) bool {
+		return own[i].DateTime.Before(own[j].DateTime)
+	})
+
+	// restore the balance history backwards from the current balance
+	t := table{header: []string{"time", "counterparty", "amount", "balance", "currency"}}
+	t.rows = make([][]string, len(own))
+	balance := a.Balance
+	for i := len(own) - 1; i >= 0; i-- {
+		p := own[i]
+		amount, counterparty := p.Amount, p.AccFromID
+		if p.AccFromID == id {
+			amount, counterparty = p.Amount.Neg(), p.AccToID
+		}
+		t.rows[i] = []string{
+			p.DateTime.Format(time.RFC3339),
+			counterparty,
+			formatAmount(amount, a.Currency),
+			formatAmount(balance, a.Currency),
+			string(a.Currency),
+		}
+		if balance, err = balance.Sub(amount); err != nil {
+			return fmt.Errorf("balance of %s before payment %d: %w", id, p.ID, err)
+		}
+	}
+	return c.print(c.format, t)
+}
+
+// export prints all accounts or payments, CSV is used unless the output format is set explicitly
+// trialBalance prints ledger account sums and fails if the ledger isn't balanced
+func (c *command) trialBalance(ctx context.Context) error {
+	tb, err := c.s.GetTrialBalance(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.print(c.format, trialBalanceTable(*tb)); err != nil {
+		return err
+	}
+	if !tb.Balanced {
+		return fmt.Errorf("ledger is not balanced")
+	}
+	return nil
+}
+
+func (c *command) export(ctx context.Context, what string) error {
+	format := formatCSV
+	if c.formatSet {
+		format = c.format
+	}
+
+	// exports contain exact amounts with all decimal places of the currency
+	switch what {
+	case "accounts":
+		t := table{header: []string{"id", "balance", "currency", "currency_name"}}
+		err := c.s.ExportAccounts(ctx, func(a model.Account) error {
+			t.rows = append(t.rows, []string{a.ID, a.Currency.FormatDecimal(a.Balance), string(a.Currency), a.Currency.String()})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return c.print(format, t)
+	case "payments":
+		t := table{header: []string{"id", "time", "from", "to", "amount", "currency", "currency_name", "amount_to", "currency_to"}}
+		err := c.s.ExportPayments(ctx, nil, nil, func(p model.Payment) error {
+			var amountTo string
+			if p.ToCurrency != "" {
+				amountTo = p.ToCurrency.FormatDecimal(p.ToAmount)
+			}
+			t.rows = append(t.rows, []string{
+				strconv.Itoa(p.ID),
+				p.DateTime.Format(time.RFC3339),
+				p.AccFromID,
+				p.AccToID,
+				p.Currency.FormatDecimal(p.Amount),
+				string(p.Currency),
+				p.Currency.String(),
+				amountTo,
+				string(p.ToCurrency),
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return c.print(format, t)
+	default:
+		return fmt.Errorf("unknown export %q, expected accounts or payments", what)
+	}
+}
+
+// getAccounts returns all accounts. The service responds 404 if there are no accounts
+func (c *command) getAccounts(ctx context.Context) ([]model.Account, error) {
+	accounts, err := c.s.GetAllAccounts(ctx, model.AccountFilter{})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	return accounts, err
+}
+
+// getPayments returns all payments. The service responds 404 if there are no payments
+func (c *command) getPayments(ctx context.Context) ([]model.Payment, error) {
+	payments, err := c.s.GetAllPayments(ctx, model.PaymentFilter{})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	return payments, err
+}
+
+func (c *command) print(format string, t table) error {
+	p, err := newPrinter(format)
+	if err != nil {
+		return err
+	}
+	return p(c.out, t)
+}
+
+// newFlagSet creates a subcommand flag set, parse errors are reported with the subcommand usage
+func newFlagSet(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	return f
+}
+
+// stringsFlag is a repeatable string flag
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// mapFlag is a repeatable key=value flag
+type mapFlag map[string]string
+
+func (f *mapFlag) String() string {
+	pairs := make([]string, 0, len(*f))
+	for k, v := range *f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f *mapFlag) Set(v string) error {
+	kv := strings.SplitN(v, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("invalid metadata %q, expected key=value", v)
+	}
+	if *f == nil {
+		*f = make(mapFlag)
+	}
+	(*f)[kv[0]] = kv[1]
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var httpErr wallet.HTTPError
+	return xerrors.As(err, &httpErr) && httpErr.Code() == http.StatusNotFound
+}
+
+func findAccount(accounts []model.Account, id string) (model.Account, bool) {
+	for _, a := range accounts {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return model.Account{}, false
+}
+
+// accountsTable lists accounts, converted balance columns are added if any account has one
+func accountsTable(accounts []model.Account) table {
+	t := table{header: []string{"id", "balance", "currency"}}
+	converted := false
+	for _, a := range accounts {
+		converted = converted || a.Converted != nil
+	}
+	if converted {
+		t.header = append(t.header, "converted", "converted_currency", "rate")
+	}
+	for _, a := range accounts {
+		row := []string{a.ID, formatAmount(a.Balance, a.Currency), string(a.Currency)}
+		if converted && a.Converted != nil {
+			row = append(row,
+				formatAmount(a.Converted.Balance, a.Converted.Currency),
+				string(a.Converted.Currency),
+				strconv.FormatFloat(a.Converted.Rate.Rate, 'f', -1, 64),
+			)
+		} else if converted {
+			row = append(row, "", "", "")
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+func ratesTable(rates []currency.Rate) table {
+	t := table{header: []string{"base", "quote", "rate", "time"}}
+	for _, r := range rates {
+		t.rows = append(t.rows, []string{
+			string(r.Base),
+			string(r.Quote),
+			strconv.FormatFloat(r.Rate, 'f', -1, 64),
+			r.Time.Format(time.RFC3339),
+		})
+	}
+	return t
+}
+
+// walletTable lists wallet pockets followed by the wallet total
+func walletTable(w model.Wallet) table {
+	t := accountsTable(w.Pockets)
+	t.rows = append(t.rows, []string{"total", formatAmount(w.Total, w.TotalCurrency), string(w.TotalCurrency)})
+	return t
+}
+
+func accrualsTable(accruals []model.InterestAccrual) table {
+	t := table{header: []string{"date", "balance", "rate", "interest", "currency", "paid"}}
+	for _, a := range accruals {
+		paid := ""
+		if a.PaymentID != 0 {
+			paid = strconv.Itoa(a.PaymentID)
+		}
+		t.rows = append(t.rows, []string{
+			a.Date.Format("2006-01-02"),
+			formatAmount(a.Balance, a.Currency),
+			strconv.FormatFloat(a.Rate, 'f', -1, 64),
+			formatAmount(a.Amount, a.Currency),
+			string(a.Currency),
+			paid,
+		})
+	}
+	return t
+}
+
+// trialBalanceTable lists ledger accounts followed by totals in each currency
+func trialBalanceTable(tb model.TrialBalance) table {
+	t := table{header: []string{"account", "currency", "debit", "credit", "balance"}}
+	for _, a := range tb.Accounts {
+		t.rows = append(t.rows, []string{
+			a.AccountID,
+			string(a.Currency),
+			formatAmount(a.Debit, a.Currency),
+			formatAmount(a.Credit, a.Currency),
+			formatAmount(a.Balance, a.Currency),
+		})
+	}
+	for _, tt := range tb.Totals {
+		// both totals are non-negative, so the difference is always in the safe range
+		balance, _ := tt.Credit.Sub(tt.Debit)
+		t.rows = append(t.rows, []string{
+			"total",
+			string(tt.Currency),
+			formatAmount(tt.Debit, tt.Currency),
+			formatAmount(tt.Credit, tt.Currency),
+			formatAmount(balance, tt.Currency),
+		})
+	}
+	return t
+}
+
+func paymentsTable(payments []model.Payment) table {
+	t := table{header: []string{"time", "from", "to", "amount", "currency"}}
+	for _, p := range payments {
+		t.rows = append(t.rows, []string{
+			p.DateTime.Format(time.RFC3339),
+			p.AccFromID,
+			p.AccToID,
+			formatAmount(p.Amount, p.Currency),
+			string(p.Currency),
+		})
+	}
+	return t
+}
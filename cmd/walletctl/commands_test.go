@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+type testService struct {
+	accounts []model.Account
+	payments []model.Payment
+}
+
+func (s *testService) GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error) {
+	var res []model.Payment
+	for _, p := range s.payments {
+		if filter.Reference == "" || p.Reference == filter.Reference {
+			res = append(res, p)
+		}
+	}
+	if len(res) == 0 {
+		return nil, wallet.NewErrHTTPStatusf(http.StatusNotFound, nil, "no payment found")
+	}
+	return res, nil
+}
+
+func (s *testService) GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error) {
+	var res []model.Account
+	for _, a := range s.accounts {
+		if filter.OwnerID != "" && a.OwnerID != filter.OwnerID {
+			continue
+		}
+		if filter.ConvertTo != "" {
+			to := currency.Currency(filter.ConvertTo)
+			a.Converted = &model.ConvertedBalance{
+				Balance:  toInternal(currency.ConvertToExternal(a.Balance, a.Currency)*0.9, to),
+				Currency: to,
+				Rate:     currency.Rate{Rate: 0.9},
+			}
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+func (s *testService) PostRates(ctx context.Context, rates []currency.Rate) (int, error) {
+	return len(rates), nil
+}
+
+func (s *testService) GetRate(ctx context.Context, base, quote string, at time.Time) (*currency.Rate, error) {
+	if at.IsZero() {
+		at = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &currency.Rate{Base: currency.Currency(base), Quote: currency.Currency(quote), Rate: 1.1, Time: at}, nil
+}
+
+func (s *testService) PatchAccount(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error) {
+	a := model.Account{ID: id, Currency: currency.USD}
+	if patch.OwnerID != nil {
+		a.OwnerID = *patch.OwnerID
+	}
+	if patch.Labels == nil || patch.Metadata == nil {
+		return nil, wallet.NewErrHTTPStatusf(http.StatusBadRequest, nil, "labels and metadata expected")
+	}
+	return &a, nil
+}
+
+func (s *testService) PostPayment(ctx context.Context, from, to string, amount float64, info model.PaymentInfo) (*model.Payment, error) {
+	return &model.Payment{
+		AccFromID:   from,
+		AccToID:     to,
+		DateTime:    time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC),
+		Amount:      toInternal(amount, currency.USD),
+		Currency:    currency.USD,
+		PaymentInfo: info,
+	}, nil
+}
+
+func (s *testService) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error {
+	for _, p := range s.payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testService) ExportAccounts(ctx context.Context, fn func(model.Account) error) error {
+	for _, a := range s.accounts {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testService) ImportAccounts(ctx context.Context, rows []model.AccountImport, dryRun bool) (*model.ImportResult, error) {
+	res := &model.ImportResult{DryRun: dryRun}
+	for _, r := range rows {
+		if _, err := currency.AtoCurrency(r.Currency); err != nil {
+			res.Errors = append(res.Errors, model.ImportError{Line: r.Line, ID: r.ID, Error: err.Error()})
+		}
+	}
+	if len(res.Errors) == 0 {
+		res.Imported = len(rows)
+	}
+	return res, nil
+}
+
+func (s *testService) PostWallet(ctx context.Context, id, ownerID string, currencies []string) (*model.Wallet, error) {
+	w := &model.Wallet{ID: id, OwnerID: ownerID, TotalCurrency: currency.USD}
+	for _, c := range currencies {
+		w.Pockets = append(w.Pockets, model.Account{ID: id + "/" + c, Currency: currency.Currency(c)})
+	}
+	return w, nil
+}
+
+func (s *testService) GetWallet(ctx context.Context, id, totalCurrency string) (*model.Wallet, error) {
+	return &model.Wallet{
+		ID: id,
+		Pockets: []model.Account{
+			{ID: id + "/EUR", Balance: currency.NewAmount(1000), Currency: currency.EUR},
+			{ID: id + "/USD", Balance: currency.NewAmount(500), Currency: currency.USD},
+		},
+		Total:         currency.NewAmount(1600),
+		TotalCurrency: currency.USD,
+	}, nil
+}
+
+func (s *testService) ConvertFunds(ctx context.Context, walletID, from, to string, amount float64) (*model.Payment, error) {
+	return &model.Payment{
+		AccFromID:  walletID + "/" + from,
+		AccToID:    walletID + "/" + to,
+		DateTime:   time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC),
+		Amount:     toInternal(amount, currency.Currency(from)),
+		Currency:   currency.Currency(from),
+		ToAmount:   toInternal(amount*1.1, currency.Currency(to)),
+		ToCurrency: currency.Currency(to),
+	}, nil
+}
+
+func (s *testService) PostSplitPayment(ctx context.Context, from string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error) {
+	g := model.PaymentGroup{ID: 1, AccFromID: from, Currency: currency.USD}
+	for _, r := range receivers {
+		g.Payments = append(g.Payments, model.Payment{
+			AccFromID: from,
+			AccToID:   r.AccountID,
+			DateTime:  time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC),
+			Amount:    toInternal(r.Amount+amount*r.Percent/100, currency.USD),
+			Currency:  currency.USD,
+			GroupID:   g.ID,
+		})
+	}
+	return &g, nil
+}
+
+func (s *testService) GetTrialBalance(ctx context.Context) (*model.TrialBalance, error) {
+	tb := model.NewTrialBalance([]model.TrialBalanceAccount{
+		{AccountID: "@suspense/USD", Currency: currency.USD, Debit: currency.NewAmount(10000)},
+		{AccountID: "alice", Currency: currency.USD, Debit: currency.NewAmount(2450), Credit: currency.NewAmount(10000)},
+		{AccountID: "bob", Currency: currency.USD, Credit: currency.NewAmount(2450)},
+	})
+	return &tb, nil
+}
+
+func (s *testService) GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error) {
+	return []model.InterestAccrual{
+		{AccountID: accountID, Date: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC), Balance: currency.NewAmount(100000), Rate: 0.0365, Amount: currency.NewAmount(10), Currency: currency.USD, PaymentID: 7},
+		{AccountID: accountID, Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Balance: currency.NewAmount(100010), Rate: 0.0365, Amount: currency.NewAmount(10), Currency: currency.USD},
+	}, nil
+}
+
+func (s *testService) PostAccount(ctx context.Context, id string, balance float64, curr string, info model.AccountInfo) (*model.Account, error) {
+	return &model.Account{ID: id, Balance: toInternal(balance, currency.BHD), Currency: currency.BHD}, nil
+}
+
+func TestCommandRun(t *testing.T) {
+	s := &testService{
+		accounts: []model.Account{
+			{ID: "alice", Balance: currency.NewAmount(7550), Currency: currency.USD},
+			{ID: "bob", Balance: currency.NewAmount(12450), Currency: currency.USD, AccountInfo: model.AccountInfo{OwnerID: "customer-1"}},
+		},
+		payments: []model.Payment{
+			{AccFromID: "alice", AccToID: "bob", DateTime: time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC), Amount: currency.NewAmount(1000), Currency: currency.USD},
+			{AccFromID: "bob", AccToID: "alice", DateTime: time.Date(2019, 5, 2, 10, 0, 0, 0, time.UTC), Amount: currency.NewAmount(550), Currency: currency.USD, PaymentInfo: model.PaymentInfo{Reference: "refund-1"}},
+		},
+	}
+	tests := []struct {
+		name      string
+		service   wallet.Service
+		args      []string
+		format    string
+		formatSet bool
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:   "accounts list",
+			args:   []string{"accounts", "list"},
+			format: formatTable,
+			want:   "ID     BALANCE  CURRENCY\nalice  75.5     USD\nbob    124.5    USD\n",
+		},
+		{
+			name:   "accounts list by owner",
+			args:   []string{"accounts", "list", "-owner", "customer-1"},
+			format: formatCSV,
+			want:   "id,balance,currency\nbob,124.5,USD\n",
+		},
+		{
+			name:   "accounts list converted",
+			args:   []string{"accounts", "list", "-convert-to", "EUR"},
+			format: formatCSV,
+			want:   "id,balance,currency,converted,converted_currency,rate\nalice,75.5,USD,67.95,EUR,0.9\nbob,124.5,USD,112.05,EUR,0.9\n",
+		},
+		{
+			name:   "accounts update",
+			args:   []string{"accounts", "update", "-owner", "customer-2", "-clear-labels", "-meta", "tier=gold", "carol"},
+			format: formatCSV,
+			want:   "id,balance,currency\ncarol,0,USD\n",
+		},
+		{
+			name:    "accounts update invalid metadata",
+			args:    []string{"accounts", "update", "-meta", "tier", "carol"},
+			format:  formatCSV,
+			wantErr: true,
+		},
+		{
+			name:   "accounts get json",
+			args:   []string{"accounts", "get", "bob"},
+			format: formatJSON,
+			want:   "[\n  {\n    \"balance\": \"124.5\",\n    \"currency\": \"USD\",\n    \"id\": \"bob\"\n  }\n]\n",
+		},
+		{
+			name:    "accounts get unknown",
+			args:    []string{"accounts", "get", "carol"},
+			format:  formatTable,
+			wantErr: true,
+		},
+		{
+			name:   "accounts create",
+			args:   []string{"accounts", "create", "-owner", "customer-2", "-label", "vip", "carol", "BHD", "1.5"},
+			format: formatCSV,
+			want:   "id,balance,currency\ncarol,1.5,BHD\n",
+		},
+		{
+			name:   "payments send",
+			args:   []string{"payments", "send", "alice", "bob", "2.25"},
+			format: formatCSV,
+			want:   "time,from,to,amount,currency\n2019-05-01T10:00:00Z,alice,bob,2.25,USD\n",
+		},
+		{
+			name:   "wallets create",
+			args:   []string{"wallets", "create", "-owner", "customer-1", "carol", "USD", "EUR"},
+			format: formatCSV,
+			want:   "id,balance,currency\ncarol/USD,0,USD\ncarol/EUR,0,EUR\ntotal,0,USD\n",
+		},
+		{
+			name:   "wallets get",
+			args:   []string{"wallets", "get", "carol"},
+			format: formatCSV,
+			want:   "id,balance,currency\ncarol/EUR,10,EUR\ncarol/USD,5,USD\ntotal,16,USD\n",
+		},
+		{
+			name:   "wallets convert",
+			args:   []string{"wallets", "convert", "carol", "USD", "EUR", "10"},
+			format: formatCSV,
+			want:   "time,from,to,amount,currency\n2019-05-01T10:00:00Z,carol/USD,carol/EUR,10,USD\n",
+		},
+		{
+			name:   "accounts interest",
+			args:   []string{"accounts", "interest", "alice"},
+			format: formatCSV,
+			want:   "date,balance,rate,interest,currency,paid\n2024-01-30,1000,0.0365,0.1,USD,7\n2024-01-31,1000.1,0.0365,0.1,USD,\n",
+		},
+		{
+			name:   "trial balance",
+			args:   []string{"trial-balance"},
+			format: formatCSV,
+			want:   "account,currency,debit,credit,balance\n@suspense/USD,USD,100,0,-100\nalice,USD,24.5,100,75.5\nbob,USD,0,24.5,24.5\ntotal,USD,124.5,124.5,0\n",
+		},
+		{
+			name:   "payments send with reference",
+			args:   []string{"payments", "send", "-ref", "invoice-42", "-desc", "May rent", "-meta", "order=42", "alice", "bob", "2.25"},
+			format: formatCSV,
+			want:   "time,from,to,amount,currency\n2019-05-01T10:00:00Z,alice,bob,2.25,USD\n",
+		},
+		{
+			name:   "payments split",
+			args:   []string{"payments", "split", "-ref", "order-1", "alice", "10", "bob=9", "carol=10%"},
+			format: formatCSV,
+			want:   "time,from,to,amount,currency\n2019-05-01T10:00:00Z,alice,bob,9,USD\n2019-05-01T10:00:00Z,alice,carol,1,USD\n",
+		},
+		{
+			name:    "payments split invalid receiver",
+			args:    []string{"payments", "split", "alice", "10", "bob"},
+			format:  formatCSV,
+			wantErr: true,
+		},
+		{
+			name:   "payments list by reference",
+			args:   []string{"payments", "list", "-ref", "refund-1"},
+			format: formatCSV,
+			want:   "time,from,to,amount,currency\n2019-05-02T10:00:00Z,bob,alice,5.5,USD\n",
+		},
+		{
+			name:   "statement",
+			args:   []string{"statement", "alice"},
+			format: formatCSV,
+			want:   "time,counterparty,amount,balance,currency\n2019-05-01T10:00:00Z,bob,-10,70,USD\n2019-05-02T10:00:00Z,bob,5.5,75.5,USD\n",
+		},
+		{
+			name:    "payments list empty",
+			service: &testService{},
+			args:    []string{"payments", "list"},
+			format:  formatCSV,
+			want:    "time,from,to,amount,currency\n",
+		},
+		{
+			name:   "export csv by default",
+			args:   []string{"export", "accounts"},
+			format: formatTable,
+			want:   "id,balance,currency,currency_name\nalice,75.50,USD,US Dollar\nbob,124.50,USD,US Dollar\n",
+		},
+		{
+			name:   "accounts import dry run",
+			args:   []string{"accounts", "import", "-dry-run", "testdata/accounts.csv"},
+			format: formatTable,
+			want:   "2 accounts are valid, nothing was created in dry-run mode\n",
+		},
+		{
+			name:    "accounts import invalid",
+			args:    []string{"accounts", "import", "testdata/invalid.jsonl"},
+			format:  formatTable,
+			wantErr: true,
+		},
+		{
+			name:   "rates set",
+			args:   []string{"rates", "set", "EUR", "USD", "1.1", "2024-01-31"},
+			format: formatTable,
+			want:   "1 rate saved\n",
+		},
+		{
+			name:    "rates set invalid time",
+			args:    []string{"rates", "set", "EUR", "USD", "1.1", "yesterday"},
+			format:  formatTable,
+			wantErr: true,
+		},
+		{
+			name:   "rates get",
+			args:   []string{"rates", "get", "-at", "2024-01-31", "EUR", "USD"},
+			format: formatCSV,
+			want:   "base,quote,rate,time\nEUR,USD,1.1,2024-01-31T00:00:00Z\n",
+		},
+		{
+			name:   "rates import",
+			args:   []string{"rates", "import", "testdata/rates.csv"},
+			format: formatTable,
+			want:   "3 rates saved\n",
+		},
+		{
+			name:    "unknown format",
+			args:    []string{"accounts", "list"},
+			format:  "xml",
+			wantErr: true,
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"accounts", "delete"},
+			format:  formatTable,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := &command{s: s, out: &out, format: tt.format, formatSet: tt.formatSet}
+			if tt.service != nil {
+				c.s = tt.service
+			}
+			err := c.run(context.Background(), tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if got := out.String(); !tt.wantErr && got != tt.want {
+				t.Errorf("wrong output %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// toInternal converts a test amount into the lowest currency units
+func toInternal(m float64, c currency.Currency) currency.Amount {
+	res, err := currency.ConvertToInternal(m, c, currency.RoundHalfEven)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
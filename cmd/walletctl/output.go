@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// table is a command result with a header and rows of values
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// printer writes a table in a certain format
+type printer func(w io.Writer, t table) error
+
+// newPrinter returns a printer of the output format
+func newPrinter(format string) (printer, error) {
+	switch format {
+	case formatTable:
+		return printTable, nil
+	case formatJSON:
+		return printJSON, nil
+	case formatCSV:
+		return printCSV, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected table, json or csv", format)
+	}
+}
+
+// printTable writes a human-readable table with aligned columns
+func printTable(w io.Writer, t table) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.header, "\t")))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printJSON writes a JSON array of objects with header fields
+func printJSON(w io.Writer, t table) error {
+	res := make([]map[string]string, 0, len(t.rows))
+	for _, row := range t.rows {
+		obj := make(map[string]string, len(t.header))
+		for i, h := range t.header {
+			obj[h] = row[i]
+		}
+		res = append(res, obj)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+// printCSV writes CSV records with a header line
+func printCSV(w io.Writer, t table) error {
+	cw := csv.NewWriter(w)
+	cw.Write(t.header)
+	cw.WriteAll(t.rows)
+	return cw.Error()
+}
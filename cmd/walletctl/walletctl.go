@@ -0,0 +1,169 @@
+// walletctl is a command-line client of the wallet service
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/ilyakaznacheev/tiny-wallet/internal/config"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/client"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+const usage = `usage: walletctl [flags] <command>
+
+commands:
+  accounts list [-owner <id>] [-label <label>]... [-convert-to <currency>]
+                                          list all accounts or accounts of the owner with the labels,
+                                          optionally with balances converted into the currency
+  accounts get <id>                       show an account
+  accounts create [-owner <id>] [-name <name>] [-label <label>]... [-meta <key=value>]...
+                  [-type <type>] <id> <currency> [balance]
+                                          create an account
+  accounts update [-owner <id>] [-name <name>] [-label <label>]... [-clear-labels]
+                  [-meta <key=value>]... [-type <type>] <id>
+                                          change the account owner, name, labels, metadata or type
+  accounts interest <id>                  show daily interest accrued on the account
+  accounts import [-dry-run] <file>       create accounts from a CSV or JSON Lines (.jsonl) file
+                                          with id, currency and balance columns
+  wallets create [-owner <id>] <id> <currency>...
+                                          create a wallet with a pocket in each currency
+  wallets get [-total <currency>] <id>    show wallet pockets and the total balance
+  wallets convert <id> <from> <to> <amount>
+                                          move money between wallet pockets
+  payments list [-ref <reference>] [-q <text>] [-meta <key=value>]... [-group <id>]
+                                          list all payments or payments matching the filters
+  payments send [-ref <reference>] [-desc <description>] [-meta <key=value>]...
+                <from> <to> <amount>      send money from one account to another
+  payments split [-ref <reference>] [-desc <description>] [-meta <key=value>]...
+                 <from> <amount> <to>=<amount>|<to>=<percent>%...
+                                          split a payment between receivers, percentages
+                                          share the amount left after fixed amounts
+  rates set <base> <quote> <rate> [time]
+                                          save an exchange rate: 1 base costs rate quote, set now by default
+  rates get [-at <time>] <base> <quote>   show the latest exchange rate at the time
+  rates import <file>                     save exchange rates from a CSV or JSON (.json) file
+                                          with base, quote, rate and optional time columns
+  statement <id>                          show payments of an account with the running balance
+  export accounts|payments                export all accounts or payments, CSV by default
+  trial-balance                           show ledger account sums, fails if the ledger isn't balanced
+
+flags:`
+
+type args struct {
+	Config  string
+	Output  string
+	Command []string
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var conf config.CtlConfig
+
+	a := parseArgs(&conf)
+	if err := readConfig(a.Config, &conf); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if a.Output != "" {
+		conf.Output = a.Output
+	}
+
+	c, err := client.New(conf.URL,
+		client.WithTimeout(conf.Timeout),
+		client.WithHTTPClient(&http.Client{
+			Transport: &authTransport{conf, http.DefaultTransport},
+		}),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	cmd := &command{
+		s:      c,
+		out:    os.Stdout,
+		format: conf.Output,
+		// export has its own default format
+		formatSet: a.Output != "",
+	}
+	if err := cmd.run(ctx, a.Command); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// readConfig reads the configuration file if it exists and environment variables
+func readConfig(path string, conf *config.CtlConfig) error {
+	if _, err := os.Stat(path); err == nil {
+		return cleanenv.ReadConfig(path, conf)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return cleanenv.ReadEnv(conf)
+}
+
+// authTransport is an HTTP transport that adds credentials to each request
+type authTransport struct {
+	conf config.CtlConfig
+	next http.RoundTripper
+}
+
+// RoundTrip adds the Authorization header and sends the request
+func (t *authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	switch {
+	case t.conf.Token != "":
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "Bearer "+t.conf.Token)
+	case t.conf.Username != "":
+		r = r.Clone(r.Context())
+		r.SetBasicAuth(t.conf.Username, t.conf.Password)
+	}
+	return t.next.RoundTrip(r)
+}
+
+func parseArgs(conf interface{}) args {
+	var a args
+
+	defaultConfig := "walletctl.yml"
+	if home, err := os.UserHomeDir(); err == nil {
+		defaultConfig = home + "/.config/walletctl.yml"
+	}
+
+	f := flag.NewFlagSet("walletctl", flag.ExitOnError)
+	f.StringVar(&a.Config, "c", defaultConfig, "path to configuration file")
+	f.StringVar(&a.Output, "o", "", "output format: table, json or csv")
+
+	f.Usage = func() {
+		fmt.Fprintln(f.Output(), usage)
+		f.PrintDefaults()
+		envHelp, _ := cleanenv.GetDescription(conf, nil)
+		fmt.Fprintln(f.Output())
+		fmt.Fprintln(f.Output(), envHelp)
+	}
+
+	f.Parse(os.Args[1:])
+	a.Command = f.Args()
+	if len(a.Command) == 0 {
+		f.Usage()
+		os.Exit(2)
+	}
+
+	return a
+}
+
+// formatAmount formats an integer amount, negative amounts are prefixed with minus
+func formatAmount(amount currency.Amount, c currency.Currency) string {
+	if amount.Sign() < 0 {
+		return "-" + c.FormatAmount(amount.Neg())
+	}
+	return c.FormatAmount(amount)
+}
@@ -40,16 +40,16 @@ To run the service call the entry-point CLI app
 
 You can just run it with
 
-	go run cmd/tiny-wallet/wallet.go
+	go run ./cmd/tiny-wallet
 
 or compile into an executable with
 
-	go build cmd/tiny-wallet/wallet.go
+	go build -o wallet ./cmd/tiny-wallet
 
 Usage and help
 
 To get help run the app with -h flag. You will get a list of command-line arguments and a list of used environment variables.
 
-	go run cmd/tiny-wallet/wallet.go -h
+	go run ./cmd/tiny-wallet -h
 */
 package wallet
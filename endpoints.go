@@ -2,11 +2,16 @@ package wallet
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
 )
 
 const (
@@ -22,8 +27,32 @@ type Endpoints struct {
 	GetAllAccountsEndpoint endpoint.Endpoint
 	// PostPayment processes a new payment
 	PostPayment endpoint.Endpoint
+	// PostSplitPayment processes a payment from one payer to many receivers
+	PostSplitPayment endpoint.Endpoint
 	// PostAccount creates a new account
 	PostAccount endpoint.Endpoint
+	// PatchAccount changes account info
+	PatchAccount endpoint.Endpoint
+	// ExportPaymentsEndpoint streams payments in CSV or JSON Lines format
+	ExportPaymentsEndpoint endpoint.Endpoint
+	// ExportAccountsEndpoint streams accounts in CSV or JSON Lines format
+	ExportAccountsEndpoint endpoint.Endpoint
+	// ImportAccountsEndpoint creates accounts in bulk from CSV or JSON Lines
+	ImportAccountsEndpoint endpoint.Endpoint
+	// PostWallet creates a new multi-currency wallet
+	PostWallet endpoint.Endpoint
+	// GetWallet returns a wallet with its pocket balances
+	GetWallet endpoint.Endpoint
+	// ConvertFunds moves money between wallet pockets
+	ConvertFunds endpoint.Endpoint
+	// GetTrialBalance returns sums of ledger accounts
+	GetTrialBalance endpoint.Endpoint
+	// GetInterestAccruals returns interest accrual history of an account
+	GetInterestAccruals endpoint.Endpoint
+	// PostRates saves historical exchange rates
+	PostRates endpoint.Endpoint
+	// GetRate returns an exchange rate at a point of time
+	GetRate endpoint.Endpoint
 	// RedirectMain redirects the user from the main page
 	RedirectMain endpoint.Endpoint
 	// RedirectAPI redirects the user from the API page
@@ -36,17 +65,75 @@ func MakeServerEndpoints(s Service) Endpoints {
 		GetAllPaymentsEndpoint: makeGetAllPaymentsEndpoint(s),
 		GetAllAccountsEndpoint: makeGetAllAccountsEndpoint(s),
 		PostPayment:            makePostPaymentEndpoint(s),
+		PostSplitPayment:       makePostSplitPaymentEndpoint(s),
 		PostAccount:            makePostAccountEndpoint(s),
+		PatchAccount:           makePatchAccountEndpoint(s),
+		ExportPaymentsEndpoint: makeExportPaymentsEndpoint(s),
+		ExportAccountsEndpoint: makeExportAccountsEndpoint(s),
+		ImportAccountsEndpoint: makeImportAccountsEndpoint(s),
+		PostWallet:             makePostWalletEndpoint(s),
+		GetWallet:              makeGetWalletEndpoint(s),
+		ConvertFunds:           makeConvertFundsEndpoint(s),
+		GetTrialBalance:        makeGetTrialBalanceEndpoint(s),
+		GetInterestAccruals:    makeGetInterestAccrualsEndpoint(s),
+		PostRates:              makePostRatesEndpoint(s),
+		GetRate:                makeGetRateEndpoint(s),
 		RedirectAPI:            makeRedirectAPIEndpoint(s),
 		RedirectMain:           makeRedirectMainEndpoint(s),
 	}
 }
 
+// MakeClientEndpoints creates client endpoints that call a remote service instance over HTTP.
+//
+// The instance is a service URL like `http://localhost:8080`. Redirect endpoints are not available on the client side
+func MakeClientEndpoints(instance string, opts ...httptransport.ClientOption) (Endpoints, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	tgt, err := url.Parse(instance)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	basePath := strings.TrimSuffix(tgt.Path, "/")
+
+	target := func(path string) *url.URL {
+		u := *tgt
+		u.Path = basePath + path
+		return &u
+	}
+
+	return Endpoints{
+		GetAllPaymentsEndpoint: httptransport.NewClient("GET", target("/api/payments"), encodeGetAllPaymentsRequest, decodeGetAllPaymentsResponse, opts...).Endpoint(),
+		GetAllAccountsEndpoint: httptransport.NewClient("GET", target("/api/accounts"), encodeGetAllAccountsRequest, decodeGetAllAccountsResponse, opts...).Endpoint(),
+		PostPayment:            httptransport.NewClient("POST", target("/api/payment"), encodeRequest, decodePaymentResponse, append(opts, httptransport.ClientBefore(encodeIdempotencyKey))...).Endpoint(),
+		PostSplitPayment:       httptransport.NewClient("POST", target("/api/payments/split"), encodeRequest, decodePaymentGroupResponse, opts...).Endpoint(),
+		PostAccount:            httptransport.NewClient("POST", target("/api/account"), encodeRequest, decodeAccountResponse, opts...).Endpoint(),
+		PatchAccount:           httptransport.NewClient("PATCH", target("/api/accounts"), encodePatchAccountRequest, decodeAccountResponse, opts...).Endpoint(),
+		// export responses are read by the caller after the endpoint returns, so the body should stay open
+		ExportPaymentsEndpoint: httptransport.NewClient("GET", target("/api/payments/export"), encodeExportPaymentsRequest, decodeExportResponse, append(opts, httptransport.BufferedStream(true))...).Endpoint(),
+		ExportAccountsEndpoint: httptransport.NewClient("GET", target("/api/accounts/export"), encodeExportAccountsRequest, decodeExportResponse, append(opts, httptransport.BufferedStream(true))...).Endpoint(),
+		ImportAccountsEndpoint: httptransport.NewClient("POST", target("/api/accounts/import"), encodeImportAccountsRequest, decodeImportAccountsResponse, opts...).Endpoint(),
+		PostWallet:             httptransport.NewClient("POST", target("/api/wallet"), encodeRequest, decodeWalletResponse, opts...).Endpoint(),
+		GetWallet:              httptransport.NewClient("GET", target("/api/wallets"), encodeGetWalletRequest, decodeWalletResponse, opts...).Endpoint(),
+		ConvertFunds:           httptransport.NewClient("POST", target("/api/wallets"), encodeConvertFundsRequest, decodePaymentResponse, opts...).Endpoint(),
+		GetTrialBalance:        httptransport.NewClient("GET", target("/api/ledger/trial-balance"), encodeDummy, decodeTrialBalanceResponse, opts...).Endpoint(),
+		GetInterestAccruals:    httptransport.NewClient("GET", target("/api/accounts"), encodeGetInterestAccrualsRequest, decodeGetInterestAccrualsResponse, opts...).Endpoint(),
+		PostRates:              httptransport.NewClient("POST", target("/api/rates"), encodePostRatesRequest, decodePostRatesResponse, opts...).Endpoint(),
+		GetRate:                httptransport.NewClient("GET", target("/api/rates"), encodeGetRateRequest, decodeRateResponse, opts...).Endpoint(),
+	}, nil
+}
+
 // makeGetAllPaymentsEndpoint creates a GetAllPayments endpoint handler
 func makeGetAllPaymentsEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetAllPaymentsRequest)
 		// call service logic
-		payments, err := s.GetAllPayments(ctx)
+		payments, err := s.GetAllPayments(ctx, model.PaymentFilter{
+			Reference: req.Reference,
+			Search:    req.Search,
+			Metadata:  req.Metadata,
+			GroupID:   req.GroupID,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -55,13 +142,7 @@ func makeGetAllPaymentsEndpoint(s Service) endpoint.Endpoint {
 			Payments: make([]Payment, 0, len(payments)),
 		}
 		for _, p := range payments {
-			res.Payments = append(res.Payments, Payment{
-				AccFromID: p.AccFromID,
-				AccToID:   p.AccToID,
-				DateTime:  p.DateTime,
-				Amount:    currency.ConvertToExternal(p.Amount, p.Currency),
-				Currency:  p.Currency,
-			})
+			res.Payments = append(res.Payments, makePayment(p))
 		}
 		return res, nil
 	}
@@ -70,8 +151,13 @@ func makeGetAllPaymentsEndpoint(s Service) endpoint.Endpoint {
 // makeGetAllAccountsEndpoint creates a GetAllAccounts endpoint handler
 func makeGetAllAccountsEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetAllAccountsRequest)
 		// call service logic
-		accounts, err := s.GetAllAccounts(ctx)
+		accounts, err := s.GetAllAccounts(ctx, model.AccountFilter{
+			OwnerID:   req.OwnerID,
+			Labels:    req.Labels,
+			ConvertTo: req.ConvertTo,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -81,11 +167,7 @@ func makeGetAllAccountsEndpoint(s Service) endpoint.Endpoint {
 			Accounts: make([]Account, 0, len(accounts)),
 		}
 		for _, a := range accounts {
-			res.Accounts = append(res.Accounts, Account{
-				ID:       a.ID,
-				Balance:  currency.ConvertToExternal(a.Balance, a.Currency),
-				Currency: a.Currency,
-			})
+			res.Accounts = append(res.Accounts, makeAccount(a))
 		}
 		return res, nil
 	}
@@ -95,44 +177,424 @@ func makeGetAllAccountsEndpoint(s Service) endpoint.Endpoint {
 func makePostPaymentEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(PostPaymentRequest)
+		if err := req.validate(); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "invalid payment request")
+		}
 		// call service logic
-		res, err := s.PostPayment(ctx, req.AccountFromID, req.AccountToID, req.Amount)
+		res, err := s.PostPayment(ctx, req.AccountFromID, req.AccountToID, req.Amount, model.PaymentInfo{
+			Reference:   req.Reference,
+			Description: req.Description,
+			Metadata:    req.Metadata,
+		})
 		if err != nil {
 			return nil, err
 		}
 
 		// convert results into the response format
-		payment := Payment{
-			AccFromID: res.AccFromID,
-			AccToID:   res.AccToID,
-			DateTime:  res.DateTime,
-			Amount:    currency.ConvertToExternal(res.Amount, res.Currency),
-			Currency:  res.Currency,
-		}
+		payment := makePayment(*res)
 		return &payment, nil
 	}
 }
 
+// makePostSplitPaymentEndpoint creates a PostSplitPayment endpoint handler
+func makePostSplitPaymentEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(PostSplitPaymentRequest)
+		if err := req.validate(); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "invalid split payment request")
+		}
+		receivers := make([]model.SplitReceiver, 0, len(req.Receivers))
+		for _, r := range req.Receivers {
+			receivers = append(receivers, model.SplitReceiver{
+				AccountID: r.AccountID,
+				Amount:    r.Amount,
+				Percent:   r.Percent,
+			})
+		}
+		// call service logic
+		res, err := s.PostSplitPayment(ctx, req.AccountFromID, req.Amount, receivers, model.PaymentInfo{
+			Reference:   req.Reference,
+			Description: req.Description,
+			Metadata:    req.Metadata,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		group := PaymentGroup{
+			ID:          res.ID,
+			AccFromID:   res.AccFromID,
+			DateTime:    res.DateTime,
+			Amount:      currency.ConvertToExternal(res.Amount, res.Currency),
+			Currency:    res.Currency,
+			Payments:    make([]Payment, 0, len(res.Payments)),
+			Reference:   res.Reference,
+			Description: res.Description,
+			Metadata:    res.Metadata,
+		}
+		for _, p := range res.Payments {
+			group.Payments = append(group.Payments, makePayment(p))
+		}
+		return &group, nil
+	}
+}
+
+// makePayment converts a payment into the response format
+func makePayment(p model.Payment) Payment {
+	return Payment{
+		AccFromID:   p.AccFromID,
+		AccToID:     p.AccToID,
+		DateTime:    p.DateTime,
+		Amount:      currency.ConvertToExternal(p.Amount, p.Currency),
+		Currency:    p.Currency,
+		ToAmount:    currency.ConvertToExternal(p.ToAmount, p.ToCurrency),
+		ToCurrency:  p.ToCurrency,
+		Reference:   p.Reference,
+		Description: p.Description,
+		Metadata:    p.Metadata,
+		GroupID:     p.GroupID,
+	}
+}
+
 // makePostAccountEndpoint creates a PostAccount endpoint handler
 func makePostAccountEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(PostAccountRequest)
+		if err := req.validate(); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "invalid account request")
+		}
 		// call service logic
-		res, err := s.PostAccount(ctx, req.ID, req.Balance, req.Currency)
+		res, err := s.PostAccount(ctx, req.ID, req.Balance, req.Currency, model.AccountInfo{
+			OwnerID:     req.OwnerID,
+			DisplayName: req.DisplayName,
+			Labels:      req.Labels,
+			Metadata:    req.Metadata,
+			Type:        req.Type,
+		})
 		if err != nil {
 			return nil, err
 		}
 
 		// convert results into the response format
-		account := Account{
-			ID:       res.ID,
-			Balance:  currency.ConvertToExternal(res.Balance, res.Currency),
-			Currency: res.Currency,
+		account := makeAccount(*res)
+		return &account, nil
+	}
+}
+
+// makePatchAccountEndpoint creates a PatchAccount endpoint handler
+func makePatchAccountEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(PatchAccountRequest)
+		// call service logic
+		res, err := s.PatchAccount(ctx, req.ID, model.AccountPatch{
+			OwnerID:     req.OwnerID,
+			DisplayName: req.DisplayName,
+			Labels:      req.Labels,
+			Metadata:    req.Metadata,
+			Type:        req.Type,
+		})
+		if err != nil {
+			return nil, err
 		}
+
+		// convert results into the response format
+		account := makeAccount(*res)
 		return &account, nil
 	}
 }
 
+// makeAccount converts an account into the response format
+func makeAccount(a model.Account) Account {
+	acc := Account{
+		ID:          a.ID,
+		Balance:     currency.ConvertToExternal(a.Balance, a.Currency),
+		Currency:    a.Currency,
+		OwnerID:     a.OwnerID,
+		DisplayName: a.DisplayName,
+		Labels:      a.Labels,
+		Metadata:    a.Metadata,
+		Type:        a.Type,
+	}
+	if c := a.Converted; c != nil {
+		acc.Converted = &ConvertedBalance{
+			Balance:  currency.ConvertToExternal(c.Balance, c.Currency),
+			Currency: c.Currency,
+			Rate:     c.Rate.Rate,
+			RateTime: c.Rate.Time,
+		}
+	}
+	return acc
+}
+
+// makeExportPaymentsEndpoint creates an ExportPayments endpoint handler.
+//
+// The payments are read from the service while the response is written
+func makeExportPaymentsEndpoint(s Service) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ExportPaymentsRequest)
+		return exportResponse{
+			name:   "payments",
+			format: req.Format,
+			header: paymentRecordHeader,
+			export: func(ctx context.Context, write func(exportRecord) error) error {
+				return s.ExportPayments(ctx, req.From, req.To, func(p model.Payment) error {
+					return write(PaymentRecord{
+						ID:           p.ID,
+						DateTime:     p.DateTime,
+						AccFromID:    p.AccFromID,
+						AccToID:      p.AccToID,
+						Amount:       p.Currency.FormatDecimal(p.Amount),
+						Currency:     p.Currency,
+						CurrencyName: p.Currency.String(),
+						ToAmount:     formatToAmount(p),
+						ToCurrency:   p.ToCurrency,
+					})
+				})
+			},
+		}, nil
+	}
+}
+
+// formatToAmount formats the receiver amount of a conversion, it is empty for a payment in one currency
+func formatToAmount(p model.Payment) string {
+	if p.ToCurrency == "" {
+		return ""
+	}
+	return p.ToCurrency.FormatDecimal(p.ToAmount)
+}
+
+// makeExportAccountsEndpoint creates an ExportAccounts endpoint handler.
+//
+// The accounts are read from the service while the response is written
+func makeExportAccountsEndpoint(s Service) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ExportAccountsRequest)
+		return exportResponse{
+			name:   "accounts",
+			format: req.Format,
+			header: accountRecordHeader,
+			export: func(ctx context.Context, write func(exportRecord) error) error {
+				return s.ExportAccounts(ctx, func(a model.Account) error {
+					return write(AccountRecord{
+						ID:           a.ID,
+						Balance:      a.Currency.FormatDecimal(a.Balance),
+						Currency:     a.Currency,
+						CurrencyName: a.Currency.String(),
+						LastUpdate:   a.LastUpdate,
+					})
+				})
+			},
+		}, nil
+	}
+}
+
+// makeImportAccountsEndpoint creates an ImportAccounts endpoint handler
+func makeImportAccountsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ImportAccountsRequest)
+		// call service logic
+		res, err := s.ImportAccounts(ctx, req.Rows, req.DryRun)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		resp := ImportAccountsResponse{
+			Imported: res.Imported,
+			DryRun:   res.DryRun,
+		}
+		for _, e := range res.Errors {
+			resp.Errors = append(resp.Errors, ImportRowError{
+				Line:  e.Line,
+				ID:    e.ID,
+				Error: e.Error,
+			})
+		}
+		return resp, nil
+	}
+}
+
+// makePostWalletEndpoint creates a PostWallet endpoint handler
+func makePostWalletEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(PostWalletRequest)
+		// call service logic
+		res, err := s.PostWallet(ctx, req.ID, req.OwnerID, req.Currencies)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		wallet := makeWallet(*res)
+		return &wallet, nil
+	}
+}
+
+// makeGetWalletEndpoint creates a GetWallet endpoint handler
+func makeGetWalletEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetWalletRequest)
+		// call service logic
+		res, err := s.GetWallet(ctx, req.ID, req.TotalCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		wallet := makeWallet(*res)
+		return &wallet, nil
+	}
+}
+
+// makeConvertFundsEndpoint creates a ConvertFunds endpoint handler
+func makeConvertFundsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ConvertFundsRequest)
+		// call service logic
+		res, err := s.ConvertFunds(ctx, req.WalletID, req.From, req.To, req.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		payment := makePayment(*res)
+		return &payment, nil
+	}
+}
+
+// makeWallet converts a wallet into the response format
+func makeWallet(w model.Wallet) Wallet {
+	res := Wallet{
+		ID:            w.ID,
+		OwnerID:       w.OwnerID,
+		Pockets:       make([]Account, 0, len(w.Pockets)),
+		Total:         currency.ConvertToExternal(w.Total, w.TotalCurrency),
+		TotalCurrency: w.TotalCurrency,
+	}
+	for _, a := range w.Pockets {
+		res.Pockets = append(res.Pockets, makeAccount(a))
+	}
+	return res
+}
+
+// makeGetTrialBalanceEndpoint creates a GetTrialBalance endpoint handler
+func makeGetTrialBalanceEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		// call service logic
+		tb, err := s.GetTrialBalance(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		res := TrialBalance{
+			Accounts: make([]TrialBalanceAccount, 0, len(tb.Accounts)),
+			Totals:   make([]TrialBalanceTotal, 0, len(tb.Totals)),
+			Balanced: tb.Balanced,
+		}
+		for _, a := range tb.Accounts {
+			res.Accounts = append(res.Accounts, TrialBalanceAccount{
+				AccountID: a.AccountID,
+				Currency:  a.Currency,
+				Debit:     currency.ConvertToExternal(a.Debit, a.Currency),
+				Credit:    currency.ConvertToExternal(a.Credit, a.Currency),
+				Balance:   currency.ConvertToExternal(a.Balance, a.Currency),
+				System:    a.System,
+			})
+		}
+		for _, t := range tb.Totals {
+			res.Totals = append(res.Totals, TrialBalanceTotal{
+				Currency: t.Currency,
+				Debit:    currency.ConvertToExternal(t.Debit, t.Currency),
+				Credit:   currency.ConvertToExternal(t.Credit, t.Currency),
+			})
+		}
+		return &res, nil
+	}
+}
+
+// makeGetInterestAccrualsEndpoint creates a GetInterestAccruals endpoint handler
+func makeGetInterestAccrualsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetInterestAccrualsRequest)
+		// call service logic
+		accruals, err := s.GetInterestAccruals(ctx, req.AccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		// convert results into the response format
+		res := GetInterestAccrualsResponse{
+			Accruals: make([]InterestAccrual, 0, len(accruals)),
+		}
+		for _, a := range accruals {
+			res.Accruals = append(res.Accruals, InterestAccrual{
+				Date:      a.Date.Format("2006-01-02"),
+				Balance:   currency.ConvertToExternal(a.Balance, a.Currency),
+				Rate:      a.Rate,
+				Amount:    currency.ConvertToExternal(a.Amount, a.Currency),
+				Currency:  a.Currency,
+				PaymentID: a.PaymentID,
+			})
+		}
+		return res, nil
+	}
+}
+
+// makePostRatesEndpoint creates a PostRates endpoint handler
+func makePostRatesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(PostRatesRequest)
+		rates := make([]currency.Rate, 0, len(req.Rates))
+		for _, r := range req.Rates {
+			rate := currency.Rate{
+				Base:  r.Base,
+				Quote: r.Quote,
+				Rate:  r.Rate,
+			}
+			if r.Time != nil {
+				rate.Time = *r.Time
+			}
+			rates = append(rates, rate)
+		}
+		// call service logic
+		saved, err := s.PostRates(ctx, rates)
+		if err != nil {
+			return nil, err
+		}
+		return PostRatesResponse{Saved: saved}, nil
+	}
+}
+
+// makeGetRateEndpoint creates a GetRate endpoint handler
+func makeGetRateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(GetRateRequest)
+		var at time.Time
+		if req.At != nil {
+			at = *req.At
+		}
+		// call service logic
+		res, err := s.GetRate(ctx, req.Base, req.Quote, at)
+		if err != nil {
+			return nil, err
+		}
+		return makeRate(*res), nil
+	}
+}
+
+// makeRate converts an exchange rate into the response format
+func makeRate(r currency.Rate) *Rate {
+	t := r.Time
+	return &Rate{
+		Base:  r.Base,
+		Quote: r.Quote,
+		Rate:  r.Rate,
+		Time:  &t,
+	}
+}
+
 // makeRedirectAPIEndpoint redirects to api documentation page
 func makeRedirectAPIEndpoint(s Service) endpoint.Endpoint {
 	return func(_ context.Context, request interface{}) (response interface{}, err error) {
@@ -161,18 +623,100 @@ type (
 	//
 	// It is used to structure REST request data.
 	PostPaymentRequest struct {
-		AccountFromID string  `json:"account-from"`
-		AccountToID   string  `json:"account-to"`
-		Amount        float64 `json:"amount"`
+		AccountFromID string            `json:"account-from"`
+		AccountToID   string            `json:"account-to"`
+		Amount        float64           `json:"amount"`
+		Reference     string            `json:"reference,omitempty"`
+		Description   string            `json:"description,omitempty"`
+		Metadata      map[string]string `json:"metadata,omitempty"`
+	}
+
+	// GetAllPaymentsRequest is a request structure for the GetAllPayments endpoint.
+	//
+	// It is used to structure REST request query parameters.
+	GetAllPaymentsRequest struct {
+		// Reference limits the list to payments with the reference
+		Reference string
+		// Search limits the list to payments with the text in the reference or the description
+		Search string
+		// Metadata limits the list to payments with all of the metadata key-value pairs
+		Metadata map[string]string
+		// GroupID limits the list to payments of the split payment
+		GroupID int
+	}
+
+	// PostSplitPaymentRequest is a request structure for the PostSplitPayment endpoint.
+	//
+	// It is used to structure REST request data.
+	PostSplitPaymentRequest struct {
+		AccountFromID string            `json:"account-from"`
+		Amount        float64           `json:"amount"`
+		Receivers     []SplitReceiver   `json:"receivers"`
+		Reference     string            `json:"reference,omitempty"`
+		Description   string            `json:"description,omitempty"`
+		Metadata      map[string]string `json:"metadata,omitempty"`
+	}
+
+	// SplitReceiver is a receiver of a split payment share.
+	//
+	// The share is either a fixed amount or a percentage of the amount left after all fixed shares.
+	SplitReceiver struct {
+		AccountID string  `json:"account"`
+		Amount    float64 `json:"amount,omitempty"`
+		Percent   float64 `json:"percent,omitempty"`
+	}
+
+	// PaymentGroup is a split payment with a payment to each receiver.
+	//
+	// It is used to structure REST response data.
+	PaymentGroup struct {
+		ID          int               `json:"id"`
+		AccFromID   string            `json:"account-from"`
+		DateTime    time.Time         `json:"time,omitempty"`
+		Amount      float64           `json:"amount"`
+		Currency    currency.Currency `json:"currency"`
+		Payments    []Payment         `json:"payments"`
+		Reference   string            `json:"reference,omitempty"`
+		Description string            `json:"description,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
 	}
 
 	// PostAccountRequest is a request structure for the PostAccount endpoint.
 	//
 	// It is used to structure REST request data.
 	PostAccountRequest struct {
-		ID       string  `json:"id"`
-		Balance  float64 `json:"balance"`
-		Currency string  `json:"currency"`
+		ID          string            `json:"id"`
+		Balance     float64           `json:"balance"`
+		Currency    string            `json:"currency"`
+		OwnerID     string            `json:"owner-id,omitempty"`
+		DisplayName string            `json:"display-name,omitempty"`
+		Labels      []string          `json:"labels,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		Type        string            `json:"type,omitempty"`
+	}
+
+	// PatchAccountRequest is a request structure for the PatchAccount endpoint.
+	//
+	// Omitted fields are not changed. Labels and metadata are replaced as a whole.
+	PatchAccountRequest struct {
+		ID          string             `json:"-"`
+		OwnerID     *string            `json:"owner-id,omitempty"`
+		DisplayName *string            `json:"display-name,omitempty"`
+		Labels      *[]string          `json:"labels,omitempty"`
+		Metadata    *map[string]string `json:"metadata,omitempty"`
+		Type        *string            `json:"type,omitempty"`
+	}
+
+	// GetAllAccountsRequest is a request structure for the GetAllAccounts endpoint.
+	//
+	// It is used to structure REST request query parameters.
+	GetAllAccountsRequest struct {
+		// OwnerID limits the list to accounts of the owner
+		OwnerID string
+		// Labels limits the list to accounts that have all of the labels
+		Labels []string
+		// ConvertTo is a currency to convert balances into
+		ConvertTo string
 	}
 
 	// GetAllPaymentsResponse  is a request structure for the GetAllPayments endpoint
@@ -189,23 +733,263 @@ type (
 		Accounts []Account `json:"accounts"`
 	}
 
-	// Account is a financial account.
+	// ExportPaymentsRequest is a request structure for the ExportPayments endpoint.
+	//
+	// It is used to structure REST request query parameters.
+	ExportPaymentsRequest struct {
+		// Format is `csv` or `jsonl`
+		Format string
+		// From is an inclusive start of the payment period
+		From *time.Time
+		// To is an exclusive end of the payment period
+		To *time.Time
+	}
+
+	// ExportAccountsRequest is a request structure for the ExportAccounts endpoint.
+	//
+	// It is used to structure REST request query parameters.
+	ExportAccountsRequest struct {
+		// Format is `csv` or `jsonl`
+		Format string
+	}
+
+	// ImportAccountsRequest is a request structure for the ImportAccounts endpoint.
+	//
+	// It is decoded from a CSV or JSON Lines request body.
+	ImportAccountsRequest struct {
+		Rows []model.AccountImport
+		// DryRun validates the rows without creating accounts
+		DryRun bool
+	}
+
+	// ImportAccountsResponse is a response structure for the ImportAccounts endpoint.
 	//
 	// It is used to structure REST response data.
-	Account struct {
-		ID       string            `json:"id"`
+	ImportAccountsResponse struct {
+		Imported int              `json:"imported"`
+		DryRun   bool             `json:"dry-run"`
+		Errors   []ImportRowError `json:"errors,omitempty"`
+	}
+
+	// ImportRowError is a reason why an import row was rejected.
+	//
+	// The line is a line number in the import file.
+	ImportRowError struct {
+		Line  int    `json:"line"`
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+
+	// PostWalletRequest is a request structure for the PostWallet endpoint.
+	//
+	// It is used to structure REST request data.
+	PostWalletRequest struct {
+		ID         string   `json:"id"`
+		OwnerID    string   `json:"owner-id,omitempty"`
+		Currencies []string `json:"currencies"`
+	}
+
+	// GetWalletRequest is a request structure for the GetWallet endpoint.
+	//
+	// It is used to structure REST request path and query parameters.
+	GetWalletRequest struct {
+		ID string
+		// TotalCurrency is a currency of the wallet total, the base currency of exchange rates if empty
+		TotalCurrency string
+	}
+
+	// ConvertFundsRequest is a request structure for the ConvertFunds endpoint.
+	//
+	// It is used to structure REST request data.
+	ConvertFundsRequest struct {
+		WalletID string  `json:"-"`
+		From     string  `json:"from"`
+		To       string  `json:"to"`
+		Amount   float64 `json:"amount"`
+	}
+
+	// Wallet is a set of accounts of one owner in different currencies.
+	//
+	// It is used to structure REST response data.
+	Wallet struct {
+		ID            string            `json:"id"`
+		OwnerID       string            `json:"owner-id,omitempty"`
+		Pockets       []Account         `json:"pockets"`
+		Total         float64           `json:"total"`
+		TotalCurrency currency.Currency `json:"total-currency"`
+	}
+
+	// TrialBalance is a report of ledger account sums.
+	//
+	// It is used to structure REST response data.
+	TrialBalance struct {
+		Accounts []TrialBalanceAccount `json:"accounts"`
+		Totals   []TrialBalanceTotal   `json:"totals"`
+		// Balanced is true if total debits equal total credits in each currency
+		Balanced bool `json:"balanced"`
+	}
+
+	// TrialBalanceAccount is a sum of postings of one ledger account in one currency.
+	//
+	// System accounts have IDs like `@fx/EUR` and exist only in the ledger.
+	TrialBalanceAccount struct {
+		AccountID string            `json:"account"`
+		Currency  currency.Currency `json:"currency"`
+		Debit     float64           `json:"debit"`
+		Credit    float64           `json:"credit"`
+		Balance   float64           `json:"balance"`
+		System    bool              `json:"system,omitempty"`
+	}
+
+	// TrialBalanceTotal is a sum of postings of all ledger accounts in one currency.
+	//
+	// It is used to structure REST response data.
+	TrialBalanceTotal struct {
+		Currency currency.Currency `json:"currency"`
+		Debit    float64           `json:"debit"`
+		Credit   float64           `json:"credit"`
+	}
+
+	// GetInterestAccrualsRequest is a request structure for the GetInterestAccruals endpoint.
+	//
+	// It is used to structure REST request path parameters.
+	GetInterestAccrualsRequest struct {
+		AccountID string
+	}
+
+	// GetInterestAccrualsResponse is a response structure for the GetInterestAccruals endpoint.
+	//
+	// It is used to structure REST response data.
+	GetInterestAccrualsResponse struct {
+		Accruals []InterestAccrual `json:"accruals"`
+	}
+
+	// InterestAccrual is interest accrued on an account for one day.
+	//
+	// It is used to structure REST response data.
+	InterestAccrual struct {
+		// Date is a day in format `2006-01-02`
+		Date string `json:"date"`
+		// Balance is the end-of-day balance the interest is calculated on
+		Balance  float64           `json:"balance"`
+		Rate     float64           `json:"rate"`
+		Amount   float64           `json:"amount"`
+		Currency currency.Currency `json:"currency"`
+		// PaymentID is a payout payment, omitted until the interest is paid
+		PaymentID int `json:"payment-id,omitempty"`
+	}
+
+	// PostRatesRequest is a request structure for the PostRates endpoint.
+	//
+	// It is used to structure REST request data.
+	PostRatesRequest struct {
+		Rates []Rate
+	}
+
+	// PostRatesResponse is a response structure for the PostRates endpoint.
+	//
+	// It is used to structure REST response data.
+	PostRatesResponse struct {
+		Saved int `json:"saved"`
+	}
+
+	// GetRateRequest is a request structure for the GetRate endpoint.
+	//
+	// It is used to structure REST request query parameters.
+	GetRateRequest struct {
+		Base  string
+		Quote string
+		// At is a time of the rate, now if it is empty
+		At *time.Time
+	}
+
+	// Rate is an exchange rate: one unit of the base currency costs Rate units of the quote currency.
+	//
+	// It is used to structure REST request and response data.
+	Rate struct {
+		Base  currency.Currency `json:"base"`
+		Quote currency.Currency `json:"quote"`
+		Rate  float64           `json:"rate"`
+		// Time is when the rate is set, a new rate is set now if it is empty
+		Time *time.Time `json:"time,omitempty"`
+	}
+
+	// ConvertedBalance is an account balance converted into another currency.
+	//
+	// It is used to structure REST response data.
+	ConvertedBalance struct {
 		Balance  float64           `json:"balance"`
 		Currency currency.Currency `json:"currency"`
+		Rate     float64           `json:"rate"`
+		RateTime time.Time         `json:"rate-time"`
+	}
+
+	// PaymentRecord is a payment in the export.
+	//
+	// The amounts are exact decimal strings with all decimal places of the currency.
+	// The receiver amount and currency are set only for a conversion between pockets of a wallet.
+	PaymentRecord struct {
+		ID           int               `json:"id"`
+		DateTime     time.Time         `json:"time"`
+		AccFromID    string            `json:"account-from"`
+		AccToID      string            `json:"account-to"`
+		Amount       string            `json:"amount"`
+		Currency     currency.Currency `json:"currency"`
+		CurrencyName string            `json:"currency-name"`
+		ToAmount     string            `json:"amount-to,omitempty"`
+		ToCurrency   currency.Currency `json:"currency-to,omitempty"`
+	}
+
+	// AccountRecord is an account in the export.
+	//
+	// The balance is an exact decimal string with all decimal places of the currency.
+	AccountRecord struct {
+		ID           string            `json:"id"`
+		Balance      string            `json:"balance"`
+		Currency     currency.Currency `json:"currency"`
+		CurrencyName string            `json:"currency-name"`
+		LastUpdate   *time.Time        `json:"last-update,omitempty"`
+	}
+
+	// Account is a financial account.
+	//
+	// It is used to structure REST response data.
+	Account struct {
+		ID          string            `json:"id"`
+		Balance     float64           `json:"balance"`
+		Currency    currency.Currency `json:"currency"`
+		OwnerID     string            `json:"owner-id,omitempty"`
+		DisplayName string            `json:"display-name,omitempty"`
+		Labels      []string          `json:"labels,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		Type        string            `json:"type,omitempty"`
+		// Converted is the balance in the requested currency
+		Converted *ConvertedBalance `json:"converted,omitempty"`
 	}
 
 	// Payment is a financial transaction between accounts.
 	//
 	// It is used to structure REST response data.
 	Payment struct {
-		AccFromID string            `json:"account-from"`
-		AccToID   string            `json:"account-to"`
-		DateTime  time.Time         `json:"time,omitempty"`
-		Amount    float64           `json:"amount"`
-		Currency  currency.Currency `json:"currency"`
+		AccFromID   string            `json:"account-from"`
+		AccToID     string            `json:"account-to"`
+		DateTime    time.Time         `json:"time,omitempty"`
+		Amount      float64           `json:"amount"`
+		Currency    currency.Currency `json:"currency"`
+		ToAmount    float64           `json:"amount-to,omitempty"`
+		ToCurrency  currency.Currency `json:"currency-to,omitempty"`
+		Reference   string            `json:"reference,omitempty"`
+		Description string            `json:"description,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		// GroupID links payments of one split payment
+		GroupID int `json:"group-id,omitempty"`
 	}
 )
+
+// StatusCode responds 422 if any row was rejected
+func (r ImportAccountsResponse) StatusCode() int {
+	if len(r.Errors) > 0 {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusOK
+}
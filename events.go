@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+// PaymentEvents is a broker that notifies subscribers about new payments.
+//
+// Events are delivered without blocking the payment processing, so a subscriber that doesn't keep up misses events
+type PaymentEvents struct {
+	mu     sync.Mutex
+	subs   map[chan model.Payment]struct{}
+	closed bool
+}
+
+// NewPaymentEvents creates a new payment event broker
+func NewPaymentEvents() *PaymentEvents {
+	return &PaymentEvents{
+		subs: make(map[chan model.Payment]struct{}),
+	}
+}
+
+// Subscribe returns a channel of new payments and a function that cancels the subscription.
+//
+// The buffer is a number of events kept for the subscriber. The channel is closed on cancel or when the broker is closed
+func (e *PaymentEvents) Subscribe(buffer int) (<-chan model.Payment, func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ch := make(chan model.Payment, buffer)
+	if e.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	e.subs[ch] = struct{}{}
+
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if _, ok := e.subs[ch]; ok {
+			delete(e.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends the payment to all subscribers
+func (e *PaymentEvents) Publish(p model.Payment) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ch := range e.subs {
+		select {
+		case ch <- p:
+		default:
+			// the subscriber is too slow
+		}
+	}
+}
+
+// Close closes all subscriptions, so the subscribers can finish.
+//
+// It has the signature of a shutdown step
+func (e *PaymentEvents) Close(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for ch := range e.subs {
+		delete(e.subs, ch)
+		close(ch)
+	}
+	return nil
+}
+
+// eventsService is a Service middleware that publishes events about new payments
+type eventsService struct {
+	Service
+	events *PaymentEvents
+}
+
+// NewEventsService wraps the service with a middleware that publishes created payments into the broker
+func NewEventsService(s Service, events *PaymentEvents) Service {
+	return &eventsService{s, events}
+}
+
+// PostPayment publishes the payment if it was created
+func (s *eventsService) PostPayment(ctx context.Context, fromID, toID string, amount float64, info model.PaymentInfo) (*model.Payment, error) {
+	p, err := s.Service.PostPayment(ctx, fromID, toID, amount, info)
+	if err == nil {
+		s.events.Publish(*p)
+	}
+	return p, err
+}
+
+// PostSplitPayment publishes each payment of the split payment if it was created
+func (s *eventsService) PostSplitPayment(ctx context.Context, fromID string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error) {
+	g, err := s.Service.PostSplitPayment(ctx, fromID, amount, receivers, info)
+	if err == nil {
+		for _, p := range g.Payments {
+			s.events.Publish(p)
+		}
+	}
+	return g, err
+}
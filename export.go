@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Export formats
+const (
+	exportFormatCSV   = "csv"
+	exportFormatJSONL = "jsonl"
+)
+
+// exportFlushRows is a number of rows sent to the client at once
+const exportFlushRows = 100
+
+var (
+	paymentRecordHeader = []string{"id", "time", "account-from", "account-to", "amount", "currency", "currency-name", "amount-to", "currency-to"}
+	accountRecordHeader = []string{"id", "balance", "currency", "currency-name", "last-update"}
+)
+
+// exportRecord is a single row of an export
+type exportRecord interface {
+	csvRecord() []string
+}
+
+func (p PaymentRecord) csvRecord() []string {
+	return []string{
+		strconv.Itoa(p.ID),
+		p.DateTime.Format(time.RFC3339Nano),
+		p.AccFromID,
+		p.AccToID,
+		p.Amount,
+		string(p.Currency),
+		p.CurrencyName,
+		p.ToAmount,
+		string(p.ToCurrency),
+	}
+}
+
+func (a AccountRecord) csvRecord() []string {
+	var lastUpdate string
+	if a.LastUpdate != nil {
+		lastUpdate = a.LastUpdate.Format(time.RFC3339Nano)
+	}
+	return []string{
+		a.ID,
+		a.Balance,
+		string(a.Currency),
+		a.CurrencyName,
+		lastUpdate,
+	}
+}
+
+// exportResponse is a lazy export response.
+//
+// The records are produced by the export function while the response is encoded, so the export is never kept in memory
+type exportResponse struct {
+	name   string
+	format string
+	header []string
+	export func(ctx context.Context, write func(exportRecord) error) error
+}
+
+// writeTracker is a writer that remembers if anything was written
+type writeTracker struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *writeTracker) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+func decodeExportPaymentsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	q := r.URL.Query()
+	format, err := exportFormat(q)
+	if err != nil {
+		return nil, err
+	}
+	from, err := exportTime(q, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := exportTime(q, "to")
+	if err != nil {
+		return nil, err
+	}
+	return ExportPaymentsRequest{
+		Format: format,
+		From:   from,
+		To:     to,
+	}, nil
+}
+
+func decodeExportAccountsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	format, err := exportFormat(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	return ExportAccountsRequest{format}, nil
+}
+
+// exportFormat returns the export format from the query, CSV by default
+func exportFormat(q url.Values) (string, error) {
+	switch f := q.Get("format"); f {
+	case "":
+		return exportFormatCSV, nil
+	case exportFormatCSV, exportFormatJSONL:
+		return f, nil
+	default:
+		return "", NewErrHTTPStatusf(http.StatusBadRequest, nil, "unknown export format %q, expected csv or jsonl", f)
+	}
+}
+
+// exportTime parses an optional query parameter as RFC 3339 time or a date
+func exportTime(q url.Values, key string) (*time.Time, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "invalid %s time %q, expected RFC 3339 time or YYYY-MM-DD date", key, v)
+}
+
+// encodeExportResponse streams the export records into the response.
+//
+// Records are flushed to the client in batches. If the export fails before anything was sent, the error is encoded as usual.
+// Otherwise the response is aborted, so the client can't take a partial export for a complete one
+func encodeExportResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(exportResponse)
+	tw := &writeTracker{w: w}
+
+	var (
+		write func(exportRecord) error
+		flush func() error
+	)
+	switch resp.format {
+	case exportFormatJSONL:
+		bw := bufio.NewWriter(tw)
+		enc := json.NewEncoder(bw)
+		write = func(r exportRecord) error { return enc.Encode(r) }
+		flush = bw.Flush
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	default:
+		cw := csv.NewWriter(tw)
+		cw.Write(resp.header)
+		write = func(r exportRecord) error { return cw.Write(r.csvRecord()) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", resp.name+"."+resp.format))
+
+	rows := 0
+	err := resp.export(ctx, func(r exportRecord) error {
+		if err := write(r); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows != 0 {
+			return nil
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		if tw.written {
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Del("Content-Disposition")
+	}
+	return err
+}
+
+func encodeExportPaymentsRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(ExportPaymentsRequest)
+	q := req.URL.Query()
+	q.Set("format", r.Format)
+	if r.From != nil {
+		q.Set("from", r.From.Format(time.RFC3339Nano))
+	}
+	if r.To != nil {
+		q.Set("to", r.To.Format(time.RFC3339Nano))
+	}
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func encodeExportAccountsRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(ExportAccountsRequest)
+	q := req.URL.Query()
+	q.Set("format", r.Format)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// decodeExportResponse returns the response body, the caller should close it
+func decodeExportResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		defer r.Body.Close()
+		return nil, decodeError(r)
+	}
+	return r.Body, nil
+}
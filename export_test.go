@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+func TestExportEndpoints(t *testing.T) {
+	ts := time.Date(2019, 6, 23, 0, 37, 47, 0, time.UTC)
+	payments := []model.Payment{
+		{ID: 1, AccFromID: "alice", AccToID: "bob", DateTime: ts, Amount: currency.NewAmount(1230), Currency: currency.USD},
+		{ID: 2, AccFromID: "carol", AccToID: "dave", DateTime: ts, Amount: currency.NewAmount(5), Currency: currency.BHD},
+		{ID: 3, AccFromID: "alice/USD", AccToID: "alice/EUR", DateTime: ts, Amount: currency.NewAmount(1000), Currency: currency.USD, ToAmount: currency.NewAmount(920), ToCurrency: currency.EUR},
+	}
+	accounts := []model.Account{
+		{ID: "alice", LastUpdate: &ts, Balance: currency.NewAmount(10000), Currency: currency.USD},
+		{ID: "carol", Balance: currency.NewAmount(1500), Currency: currency.CLP},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		db       *TestDatabase
+		wantCode int
+		wantType string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "payments csv",
+			path:     "/api/payments/export",
+			db:       &TestDatabase{ExportPaymentsData: testDatabaseData{dat: payments}},
+			wantCode: http.StatusOK,
+			wantType: "text/csv; charset=utf-8",
+			want: "id,time,account-from,account-to,amount,currency,currency-name,amount-to,currency-to\n" +
+				"1,2019-06-23T00:37:47Z,alice,bob,12.30,USD,US Dollar,,\n" +
+				"2,2019-06-23T00:37:47Z,carol,dave,0.005,BHD,Bahraini Dinar,,\n" +
+				"3,2019-06-23T00:37:47Z,alice/USD,alice/EUR,10.00,USD,US Dollar,9.20,EUR\n",
+		},
+		{
+			name:     "payments jsonl",
+			path:     "/api/payments/export?format=jsonl&from=2019-06-01&to=2019-07-01T00:00:00Z",
+			db:       &TestDatabase{ExportPaymentsData: testDatabaseData{dat: payments[:1]}},
+			wantCode: http.StatusOK,
+			wantType: "application/x-ndjson; charset=utf-8",
+			want:     `{"id":1,"time":"2019-06-23T00:37:47Z","account-from":"alice","account-to":"bob","amount":"12.30","currency":"USD","currency-name":"US Dollar"}` + "\n",
+		},
+		{
+			name:     "conversion jsonl",
+			path:     "/api/payments/export?format=jsonl",
+			db:       &TestDatabase{ExportPaymentsData: testDatabaseData{dat: payments[2:]}},
+			wantCode: http.StatusOK,
+			wantType: "application/x-ndjson; charset=utf-8",
+			want:     `{"id":3,"time":"2019-06-23T00:37:47Z","account-from":"alice/USD","account-to":"alice/EUR","amount":"10.00","currency":"USD","currency-name":"US Dollar","amount-to":"9.20","currency-to":"EUR"}` + "\n",
+		},
+		{
+			name:     "accounts csv",
+			path:     "/api/accounts/export?format=csv",
+			db:       &TestDatabase{ExportAccountsData: testDatabaseData{dat: accounts}},
+			wantCode: http.StatusOK,
+			wantType: "text/csv; charset=utf-8",
+			want: "id,balance,currency,currency-name,last-update\n" +
+				"alice,100.00,USD,US Dollar,2019-06-23T00:37:47Z\n" +
+				"carol,1500,CLP,Chilean Peso,\n",
+		},
+		{
+			name:     "empty",
+			path:     "/api/accounts/export?format=jsonl",
+			db:       &TestDatabase{},
+			wantCode: http.StatusOK,
+			wantType: "application/x-ndjson; charset=utf-8",
+			want:     "",
+		},
+		{
+			name:     "unknown format",
+			path:     "/api/payments/export?format=xml",
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "invalid time",
+			path:     "/api/payments/export?from=yesterday",
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "wrong period",
+			path:     "/api/payments/export?from=2019-07-01&to=2019-06-01",
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name:     "database error",
+			path:     "/api/payments/export",
+			db:       &TestDatabase{ExportPaymentsData: testDatabaseData{err: errors.New("test error")}},
+			wantCode: http.StatusInternalServerError,
+			wantType: "application/json; charset=utf-8",
+		},
+		{
+			name: "error after rows sent",
+			path: "/api/payments/export",
+			db: &TestDatabase{ExportPaymentsData: testDatabaseData{
+				dat: make([]model.Payment, exportFlushRows),
+				err: errors.New("test error"),
+			}},
+			wantCode: http.StatusOK,
+			wantType: "text/csv; charset=utf-8",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(MakeHTTPHandler(NewWalletService(tt.db), log.NewNopLogger()))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong body read error %v, want error %v", err, tt.wantErr)
+			}
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("wrong status code %v, want %v", resp.StatusCode, tt.wantCode)
+			}
+			if got := resp.Header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("wrong content type %v, want %v", got, tt.wantType)
+			}
+			if tt.wantCode == http.StatusOK && !tt.wantErr && string(body) != tt.want {
+				t.Errorf("wrong export %q, want %q", body, tt.want)
+			}
+			if tt.wantCode != http.StatusOK && !strings.Contains(string(body), `"code"`) {
+				t.Errorf("wrong error response %s", body)
+			}
+		})
+	}
+}
+
+func TestExportPaymentsPeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{"utc", "from=2019-06-23T00:00:00Z&to=2019-06-24T00:00:00Z", time.Date(2019, 6, 23, 0, 0, 0, 0, time.UTC), time.Date(2019, 6, 24, 0, 0, 0, 0, time.UTC)},
+		{"offset", "from=2019-06-23T00:00:00%2B02:00&to=2019-06-24T00:00:00-05:00", time.Date(2019, 6, 22, 22, 0, 0, 0, time.UTC), time.Date(2019, 6, 24, 5, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{}
+			h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/api/payments/export?"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("wrong status code %v, want %v: %s", w.Code, http.StatusOK, w.Body)
+			}
+			for _, tm := range []struct {
+				got  *time.Time
+				want time.Time
+			}{{db.exportFrom, tt.wantFrom}, {db.exportTo, tt.wantTo}} {
+				if tm.got == nil || !tm.got.Equal(tm.want) || tm.got.Location() != time.UTC {
+					t.Errorf("wrong period time %v, want %v", tm.got, tm.want)
+				}
+			}
+		})
+	}
+}
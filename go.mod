@@ -1,13 +1,23 @@
 module github.com/ilyakaznacheev/tiny-wallet
 
+go 1.16
+
 require (
-	github.com/go-kit/kit v0.8.0
-	github.com/go-logfmt/logfmt v0.4.0 // indirect
-	github.com/go-stack/stack v1.8.0 // indirect
+	github.com/go-kit/kit v0.9.0
 	github.com/gorilla/mux v1.7.2
 	github.com/ilyakaznacheev/cleanenv v1.0.0
-	github.com/kr/pretty v0.1.0 // indirect
 	github.com/lib/pq v1.1.1
-	golang.org/x/xerrors v0.0.0-20190513163551-3ee3066db522
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	github.com/prometheus/client_golang v1.12.2
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.32.0
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.32.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.7.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da
+	google.golang.org/grpc v1.47.0
+	google.golang.org/protobuf v1.28.0
 )
+
+require github.com/VividCortex/gohistogram v1.0.0 // indirect
@@ -0,0 +1,133 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Health probe route names
+const (
+	routeLiveness  = "liveness"
+	routeReadiness = "readiness"
+)
+
+// Health check statuses
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// ReadinessCheck is a named check of a service dependency
+type ReadinessCheck struct {
+	// Name of the dependency, e.g. `database`
+	Name string
+	// Check returns an error if the dependency isn't ready. It should respect the context deadline
+	Check func(ctx context.Context) error
+}
+
+type (
+	// HealthResponse is a response structure of health endpoints
+	HealthResponse struct {
+		Status string                       `json:"status"`
+		Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+	}
+
+	// HealthCheckResult is a result of a single dependency check
+	HealthCheckResult struct {
+		Status   string `json:"status"`
+		Duration string `json:"duration"`
+		Error    string `json:"error,omitempty"`
+	}
+)
+
+// makeLivenessHandler creates a liveness probe handler.
+//
+// It responds 200 as long as the process is able to serve HTTP requests
+func makeLivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodeHealth(w, http.StatusOK, HealthResponse{Status: healthStatusOK})
+	})
+}
+
+// makeReadinessHandler creates a readiness probe handler.
+//
+// It runs all checks concurrently, each check is limited by the timeout.
+// It responds 200 if all dependencies are ready, otherwise 503
+func makeReadinessHandler(timeout time.Duration, checks []ReadinessCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := HealthResponse{
+			Status: healthStatusOK,
+			Checks: make(map[string]HealthCheckResult, len(checks)),
+		}
+
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		for _, c := range checks {
+			wg.Add(1)
+			go func(c ReadinessCheck) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+
+				begin := time.Now()
+				// don't wait for checks that ignore the context deadline
+				done := make(chan error, 1)
+				go func() {
+					done <- c.Check(ctx)
+				}()
+				var err error
+				select {
+				case err = <-done:
+				case <-ctx.Done():
+					err = ctx.Err()
+				}
+				result := HealthCheckResult{
+					Status:   healthStatusOK,
+					Duration: time.Since(begin).String(),
+				}
+				if err != nil {
+					result.Status = healthStatusFail
+					result.Error = err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				res.Checks[c.Name] = result
+				if err != nil {
+					res.Status = healthStatusFail
+				}
+			}(c)
+		}
+		wg.Wait()
+
+		code := http.StatusOK
+		if res.Status != healthStatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		encodeHealth(w, code, res)
+	})
+}
+
+// isProbeRequest checks if the request is routed to one of the health probes
+func isProbeRequest(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	name := route.GetName()
+	return name == routeLiveness || name == routeReadiness
+}
+
+func encodeHealth(w http.ResponseWriter, code int, res HealthResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
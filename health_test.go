@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	okCheck := func(context.Context) error { return nil }
+	failCheck := func(context.Context) error { return errors.New("test error") }
+	slowCheck := func(ctx context.Context) error {
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		checks     []ReadinessCheck
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "liveness",
+			path:       "/healthz",
+			checks:     []ReadinessCheck{{"database", failCheck}},
+			wantCode:   http.StatusOK,
+			wantStatus: healthStatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name:       "ready",
+			path:       "/readyz",
+			checks:     []ReadinessCheck{{"database", okCheck}, {"migrations", okCheck}},
+			wantCode:   http.StatusOK,
+			wantStatus: healthStatusOK,
+			wantChecks: map[string]string{"database": healthStatusOK, "migrations": healthStatusOK},
+		},
+		{
+			name:       "not ready",
+			path:       "/readyz",
+			checks:     []ReadinessCheck{{"database", okCheck}, {"migrations", failCheck}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: healthStatusFail,
+			wantChecks: map[string]string{"database": healthStatusOK, "migrations": healthStatusFail},
+		},
+		{
+			name:       "timeout",
+			path:       "/readyz",
+			checks:     []ReadinessCheck{{"database", slowCheck}},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: healthStatusFail,
+			wantChecks: map[string]string{"database": healthStatusFail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := MakeHTTPHandler(&WalletService{}, log.NewNopLogger(), WithReadinessChecks(10*time.Millisecond, tt.checks...))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("wrong status code %d, want %d", w.Code, tt.wantCode)
+			}
+			var res HealthResponse
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != tt.wantStatus {
+				t.Errorf("wrong status %s, want %s", res.Status, tt.wantStatus)
+			}
+			if len(res.Checks) != len(tt.wantChecks) {
+				t.Errorf("wrong checks %v, want %v", res.Checks, tt.wantChecks)
+			}
+			for name, status := range tt.wantChecks {
+				if res.Checks[name].Status != status {
+					t.Errorf("wrong %s check status %s, want %s", name, res.Checks[name].Status, status)
+				}
+			}
+		})
+	}
+}
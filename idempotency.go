@@ -0,0 +1,44 @@
+package wallet
+
+import (
+	"context"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is an HTTP header that carries the idempotency key of the request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is a maximum length of an idempotency key, it fits into the database column
+const maxIdempotencyKeyLength = 64
+
+type idempotencyKeyKey struct{}
+
+// ContextWithIdempotencyKey returns a context with an idempotency key of the call.
+//
+// A payment is created only once for each key of the payer, so the call can be safely retried after network errors.
+// The key is sent in the `Idempotency-Key` header
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns an idempotency key from the context or an empty string
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
+// decodeIdempotencyKey puts the idempotency key from the request header into the context
+func decodeIdempotencyKey(ctx context.Context, r *http.Request) context.Context {
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		return ContextWithIdempotencyKey(ctx, key)
+	}
+	return ctx
+}
+
+// encodeIdempotencyKey sets the idempotency key header from the context
+func encodeIdempotencyKey(ctx context.Context, r *http.Request) context.Context {
+	if key := IdempotencyKeyFromContext(ctx); key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return ctx
+}
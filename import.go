@@ -0,0 +1,204 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+// importMaxBytes is a maximum size of an import file
+const importMaxBytes = 32 << 20
+
+// importColumns are required columns of a CSV import file
+var importColumns = []string{"id", "currency", "balance"}
+
+// accountImportRecord is an account in a JSON Lines import file.
+//
+// The balance is a decimal number or string
+type accountImportRecord struct {
+	ID       string          `json:"id"`
+	Currency string          `json:"currency"`
+	Balance  json.RawMessage `json:"balance"`
+}
+
+// DecodeAccountImport reads account import rows from a CSV or JSON Lines file.
+//
+// A CSV file must have a header line with `id`, `currency` and `balance` columns in any order.
+// The rows are not validated, only a malformed file results in an error
+func DecodeAccountImport(r io.Reader, format string) ([]model.AccountImport, error) {
+	switch format {
+	case exportFormatCSV:
+		return decodeAccountImportCSV(r)
+	case exportFormatJSONL:
+		return decodeAccountImportJSONL(r)
+	default:
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "unknown import format %q, expected csv or jsonl", format)
+	}
+}
+
+func decodeAccountImportCSV(r io.Reader) ([]model.AccountImport, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "empty import file")
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid CSV file")
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, c := range importColumns {
+		if _, ok := columns[c]; !ok {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "missing %q column in CSV header", c)
+		}
+	}
+
+	var rows []model.AccountImport
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid CSV file")
+		}
+		rows = append(rows, model.AccountImport{
+			Line:     line,
+			ID:       strings.TrimSpace(rec[columns["id"]]),
+			Currency: strings.TrimSpace(rec[columns["currency"]]),
+			Balance:  strings.TrimSpace(rec[columns["balance"]]),
+		})
+	}
+}
+
+func decodeAccountImportJSONL(r io.Reader) ([]model.AccountImport, error) {
+	sc := bufio.NewScanner(r)
+
+	var rows []model.AccountImport
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var rec accountImportRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid JSON in line %d", line)
+		}
+		balance, err := importBalance(rec.Balance)
+		if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid balance in line %d", line)
+		}
+		rows = append(rows, model.AccountImport{
+			Line:     line,
+			ID:       rec.ID,
+			Currency: rec.Currency,
+			Balance:  balance,
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid JSON Lines file")
+	}
+	return rows, nil
+}
+
+// importBalance returns a JSON number or string as is, so it can be parsed with the currency precision
+func importBalance(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return strings.TrimSpace(s), err
+	}
+	var n json.Number
+	err := json.Unmarshal(raw, &n)
+	return n.String(), err
+}
+
+// importFormat returns the import format from the query or the content type, CSV by default
+func importFormat(r *http.Request) (string, error) {
+	if r.URL.Query().Get("format") == "" {
+		switch ct := r.Header.Get("Content-Type"); {
+		case strings.HasPrefix(ct, "application/x-ndjson"), strings.HasPrefix(ct, "application/jsonl"):
+			return exportFormatJSONL, nil
+		}
+	}
+	return exportFormat(r.URL.Query())
+}
+
+func decodeImportAccountsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	format, err := importFormat(r)
+	if err != nil {
+		return nil, err
+	}
+	var dryRun bool
+	if v := r.URL.Query().Get("dry-run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "invalid dry-run value %q", v)
+		}
+	}
+
+	rows, err := DecodeAccountImport(http.MaxBytesReader(nil, r.Body, importMaxBytes), format)
+	if err != nil {
+		return nil, err
+	}
+	return ImportAccountsRequest{
+		Rows:   rows,
+		DryRun: dryRun,
+	}, nil
+}
+
+// encodeImportAccountsRequest sends the rows as JSON Lines, balances are sent as strings
+func encodeImportAccountsRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(ImportAccountsRequest)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, row := range r.Rows {
+		balance, err := json.Marshal(row.Balance)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(accountImportRecord{
+			ID:       row.ID,
+			Currency: row.Currency,
+			Balance:  balance,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	q := req.URL.Query()
+	q.Set("format", exportFormatJSONL)
+	q.Set("dry-run", strconv.FormatBool(r.DryRun))
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	req.ContentLength = int64(buf.Len())
+	req.Body = ioutil.NopCloser(&buf)
+	return nil
+}
+
+// decodeImportAccountsResponse decodes the import result, which is also sent with the rejected rows
+func decodeImportAccountsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest && r.StatusCode != http.StatusUnprocessableEntity {
+		return nil, decodeError(r)
+	}
+	var res ImportAccountsResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+func TestImportAccountsEndpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		db          *TestDatabase
+		wantCode    int
+		want        ImportAccountsResponse
+		wantCreated []model.Account
+	}{
+		{
+			name:     "csv",
+			path:     "/api/accounts/import",
+			body:     "\ufeffBalance,ID,Currency\n12.30, alice ,USD\n0.005,bob,BHD\n",
+			db:       &TestDatabase{},
+			wantCode: http.StatusOK,
+			want:     ImportAccountsResponse{Imported: 2},
+			wantCreated: []model.Account{
+				{ID: "alice", Balance: currency.NewAmount(1230), Currency: currency.USD},
+				{ID: "bob", Balance: currency.NewAmount(5), Currency: currency.BHD},
+			},
+		},
+		{
+			name:        "jsonl by content type",
+			path:        "/api/accounts/import?dry-run=true",
+			contentType: "application/x-ndjson",
+			body:        `{"id":"alice","currency":"USD","balance":12.3}` + "\n\n" + `{"id":"bob","currency":"CLP","balance":"1500"}` + "\n",
+			db:          &TestDatabase{},
+			wantCode:    http.StatusOK,
+			want:        ImportAccountsResponse{Imported: 2, DryRun: true},
+			wantCreated: []model.Account{
+				{ID: "alice", Balance: currency.NewAmount(1230), Currency: currency.USD},
+				{ID: "bob", Balance: currency.NewAmount(1500), Currency: currency.CLP},
+			},
+		},
+		{
+			name:     "invalid rows",
+			path:     "/api/accounts/import?format=jsonl",
+			body:     `{"id":"alice","currency":"USD","balance":-1}` + "\n" + `{"id":"bob","currency":"USD"}` + "\n",
+			db:       &TestDatabase{},
+			wantCode: http.StatusUnprocessableEntity,
+			want: ImportAccountsResponse{Errors: []ImportRowError{
+				{Line: 1, ID: "alice", Error: "negative balance -1"},
+				{Line: 2, ID: "bob", Error: "empty balance"},
+			}},
+		},
+		{
+			name:     "missing column",
+			path:     "/api/accounts/import",
+			body:     "id,balance\nalice,1\n",
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "malformed csv",
+			path:     "/api/accounts/import",
+			body:     "id,currency,balance\nalice,USD\n",
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "malformed jsonl",
+			path:     "/api/accounts/import?format=jsonl",
+			body:     `{"id":"alice",`,
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid dry run",
+			path:     "/api/accounts/import?dry-run=maybe",
+			body:     "id,currency,balance\nalice,USD,1\n",
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := MakeHTTPHandler(NewWalletService(tt.db), log.NewNopLogger())
+
+			r := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("wrong status code %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if !reflect.DeepEqual(tt.db.created, tt.wantCreated) {
+				t.Errorf("wrong created accounts %v, want %v", tt.db.created, tt.wantCreated)
+			}
+			if tt.wantCode == http.StatusBadRequest {
+				return
+			}
+			var got ImportAccountsResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong response %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package wallet
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/xerrors"
+)
+
+// Payment rejection reason labels
+const (
+	reasonNotFound          = "not_found"
+	reasonCurrencyMismatch  = "currency_mismatch"
+	reasonInsufficientFunds = "insufficient_funds"
+	reasonConflict          = "conflict"
+	reasonDuplicate         = "duplicate_reference"
+	reasonOther             = "other"
+)
+
+// Metrics is a set of application metrics
+type Metrics struct {
+	// RequestCount counts HTTP requests by endpoint and status code
+	RequestCount metrics.Counter
+	// RequestLatency observes HTTP request duration in seconds by endpoint and status code
+	RequestLatency metrics.Histogram
+	// PaymentsCreated counts successful payments by currency
+	PaymentsCreated metrics.Counter
+	// AmountMoved sums amounts of successful payments by currency
+	AmountMoved metrics.Counter
+	// PaymentsRejected counts declined payments by reason
+	PaymentsRejected metrics.Counter
+	// QueryLatency observes database query duration in seconds by query
+	QueryLatency metrics.Histogram
+	// LockConflicts counts payments declined due to concurrent account changes
+	LockConflicts metrics.Counter
+}
+
+// NewPrometheusMetrics creates a set of metrics and registers them in the default Prometheus registry
+func NewPrometheusMetrics() *Metrics {
+	const namespace = "tiny_wallet"
+	return &Metrics{
+		RequestCount: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests processed.",
+		}, []string{"endpoint", "code"}),
+		RequestLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests in seconds.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"endpoint", "code"}),
+		PaymentsCreated: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "payments",
+			Name:      "created_total",
+			Help:      "Number of payments created.",
+		}, []string{"currency"}),
+		AmountMoved: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "payments",
+			Name:      "amount_total",
+			Help:      "Total amount of money moved between accounts.",
+		}, []string{"currency"}),
+		PaymentsRejected: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "payments",
+			Name:      "rejected_total",
+			Help:      "Number of payments rejected.",
+		}, []string{"reason"}),
+		QueryLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "database",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of database queries in seconds.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"query"}),
+		LockConflicts: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "database",
+			Name:      "lock_conflicts_total",
+			Help:      "Number of optimistic lock conflicts on payment creation.",
+		}, []string{}),
+	}
+}
+
+// instrumentingService is a Service middleware that collects business metrics
+type instrumentingService struct {
+	Service
+	m *Metrics
+}
+
+// NewInstrumentingService wraps the service with a business metrics middleware
+func NewInstrumentingService(s Service, m *Metrics) Service {
+	return &instrumentingService{s, m}
+}
+
+// PostPayment counts created payments, moved amounts and rejected payments
+func (s *instrumentingService) PostPayment(ctx context.Context, fromID, toID string, amount float64, info model.PaymentInfo) (*model.Payment, error) {
+	p, err := s.Service.PostPayment(ctx, fromID, toID, amount, info)
+	if err != nil {
+		s.m.PaymentsRejected.With("reason", rejectionReason(err)).Add(1)
+		return p, err
+	}
+	s.m.PaymentsCreated.With("currency", string(p.Currency)).Add(1)
+	s.m.AmountMoved.With("currency", string(p.Currency)).Add(currency.ConvertToExternal(p.Amount, p.Currency))
+	return p, nil
+}
+
+// PostSplitPayment counts each payment of the split payment, the moved total and rejected split payments
+func (s *instrumentingService) PostSplitPayment(ctx context.Context, fromID string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error) {
+	g, err := s.Service.PostSplitPayment(ctx, fromID, amount, receivers, info)
+	if err != nil {
+		s.m.PaymentsRejected.With("reason", rejectionReason(err)).Add(1)
+		return g, err
+	}
+	s.m.PaymentsCreated.With("currency", string(g.Currency)).Add(float64(len(g.Payments)))
+	s.m.AmountMoved.With("currency", string(g.Currency)).Add(currency.ConvertToExternal(g.Amount, g.Currency))
+	return g, nil
+}
+
+// rejectionReason returns a metrics label describing why the payment was rejected
+func rejectionReason(err error) string {
+	switch {
+	case xerrors.Is(err, ErrAccountNotFound):
+		return reasonNotFound
+	case xerrors.Is(err, ErrCurrencyMismatch):
+		return reasonCurrencyMismatch
+	case xerrors.Is(err, ErrInsufficientFunds):
+		return reasonInsufficientFunds
+	case xerrors.Is(err, model.ErrConflict):
+		return reasonConflict
+	case xerrors.Is(err, ErrDuplicateReference):
+		return reasonDuplicate
+	default:
+		return reasonOther
+	}
+}
+
+// instrumentingDatabase is a Database middleware that collects query metrics
+type instrumentingDatabase struct {
+	db Database
+	m  *Metrics
+}
+
+// NewInstrumentingDatabase wraps the database with a query metrics middleware
+func NewInstrumentingDatabase(db Database, m *Metrics) Database {
+	return &instrumentingDatabase{db, m}
+}
+
+// observe records the query duration since begin
+func (d *instrumentingDatabase) observe(query string, begin time.Time) {
+	d.m.QueryLatency.With("query", query).Observe(time.Since(begin).Seconds())
+}
+
+// GetAllAccounts measures the GetAllAccounts query
+func (d *instrumentingDatabase) GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error) {
+	defer d.observe("GetAllAccounts", time.Now())
+	return d.db.GetAllAccounts(ctx, filter)
+}
+
+// GetAllPayments measures the GetAllPayments query
+func (d *instrumentingDatabase) GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error) {
+	defer d.observe("GetAllPayments", time.Now())
+	return d.db.GetAllPayments(ctx, filter)
+}
+
+// GetAccount measures the GetAccount query
+func (d *instrumentingDatabase) GetAccount(ctx context.Context, accountID string) (*model.Account, error) {
+	defer d.observe("GetAccount", time.Now())
+	return d.db.GetAccount(ctx, accountID)
+}
+
+// GetPaymentByIdempotencyKey measures the GetPaymentByIdempotencyKey query
+func (d *instrumentingDatabase) GetPaymentByIdempotencyKey(ctx context.Context, accountFromID, key string) (*model.Payment, error) {
+	defer d.observe("GetPaymentByIdempotencyKey", time.Now())
+	return d.db.GetPaymentByIdempotencyKey(ctx, accountFromID, key)
+}
+
+// CreatePayment measures the CreatePayment transaction and counts optimistic lock conflicts
+func (d *instrumentingDatabase) CreatePayment(ctx context.Context, p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (*model.Payment, error) {
+	defer d.observe("CreatePayment", time.Now())
+	res, err := d.db.CreatePayment(ctx, p, lastChangedFrom, lastChangedTo)
+	if xerrors.Is(err, model.ErrConflict) {
+		d.m.LockConflicts.Add(1)
+	}
+	return res, err
+}
+
+// CreatePaymentGroup measures the CreatePaymentGroup transaction and counts optimistic lock conflicts
+func (d *instrumentingDatabase) CreatePaymentGroup(ctx context.Context, g model.PaymentGroup, lastChanged map[string]*time.Time) (*model.PaymentGroup, error) {
+	defer d.observe("CreatePaymentGroup", time.Now())
+	res, err := d.db.CreatePaymentGroup(ctx, g, lastChanged)
+	if xerrors.Is(err, model.ErrConflict) {
+		d.m.LockConflicts.Add(1)
+	}
+	return res, err
+}
+
+// CreateAccount measures the CreateAccount query
+func (d *instrumentingDatabase) CreateAccount(ctx context.Context, a model.Account) (*model.Account, error) {
+	defer d.observe("CreateAccount", time.Now())
+	return d.db.CreateAccount(ctx, a)
+}
+
+// ExportPayments measures the ExportPayments query including the time of row processing
+func (d *instrumentingDatabase) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error {
+	defer d.observe("ExportPayments", time.Now())
+	return d.db.ExportPayments(ctx, from, to, fn)
+}
+
+// ExportAccounts measures the ExportAccounts query including the time of row processing
+func (d *instrumentingDatabase) ExportAccounts(ctx context.Context, fn func(model.Account) error) error {
+	defer d.observe("ExportAccounts", time.Now())
+	return d.db.ExportAccounts(ctx, fn)
+}
+
+// CreateAccounts measures the CreateAccounts transaction
+func (d *instrumentingDatabase) CreateAccounts(ctx context.Context, accounts []model.Account, dryRun bool) ([]int, error) {
+	defer d.observe("CreateAccounts", time.Now())
+	return d.db.CreateAccounts(ctx, accounts, dryRun)
+}
+
+// UpdateAccountInfo measures the UpdateAccountInfo query
+func (d *instrumentingDatabase) UpdateAccountInfo(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error) {
+	defer d.observe("UpdateAccountInfo", time.Now())
+	return d.db.UpdateAccountInfo(ctx, id, patch)
+}
+
+// CreateWallet measures the CreateWallet transaction
+func (d *instrumentingDatabase) CreateWallet(ctx context.Context, w model.Wallet) (*model.Wallet, error) {
+	defer d.observe("CreateWallet", time.Now())
+	return d.db.CreateWallet(ctx, w)
+}
+
+// GetWallet measures the GetWallet queries
+func (d *instrumentingDatabase) GetWallet(ctx context.Context, id string) (*model.Wallet, error) {
+	defer d.observe("GetWallet", time.Now())
+	return d.db.GetWallet(ctx, id)
+}
+
+// GetTrialBalance measures the GetTrialBalance query
+func (d *instrumentingDatabase) GetTrialBalance(ctx context.Context) ([]model.TrialBalanceAccount, error) {
+	defer d.observe("GetTrialBalance", time.Now())
+	return d.db.GetTrialBalance(ctx)
+}
+
+// GetEndOfDayBalances measures the GetEndOfDayBalances query
+func (d *instrumentingDatabase) GetEndOfDayBalances(ctx context.Context, types []string, end time.Time) ([]model.Account, error) {
+	defer d.observe("GetEndOfDayBalances", time.Now())
+	return d.db.GetEndOfDayBalances(ctx, types, end)
+}
+
+// CreateInterestAccruals measures the CreateInterestAccruals transaction
+func (d *instrumentingDatabase) CreateInterestAccruals(ctx context.Context, accruals []model.InterestAccrual) (int, error) {
+	defer d.observe("CreateInterestAccruals", time.Now())
+	return d.db.CreateInterestAccruals(ctx, accruals)
+}
+
+// PayInterest measures the PayInterest transaction
+func (d *instrumentingDatabase) PayInterest(ctx context.Context, accountID, expenseAccountID string, upTo time.Time) (*model.Payment, error) {
+	defer d.observe("PayInterest", time.Now())
+	return d.db.PayInterest(ctx, accountID, expenseAccountID, upTo)
+}
+
+// GetInterestAccruals measures the GetInterestAccruals query
+func (d *instrumentingDatabase) GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error) {
+	defer d.observe("GetInterestAccruals", time.Now())
+	return d.db.GetInterestAccruals(ctx, accountID)
+}
+
+// CreateRates measures the CreateRates transaction
+func (d *instrumentingDatabase) CreateRates(ctx context.Context, rates []currency.Rate) (int, error) {
+	defer d.observe("CreateRates", time.Now())
+	return d.db.CreateRates(ctx, rates)
+}
+
+// GetRate measures the GetRate query
+func (d *instrumentingDatabase) GetRate(ctx context.Context, base, quote currency.Currency, at time.Time) (*currency.Rate, error) {
+	defer d.observe("GetRate", time.Now())
+	return d.db.GetRate(ctx, base, quote, at)
+}
+
+// statusRecorder is an http.ResponseWriter that remembers the response status code
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+// WriteHeader saves the status code and writes it into the response
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush sends buffered data to the client if the underlying writer supports it
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// makeInstrumentingMiddleware creates a router middleware that counts requests and measures their latency.
+//
+// The endpoint label is a path template of the matched route
+func makeInstrumentingMiddleware(m *Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{w, http.StatusOK}
+			defer func(begin time.Time) {
+				endpoint := "unknown"
+				if route := mux.CurrentRoute(r); route != nil {
+					if tpl, err := route.GetPathTemplate(); err == nil {
+						endpoint = tpl
+					}
+				}
+				code := strconv.Itoa(rec.code)
+				m.RequestCount.With("endpoint", endpoint, "code", code).Add(1)
+				m.RequestLatency.With("endpoint", endpoint, "code", code).Observe(time.Since(begin).Seconds())
+			}(time.Now())
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+// testCounter is a counter that sums values by label values
+type testCounter struct {
+	values map[string]float64
+	lvs    []string
+}
+
+func newTestCounter() *testCounter {
+	return &testCounter{values: make(map[string]float64)}
+}
+
+func (c *testCounter) With(labelValues ...string) metrics.Counter {
+	return &testCounter{
+		values: c.values,
+		lvs:    append(append([]string{}, c.lvs...), labelValues...),
+	}
+}
+
+func (c *testCounter) Add(delta float64) {
+	c.values[strings.Join(c.lvs, ",")] += delta
+}
+
+func TestInstrumentingServicePostPayment(t *testing.T) {
+	now := time.Now()
+	accounts := map[string]testDatabaseData{
+		"1": testDatabaseData{
+			dat: &model.Account{ID: "1", LastUpdate: &now, Balance: currency.NewAmount(12345), Currency: currency.USD},
+		},
+		"2": testDatabaseData{
+			dat: &model.Account{ID: "2", LastUpdate: &now, Balance: currency.NewAmount(67890), Currency: currency.USD},
+		},
+		"3": testDatabaseData{
+			dat: &model.Account{ID: "3", LastUpdate: &now, Balance: currency.NewAmount(67890), Currency: currency.CAD},
+		},
+		"4": testDatabaseData{
+			dat: &model.Account{},
+			err: sql.ErrNoRows,
+		},
+	}
+
+	tests := []struct {
+		name         string
+		fromID       string
+		toID         string
+		amount       float64
+		createErr    error
+		wantCreated  map[string]float64
+		wantMoved    map[string]float64
+		wantRejected map[string]float64
+	}{
+		{
+			name:         "created",
+			fromID:       "1",
+			toID:         "2",
+			amount:       12.5,
+			wantCreated:  map[string]float64{"currency,USD": 1},
+			wantMoved:    map[string]float64{"currency,USD": 12.5},
+			wantRejected: map[string]float64{},
+		},
+		{
+			name:         "insufficient funds",
+			fromID:       "1",
+			toID:         "2",
+			amount:       500,
+			wantCreated:  map[string]float64{},
+			wantMoved:    map[string]float64{},
+			wantRejected: map[string]float64{"reason,insufficient_funds": 1},
+		},
+		{
+			name:         "currency mismatch",
+			fromID:       "1",
+			toID:         "3",
+			amount:       1,
+			wantCreated:  map[string]float64{},
+			wantMoved:    map[string]float64{},
+			wantRejected: map[string]float64{"reason,currency_mismatch": 1},
+		},
+		{
+			name:         "not found",
+			fromID:       "1",
+			toID:         "4",
+			amount:       1,
+			wantCreated:  map[string]float64{},
+			wantMoved:    map[string]float64{},
+			wantRejected: map[string]float64{"reason,not_found": 1},
+		},
+		{
+			name:         "conflict",
+			fromID:       "1",
+			toID:         "2",
+			amount:       1,
+			createErr:    model.ErrConflict,
+			wantCreated:  map[string]float64{},
+			wantMoved:    map[string]float64{},
+			wantRejected: map[string]float64{"reason,conflict": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, moved, rejected := newTestCounter(), newTestCounter(), newTestCounter()
+			m := &Metrics{
+				PaymentsCreated:  created,
+				AmountMoved:      moved,
+				PaymentsRejected: rejected,
+			}
+			db := &TestDatabase{
+				GetAccountData: accounts,
+				CreatePaymentData: testDatabaseData{
+					dat: &model.Payment{AccFromID: tt.fromID, AccToID: tt.toID, DateTime: now, Amount: toInternal(tt.amount, currency.USD)},
+					err: tt.createErr,
+				},
+			}
+			s := NewInstrumentingService(NewWalletService(db), m)
+			s.PostPayment(context.Background(), tt.fromID, tt.toID, tt.amount, model.PaymentInfo{})
+
+			if !reflect.DeepEqual(created.values, tt.wantCreated) {
+				t.Errorf("wrong created payments %v, want %v", created.values, tt.wantCreated)
+			}
+			if !reflect.DeepEqual(moved.values, tt.wantMoved) {
+				t.Errorf("wrong moved amount %v, want %v", moved.values, tt.wantMoved)
+			}
+			if !reflect.DeepEqual(rejected.values, tt.wantRejected) {
+				t.Errorf("wrong rejected payments %v, want %v", rejected.values, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestRejectionReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not found", NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account not found"), reasonNotFound},
+		{"currency", NewErrHTTPStatusf(http.StatusBadRequest, ErrCurrencyMismatch, "different currencies"), reasonCurrencyMismatch},
+		{"funds", NewErrHTTPStatusf(http.StatusBadRequest, ErrInsufficientFunds, "not enough money"), reasonInsufficientFunds},
+		{"conflict", NewErrHTTPStatusf(http.StatusConflict, model.ErrConflict, "conflict"), reasonConflict},
+		{"duplicate", NewErrHTTPStatusf(http.StatusConflict, ErrDuplicateReference, "duplicate reference"), reasonDuplicate},
+		{"other", NewErrHTTPStatusf(http.StatusInternalServerError, testDatabaseErr, "unexpected error"), reasonOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rejectionReason(tt.err); got != tt.want {
+				t.Errorf("wrong reason %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+// InterestJob accrues daily interest on accounts of interest-bearing types and pays it out at the end of each compounding period.
+//
+// The job should run once a day after midnight UTC for the previous day. A rerun for the same day doesn't accrue or pay twice
+type InterestJob struct {
+	db    Database
+	types map[string]model.AccountType
+	// expenseAccount is a wallet the interest is paid from, it should have a pocket in each currency of interest-bearing accounts
+	expenseAccount string
+}
+
+// NewInterestJob creates a new interest job.
+//
+// The interest in each currency is paid from the pocket of the expense wallet, e.g. `interest-expense/USD`
+func NewInterestJob(db Database, types []model.AccountType, expenseAccount string) *InterestJob {
+	j := &InterestJob{
+		db:             db,
+		types:          make(map[string]model.AccountType, len(types)),
+		expenseAccount: expenseAccount,
+	}
+	for _, t := range types {
+		j.types[t.Name] = t
+	}
+	return j
+}
+
+// Run accrues interest on end-of-day ledger balances of the day and pays out accrued interest of accounts whose compounding period ends on the day
+func (j *InterestJob) Run(ctx context.Context, day time.Time) (*model.InterestRun, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	res := &model.InterestRun{Date: day}
+
+	names := make([]string, 0, len(j.types))
+	for name := range j.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	accounts, err := j.db.GetEndOfDayBalances(ctx, names, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, xerrors.Errorf("end-of-day balances: %w", err)
+	}
+
+	accruals := make([]model.InterestAccrual, 0, len(accounts))
+	for _, a := range accounts {
+		t := j.types[a.Type]
+		interest, err := t.DailyInterest(a.Balance, day)
+		if err != nil {
+			return nil, xerrors.Errorf("interest on %s: %w", a.ID, err)
+		}
+		accruals = append(accruals, model.InterestAccrual{
+			AccountID: a.ID,
+			Date:      day,
+			Balance:   a.Balance,
+			Rate:      t.AnnualRate,
+			Amount:    interest,
+			Currency:  a.Currency,
+		})
+	}
+	if res.Accrued, err = j.db.CreateInterestAccruals(ctx, accruals); err != nil {
+		return nil, xerrors.Errorf("interest accrual: %w", err)
+	}
+
+	for _, a := range accounts {
+		if !j.types[a.Type].PayoutDue(day) {
+			continue
+		}
+		p, err := j.db.PayInterest(ctx, a.ID, model.PocketID(j.expenseAccount, a.Currency), day)
+		if err != nil {
+			return res, xerrors.Errorf("interest payout to %s: %w", a.ID, err)
+		}
+		if p != nil {
+			res.Payouts = append(res.Payouts, *p)
+		}
+	}
+	return res, nil
+}
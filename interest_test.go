@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+func TestInterestJobRun(t *testing.T) {
+	types := []model.AccountType{
+		{Name: "savings", AnnualRate: 0.0365, Compounding: model.CompoundingMonthly},
+		{Name: "deposit", AnnualRate: 0.0365, Compounding: model.CompoundingAnnually},
+	}
+	accounts := []model.Account{
+		{ID: "alice", Balance: currency.NewAmount(100000), Currency: currency.USD, AccountInfo: model.AccountInfo{Type: "savings"}},
+		{ID: "bob", Balance: currency.NewAmount(1000), Currency: currency.EUR, AccountInfo: model.AccountInfo{Type: "deposit"}},
+		{ID: "carol", Balance: currency.NewAmount(-500), Currency: currency.USD, AccountInfo: model.AccountInfo{Type: "savings"}},
+	}
+	tests := []struct {
+		name        string
+		day         time.Time
+		wantAmounts []int64
+		wantPayers  map[string]string
+	}{
+		{
+			name:        "middle of month",
+			day:         time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC),
+			wantAmounts: []int64{10, 0, 0},
+			wantPayers:  map[string]string{},
+		},
+		{
+			name:        "end of month",
+			day:         time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+			wantAmounts: []int64{10, 0, 0},
+			wantPayers:  map[string]string{"alice": "interest-expense/USD", "carol": "interest-expense/USD"},
+		},
+		{
+			name:        "end of year",
+			day:         time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+			wantAmounts: []int64{10, 0, 0},
+			wantPayers: map[string]string{
+				"alice": "interest-expense/USD",
+				"bob":   "interest-expense/EUR",
+				"carol": "interest-expense/USD",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				EndOfDayData: testDatabaseData{dat: accounts},
+				PayInterestData: map[string]testDatabaseData{
+					"alice": {dat: &model.Payment{AccToID: "alice", Amount: currency.NewAmount(310), Currency: currency.USD}},
+				},
+				payers: map[string]string{},
+			}
+			res, err := NewInterestJob(db, types, "interest-expense").Run(context.Background(), tt.day.Add(15*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Date.Equal(tt.day) || res.Accrued != len(accounts) {
+				t.Errorf("wrong run %v %v, want %v %v", res.Date, res.Accrued, tt.day, len(accounts))
+			}
+			for i, a := range db.accruals {
+				if a.Amount != currency.NewAmount(tt.wantAmounts[i]) {
+					t.Errorf("wrong %s interest %v, want %v", a.AccountID, a.Amount, tt.wantAmounts[i])
+				}
+			}
+			if len(db.payers) != len(tt.wantPayers) {
+				t.Fatalf("wrong payouts %v, want %v", db.payers, tt.wantPayers)
+			}
+			for acc, payer := range tt.wantPayers {
+				if db.payers[acc] != payer {
+					t.Errorf("wrong %s payer %v, want %v", acc, db.payers[acc], payer)
+				}
+			}
+			// only alice has unpaid interest
+			wantPayouts := 0
+			if _, ok := tt.wantPayers["alice"]; ok {
+				wantPayouts = 1
+			}
+			if len(res.Payouts) != wantPayouts {
+				t.Errorf("wrong payouts %v, want %v", len(res.Payouts), wantPayouts)
+			}
+		})
+	}
+}
@@ -9,6 +9,8 @@
 // 	- Heroku: if the environment variable `HEROKU` is set, the method overrides `MainConfig.Server.Port` value from `PORT` environment variable
 package config
 
+import "time"
+
 // MainConfig is a structure of the application configuration
 // This describes a configuration file structure
 // Each variable can be overridden with the environment variable
@@ -16,6 +18,10 @@ package config
 type MainConfig struct {
 	Server   ServerConfig   `yaml:"server"`
 	Database DatabaseConfig `yaml:"database"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Rates    RatesConfig    `yaml:"rates"`
+	Interest InterestConfig `yaml:"interest"`
+	Currency CurrencyConfig `yaml:"currency"`
 }
 
 // ServerConfig is a set of application server configuration variables
@@ -25,6 +31,14 @@ type ServerConfig struct {
 	Host string `yaml:"host" env:"SERVER_HOST" env-description:"application server host"`
 	// Host is an application server port
 	Port string `yaml:"port" env:"SERVER_PORT" env-description:"application server port"`
+	// GRPCPort is a gRPC server port. The gRPC server is disabled if the port is empty
+	GRPCPort string `yaml:"grpc-port" env:"SERVER_GRPC_PORT" env-description:"gRPC server port, disabled if empty"`
+	// ReadinessTimeout is a time limit of each dependency check of the readiness probe
+	ReadinessTimeout time.Duration `yaml:"readiness-timeout" env:"SERVER_READINESS_TIMEOUT" env-default:"2s" env-description:"readiness probe dependency check timeout"`
+	// DrainDelay is a time between the readiness probe failure and the listener shutdown, so load balancers notice that the instance is draining
+	DrainDelay time.Duration `yaml:"drain-delay" env:"SERVER_DRAIN_DELAY" env-default:"5s" env-description:"delay between failing the readiness probe and closing listeners on shutdown, at least one probe period"`
+	// DrainTimeout is a time limit for in-flight requests to complete on shutdown
+	DrainTimeout time.Duration `yaml:"drain-timeout" env:"SERVER_DRAIN_TIMEOUT" env-default:"20s" env-description:"graceful shutdown timeout"`
 }
 
 // DatabaseConfig is a set of database configuration variables
@@ -48,4 +62,79 @@ type DatabaseConfig struct {
 	ConnectionPool int `yaml:"conn-pool" env:"DATABASE_CONN_POOL" env-description:"database connection pool size"`
 	// ConnectionWait wait until the database will up in the infinite loop
 	ConnectionWait bool `yaml:"conn-wait" env:"DATABASE_CONN_WAIT" env-description:"wait until database up"`
+	// AutoMigrate applies pending schema migrations on startup
+	AutoMigrate bool `yaml:"auto-migrate" env:"DATABASE_AUTO_MIGRATE" env-description:"apply pending migrations on startup"`
+}
+
+// TracingConfig is a set of distributed tracing configuration variables
+// Each variable can be overridden with the environment variable
+type TracingConfig struct {
+	// Exporter is a span exporter type: `none`, `stdout` or `otlp`
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none" env-description:"span exporter: none, stdout or otlp"`
+	// OTLPEndpoint is a base URL of OpenTelemetry collector OTLP/HTTP receiver
+	OTLPEndpoint string `yaml:"otlp-endpoint" env:"TRACING_OTLP_ENDPOINT" env-default:"http://localhost:4318" env-description:"OpenTelemetry collector OTLP/HTTP endpoint"`
+	// ServiceName is a name of the service in exported traces
+	ServiceName string `yaml:"service-name" env:"TRACING_SERVICE_NAME" env-default:"tiny-wallet" env-description:"service name in traces"`
+}
+
+// RatesConfig is an initial exchange rate table, it is saved into the exchange rate history on startup for currency pairs without stored rates
+// Each variable can be overridden with the environment variable
+type RatesConfig struct {
+	// Base is a currency the rates are quoted in. Wallet totals are calculated in this currency by default
+	Base string `yaml:"base" env:"RATES_BASE" env-default:"USD" env-description:"base currency of exchange rates"`
+	// Rates are prices of one unit of each currency in the base currency
+	Rates map[string]float64 `yaml:"rates" env:"RATES" env-description:"exchange rates to the base currency, e.g. EUR:1.1,GBP:1.27"`
+}
+
+// InterestConfig is a set of interest-bearing account types and the account the interest is paid from
+// Each variable can be overridden with the environment variable
+type InterestConfig struct {
+	// ExpenseAccount is a wallet with a pocket in each currency of interest-bearing accounts, the interest is paid from its pockets
+	ExpenseAccount string `yaml:"expense-account" env:"INTEREST_EXPENSE_ACCOUNT" env-default:"interest-expense" env-description:"wallet the interest is paid from"`
+	// Types are interest-bearing account types by name
+	Types map[string]AccountTypeConfig `yaml:"types"`
+}
+
+// AccountTypeConfig is an interest-bearing account type
+type AccountTypeConfig struct {
+	// Rate is an annual interest rate, e.g. 0.02 for 2%
+	Rate float64 `yaml:"rate"`
+	// Compounding is a period of interest payouts: `daily`, `monthly`, `quarterly` or `annually`
+	Compounding string `yaml:"compounding"`
+}
+
+// CurrencyConfig is a set of money amount processing variables
+// Each variable can be overridden with the environment variable
+type CurrencyConfig struct {
+	// Rounding is a rounding mode of amounts with more decimals than the currency allows: `half-even`, `half-up`, `down`, `up`, `ceiling` or `floor`
+	Rounding string `yaml:"rounding" env:"CURRENCY_ROUNDING" env-default:"half-even" env-description:"amount rounding mode: half-even, half-up, down, up, ceiling or floor"`
+	// Custom are non-ISO currencies, e.g. loyalty points or crypto assets. More custom currencies are loaded from the database
+	Custom []CustomCurrencyConfig `yaml:"custom"`
+}
+
+// CustomCurrencyConfig is a non-ISO currency
+type CustomCurrencyConfig struct {
+	// Code is 3 to 10 upper case letters and digits, e.g. `POINTS`
+	Code string `yaml:"code"`
+	// Name is a currency name
+	Name string `yaml:"name"`
+	// Decimals is a number of decimal places from 0 to 18
+	Decimals int `yaml:"decimals"`
+}
+
+// CtlConfig is a configuration of the `walletctl` command-line client
+// Each variable can be overridden with the environment variable
+type CtlConfig struct {
+	// URL is a base URL of the wallet service
+	URL string `yaml:"url" env:"WALLETCTL_URL" env-default:"http://localhost:8080" env-description:"wallet service URL"`
+	// Token is a bearer token sent in the Authorization header
+	Token string `yaml:"token" env:"WALLETCTL_TOKEN" env-description:"bearer token"`
+	// Username is a user name for the basic authentication
+	Username string `yaml:"username" env:"WALLETCTL_USERNAME" env-description:"basic authentication user name"`
+	// Password is a password for the basic authentication
+	Password string `yaml:"password" env:"WALLETCTL_PASSWORD" env-description:"basic authentication password"`
+	// Timeout is a time limit of a single request
+	Timeout time.Duration `yaml:"timeout" env:"WALLETCTL_TIMEOUT" env-default:"10s" env-description:"request timeout"`
+	// Output is a default output format: `table`, `json` or `csv`
+	Output string `yaml:"output" env:"WALLETCTL_OUTPUT" env-default:"table" env-description:"output format: table, json or csv"`
 }
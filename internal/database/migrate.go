@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// migrationLockID is a key of the Postgres advisory lock that serializes migrations between application replicas
+const migrationLockID = 42170001
+
+// migrationFileName is a pattern of migration file names: `<version>_<name>.<up|down>.sql`
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrSchemaVersion means that the database schema version doesn't match the application
+var ErrSchemaVersion = errors.New("unexpected database schema version")
+
+// Migration is a database schema change
+type Migration struct {
+	Version int
+	Name    string
+	// Up is an SQL script that applies the change
+	Up string
+	// Down is an SQL script that reverts the change
+	Down string
+}
+
+// MigrationStatus is a migration with its application state
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is a time of migration application or nil if the migration isn't applied
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads migration files from the file system root.
+//
+// Each migration should have both up and down scripts. Migrations are sorted by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, f := range files {
+		match := migrationFileName.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names %s and %s", version, m.Name, match[2])
+		}
+
+		data, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s should have both up and down scripts", m.Version, m.Name)
+		}
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	return res, nil
+}
+
+// Migrator applies and reverts database schema migrations.
+//
+// Applied migration versions are tracked in the `schema_migrations` table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a new migrator with a set of migrations sorted by version
+func (pg *PostgresClient) NewMigrator(migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         pg.db,
+		migrations: migrations,
+	}
+}
+
+// Latest returns a version of the last known migration
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations.
+//
+// The method takes a Postgres advisory lock, so concurrent application replicas wait for each other instead of racing.
+// Each migration runs in its own transaction. Returns a list of applied migrations
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := versions[mg.Version]; ok {
+				continue
+			}
+			err := runMigration(ctx, conn, mg.Up, `INSERT INTO schema_migrations (version) VALUES ($1)`, mg.Version)
+			if err != nil {
+				return xerrors.Errorf("migration %d_%s up: %w", mg.Version, mg.Name, err)
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the given number of the latest applied migrations.
+//
+// The method takes the same advisory lock as `Up`. Returns a list of reverted migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := versions[mg.Version]; !ok {
+				continue
+			}
+			err := runMigration(ctx, conn, mg.Down, `DELETE FROM schema_migrations WHERE version = $1`, mg.Version)
+			if err != nil {
+				return xerrors.Errorf("migration %d_%s down: %w", mg.Version, mg.Name, err)
+			}
+			reverted = append(reverted, mg)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns all known migrations with their application time
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := prepareMigrationTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	versions, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := MigrationStatus{Migration: mg}
+		if t, ok := versions[mg.Version]; ok {
+			st.AppliedAt = &t
+		}
+		res = append(res, st)
+	}
+	return res, nil
+}
+
+// CheckVersion checks if the database schema is migrated to the latest known migration.
+//
+// If the schema is older or newer, the method will return `ErrSchemaVersion` error
+func (m *Migrator) CheckVersion(ctx context.Context) error {
+	var version sql.NullInt64
+	err := m.db.QueryRowContext(ctx, `
+		SELECT max(version)
+			FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return err
+	}
+	if int(version.Int64) != m.Latest() {
+		return xerrors.Errorf("database schema version %d, expected %d: %w", version.Int64, m.Latest(), ErrSchemaVersion)
+	}
+	return nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	// advisory locks belong to a session, so all statements should run on the same connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if err := prepareMigrationTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// prepareMigrationTable creates the migration tracking table if needed.
+//
+// Databases initialized with the first migration before the versions were tracked get the version 1 marked as applied
+func prepareMigrationTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version integer PRIMARY KEY NOT NULL,
+			applied_at timestamp without time zone NOT NULL DEFAULT (now() at time zone 'utc')
+		)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version)
+			SELECT 1
+			WHERE
+				to_regclass('accounts') IS NOT NULL AND
+				NOT EXISTS (SELECT 1 FROM schema_migrations)`)
+	return err
+}
+
+// appliedVersions returns applied migration versions and their application time
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT version, applied_at
+			FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			applied time.Time
+		)
+		if err := rows.Scan(&version, &applied); err != nil {
+			return nil, err
+		}
+		res[version] = applied
+	}
+	return res, rows.Err()
+}
+
+// runMigration executes a migration script and updates the version table in one transaction
+func runMigration(ctx context.Context, conn *sql.Conn, script, versionQuery string, version int) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !isEmptyScript(script) {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, versionQuery, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isEmptyScript checks if the SQL script contains only comments and whitespaces
+func isEmptyScript(script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ilyakaznacheev/tiny-wallet/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr bool
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"10_ten.up.sql":   {Data: []byte("up10")},
+				"10_ten.down.sql": {Data: []byte("down10")},
+				"2_two.up.sql":    {Data: []byte("up2")},
+				"2_two.down.sql":  {Data: []byte("down2")},
+				"migrations.go":   {Data: []byte("package migrations")},
+			},
+			want: []Migration{
+				{Version: 2, Name: "two", Up: "up2", Down: "down2"},
+				{Version: 10, Name: "ten", Up: "up10", Down: "down10"},
+			},
+		},
+		{
+			name: "missing down script",
+			fsys: fstest.MapFS{
+				"1_init.up.sql": {Data: []byte("up")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "different names",
+			fsys: fstest.MapFS{
+				"1_init.up.sql":  {Data: []byte("up")},
+				"1_other.up.sql": {Data: []byte("down")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty",
+			fsys: fstest.MapFS{},
+			want: []Migration{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMigrations(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong migrations %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	got, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range got {
+		if m.Version != i+1 {
+			t.Errorf("wrong migration version %v, want %v", m.Version, i+1)
+		}
+	}
+}
+
+func TestIsEmptyScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{"empty", "", true},
+		{"comments", "-- comment\n\n  -- another one\n", true},
+		{"statement", "-- comment\nDROP TABLE accounts;", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEmptyScript(tt.script); got != tt.want {
+				t.Errorf("wrong result %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,26 +8,35 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ilyakaznacheev/tiny-wallet/internal/model"
+	"github.com/ilyakaznacheev/tiny-wallet/internal/tracing"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
 	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/xerrors"
 )
 
 // PostgresClient is a database communication manager
 type PostgresClient struct {
-	db  *sql.DB
-	ctx context.Context
+	db     *sql.DB
+	tracer *tracing.Tracer
 }
 
 // NewPostgresClient create a new database communication manager
 //
-// - ctx: context of the database client. Can be used to interrupt connection wait loop or long transactions;
+// - ctx: context of the database client. Can be used to interrupt connection wait loop;
 // - options: database connection options. Please provide a string of options in format `host=localhost port=5432 ...`. For more information about possible options see [Database Connection Control Functions](https://www.postgresql.org/docs/current/libpq-connect.html);
-// - wait: describes will the app wait until the database will up or fails after first unsuccessful ping. Useful for orchestration environments like K8s or Docker Compose or Swarm to wait when the database container of proxy will up.
-func NewPostgresClient(ctx context.Context, options string, wait bool) (*PostgresClient, error) {
+// - wait: describes will the app wait until the database will up or fails after first unsuccessful ping. Useful for orchestration environments like K8s or Docker Compose or Swarm to wait when the database container of proxy will up;
+// - tracer: creates a span for each database query. Can be nil to disable tracing.
+func NewPostgresClient(ctx context.Context, options string, wait bool, tracer *tracing.Tracer) (*PostgresClient, error) {
 	db, err := sql.Open("postgres", options)
 	if err != nil {
 		return nil, err
@@ -59,21 +68,50 @@ func NewPostgresClient(ctx context.Context, options string, wait bool) (*Postgre
 	}
 
 	return &PostgresClient{
-		db:  db,
-		ctx: ctx,
+		db:     db,
+		tracer: tracer,
 	}, nil
 }
 
+// Close closes the database connection pool.
+//
+// It waits until all queries that have started are finished
+func (pg *PostgresClient) Close() error {
+	return pg.db.Close()
+}
+
+// Ping checks if the database is reachable
+func (pg *PostgresClient) Ping(ctx context.Context) (err error) {
+	ctx, span := pg.startSpan(ctx, "PING")
+	defer func() { tracing.End(span, err) }()
+
+	return pg.db.PingContext(ctx)
+}
+
+// startSpan starts a database query span
+func (pg *PostgresClient) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return pg.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")),
+	)
+}
+
 // GetAllAccounts returns a list of existing accounts.
 //
 // The view v_accounts calculates a sum of account balance and following payments affecting this account.
 //
 // To improve database performance you can periodically calculate a sum op payments related to each account and update its fields `balance` and `balance_date`. Thus, the payments older than balance_date will not be affected in aggregations anymore. All dates should be in UTC+0.
-func (pg *PostgresClient) GetAllAccounts() ([]model.Account, error) {
+func (pg *PostgresClient) GetAllAccounts(ctx context.Context, filter model.AccountFilter) (res []model.Account, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT v_accounts")
+	defer func() { tracing.End(span, err) }()
+
 	// fetch the data
-	rows, err := pg.db.Query(
-		`SELECT *
-			FROM v_accounts`)
+	rows, err := pg.db.QueryContext(ctx,
+		`SELECT `+accountColumns+`
+			FROM v_accounts
+			WHERE
+				($1 = '' OR owner_id = $1) AND
+				labels @> $2`, filter.OwnerID, pq.Array(append([]string{}, filter.Labels...)))
 	if err != nil {
 		return nil, err
 	}
@@ -81,30 +119,50 @@ func (pg *PostgresClient) GetAllAccounts() ([]model.Account, error) {
 	defer rows.Close()
 
 	// process the result
-	res := make([]model.Account, 0)
+	res = make([]model.Account, 0)
 
 	for rows.Next() {
-		rec := model.Account{}
-		if err := rows.Scan(&rec.ID, &rec.LastUpdate, &rec.Balance, &rec.Currency); err != nil {
+		rec, err := scanAccount(rows)
+		if err != nil {
 			return nil, err
 		}
-		res = append(res, rec)
+		res = append(res, *rec)
 	}
 
-	return res, nil
+	return res, rows.Err()
 }
 
-// GetAllPayments returns a list of existing payments in historical order
+// GetAllPayments returns a list of existing payments matching the filter in historical order
 //
-// Since the payment doesn't contain currency code, it will be received from the corresponding payer account
-func (pg *PostgresClient) GetAllPayments() ([]model.Payment, error) {
+// Since the payment doesn't contain currency code, it will be received from the corresponding payer account.
+// The receiver account currency is returned only for conversions between wallet pockets
+func (pg *PostgresClient) GetAllPayments(ctx context.Context, filter model.PaymentFilter) (res []model.Payment, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT payments")
+	defer func() { tracing.End(span, err) }()
+
+	// the metadata condition is skipped without a filter
+	var metadata interface{}
+	if len(filter.Metadata) > 0 {
+		if metadata, err = json.Marshal(filter.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
 	// fetch the data
-	rows, err := pg.db.Query(
-		`SELECT p.*, a.currency
+	rows, err := pg.db.QueryContext(ctx,
+		`SELECT p.id, p.account_from_id, p.account_to_id, p.trx_time, p.amount, a.currency,
+				p.amount_to, b.currency, coalesce(p.reference, ''), p.description, p.metadata, coalesce(p.group_id, 0)
 			FROM payments AS p
 				INNER JOIN accounts AS a ON
 					a.id = p.account_from_id
-			ORDER BY account_from_id, trx_time`)
+				INNER JOIN accounts AS b ON
+					b.id = p.account_to_id
+			WHERE
+				($1 = '' OR p.reference = $1) AND
+				($2 = '' OR p.reference ILIKE $2 OR p.description ILIKE $2) AND
+				($3::jsonb IS NULL OR p.metadata @> $3::jsonb) AND
+				($4 = 0 OR p.group_id = $4)
+			ORDER BY account_from_id, trx_time`, filter.Reference, likePattern(filter.Search), metadata, filter.GroupID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,17 +170,114 @@ func (pg *PostgresClient) GetAllPayments() ([]model.Payment, error) {
 	defer rows.Close()
 
 	// process the result
-	res := make([]model.Payment, 0)
+	res = make([]model.Payment, 0)
 
 	for rows.Next() {
-		rec := model.Payment{}
-		if err := rows.Scan(&rec.ID, &rec.AccFromID, &rec.AccToID, &rec.DateTime, &rec.Amount, &rec.Currency); err != nil {
+		var (
+			rec        model.Payment
+			amountTo   *currency.Amount
+			currencyTo currency.Currency
+			meta       []byte
+		)
+		err := rows.Scan(&rec.ID, &rec.AccFromID, &rec.AccToID, &rec.DateTime, &rec.Amount, &rec.Currency,
+			&amountTo, &currencyTo, &rec.Reference, &rec.Description, &meta, &rec.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		if amountTo != nil {
+			rec.ToAmount, rec.ToCurrency = *amountTo, currencyTo
+		}
+		if err := json.Unmarshal(meta, &rec.Metadata); err != nil {
 			return nil, err
 		}
 		res = append(res, rec)
 	}
 
-	return res, nil
+	return res, rows.Err()
+}
+
+// likePattern returns a LIKE pattern that matches strings containing the text, or an empty string for an empty text
+func likePattern(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text) + "%"
+}
+
+// ExportAccounts passes existing accounts to fn one by one ordered by ID.
+//
+// Rows are read from the database while fn consumes them, so the whole result is never kept in memory
+func (pg *PostgresClient) ExportAccounts(ctx context.Context, fn func(model.Account) error) (err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT v_accounts")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := pg.db.QueryContext(ctx,
+		`SELECT id, last_update, balance, currency
+			FROM v_accounts
+			ORDER BY id`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		rec := model.Account{}
+		if err := rows.Scan(&rec.ID, &rec.LastUpdate, &rec.Balance, &rec.Currency); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportPayments passes payments to fn one by one in historical order.
+//
+// The payment time is limited by from (inclusive) and to (exclusive), each of them can be nil.
+//
+// Rows are read from the database while fn consumes them, so the whole result is never kept in memory
+func (pg *PostgresClient) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) (err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT payments")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := pg.db.QueryContext(ctx,
+		`SELECT p.id, p.account_from_id, p.account_to_id, p.trx_time, p.amount, a.currency, p.amount_to, b.currency
+			FROM payments AS p
+				INNER JOIN accounts AS a ON
+					a.id = p.account_from_id
+				INNER JOIN accounts AS b ON
+					b.id = p.account_to_id
+			WHERE
+				($1::timestamp IS NULL OR p.trx_time >= $1) AND
+				($2::timestamp IS NULL OR p.trx_time < $2)
+			ORDER BY p.trx_time, p.id`, from, to)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			rec        model.Payment
+			amountTo   *currency.Amount
+			currencyTo currency.Currency
+		)
+		if err := rows.Scan(&rec.ID, &rec.AccFromID, &rec.AccToID, &rec.DateTime, &rec.Amount, &rec.Currency, &amountTo, &currencyTo); err != nil {
+			return err
+		}
+		if amountTo != nil {
+			rec.ToAmount, rec.ToCurrency = *amountTo, currencyTo
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
 }
 
 // GetAccount returns an existing account.
@@ -130,30 +285,66 @@ func (pg *PostgresClient) GetAllPayments() ([]model.Payment, error) {
 // The view v_accounts calculates a sum of account balance and following payments affecting this account.
 //
 // To improve database performance you can periodically calculate a sum op payments related to each account and update its fields `balance` and `balance_date`. Thus, the payments older than balance_date will not be affected in aggregations anymore. All dates should be in UTC+0.
-func (pg *PostgresClient) GetAccount(accountID string) (*model.Account, error) {
+func (pg *PostgresClient) GetAccount(ctx context.Context, accountID string) (res *model.Account, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT v_accounts")
+	defer func() { tracing.End(span, err) }()
+
 	// fetch the data
-	row := pg.db.QueryRow(`
-		SELECT *
+	row := pg.db.QueryRowContext(ctx, `
+		SELECT `+accountColumns+`
 			FROM v_accounts
 			WHERE
 				id = $1`, accountID)
 
 	// process the result
-	rec := model.Account{}
-	if err := row.Scan(&rec.ID, &rec.LastUpdate, &rec.Balance, &rec.Currency); err != nil {
-		return nil, err
+	return scanAccount(row)
+}
+
+// marshalMetadata encodes the metadata as a JSON object, nil metadata is stored as an empty object
+func marshalMetadata(m map[string]string) ([]byte, error) {
+	if m == nil {
+		return []byte("{}"), nil
 	}
+	return json.Marshal(m)
+}
+
+// accountColumns are columns of v_accounts read by scanAccount
+const accountColumns = `id, last_update, balance, currency, owner_id, display_name, labels, metadata, type`
 
+// scanner is a database row
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAccount reads an account from a row of accountColumns
+func scanAccount(row scanner) (*model.Account, error) {
+	var (
+		rec      model.Account
+		metadata []byte
+	)
+	err := row.Scan(&rec.ID, &rec.LastUpdate, &rec.Balance, &rec.Currency,
+		&rec.OwnerID, &rec.DisplayName, pq.Array(&rec.Labels), &metadata, &rec.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(metadata, &rec.Metadata); err != nil {
+		return nil, err
+	}
 	return &rec, nil
 }
 
 // CreatePayment tries to create a financial transaction
 // Concurrent data access is managed by means of MVCC (Multiversion Concurrency Control)
 // In case of any inconsistency, race condition or any other concurrency problem it raises an error
-func (pg *PostgresClient) CreatePayment(p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (*model.Payment, error) {
+// If the payer already has a payment with the same reference or idempotency key, the method will return `model.ErrRowExists` error
+// The payment is recorded in the ledger as a journal entry in the same transaction
+func (pg *PostgresClient) CreatePayment(ctx context.Context, p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (res *model.Payment, err error) {
+	ctx, span := pg.startSpan(ctx, "CreatePayment")
+	defer func() { tracing.End(span, err) }()
+
 	now := time.Now()
 	// get pg transaction
-	tx, err := pg.db.BeginTx(pg.ctx, &sql.TxOptions{
+	tx, err := pg.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 		ReadOnly:  false,
 	})
@@ -163,71 +354,841 @@ func (pg *PostgresClient) CreatePayment(p model.Payment, lastChangedFrom, lastCh
 	defer tx.Rollback()
 
 	// try to update the payer account if it wasn't updated from any concurrent process
-	_, err = tx.Exec(`
-		UPDATE accounts SET
-			last_update = $1
-		WHERE
-			id = $2 AND
-			last_update = $3`, now, p.AccFromID, lastChangedFrom)
+	err = pg.updateLastChanged(ctx, tx, p.AccFromID, lastChangedFrom, now)
 	if err != nil {
 		return nil, err
 	}
 
 	// try to update the receiver account if it wasn't updated from any concurrent process
-	_, err = tx.Exec(`
-		UPDATE accounts SET
-			last_update = $1
-		WHERE
-			id = $2 AND
-			last_update = $3`, now, p.AccToID, lastChangedTo)
+	err = pg.updateLastChanged(ctx, tx, p.AccToID, lastChangedTo, now)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := marshalMetadata(p.Metadata)
 	if err != nil {
 		return nil, err
 	}
+	// the receiver amount is stored only for conversions
+	var amountTo interface{}
+	if p.ToCurrency != "" {
+		amountTo = p.ToAmount
+	}
 
 	// create a new payment
-	row := tx.QueryRow(`
-		INSERT INTO payments (account_from_id, account_to_id, amount, trx_time)
-			VALUES($1, $2, $3, $4)
-			RETURNING *`,
-		p.AccFromID, p.AccToID, p.Amount, now)
+	insCtx, insSpan := pg.startSpan(ctx, "INSERT payments")
+	row := tx.QueryRowContext(insCtx, `
+		INSERT INTO payments (account_from_id, account_to_id, amount, trx_time, reference, description, metadata, amount_to, idempotency_key)
+			VALUES($1, $2, $3, $4, nullif($5, ''), $6, $7, $8, nullif($9, ''))
+			RETURNING id, account_from_id, account_to_id, trx_time, amount, coalesce(reference, ''), description, metadata`,
+		p.AccFromID, p.AccToID, p.Amount, now, p.Reference, p.Description, metadata, amountTo, p.IdempotencyKey)
 
-	rec := model.Payment{}
+	var (
+		rec  model.Payment
+		meta []byte
+	)
 
-	if err := row.Scan(&rec.ID, &rec.AccFromID, &rec.AccToID, &rec.DateTime, &rec.Amount); err != nil {
+	err = row.Scan(&rec.ID, &rec.AccFromID, &rec.AccToID, &rec.DateTime, &rec.Amount, &rec.Reference, &rec.Description, &meta)
+	tracing.End(insSpan, err)
+	if err != nil {
+		var pqErr *pq.Error
+		if xerrors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return nil, model.ErrRowExists
+		}
+		return nil, checkConflict(err)
+	}
+	if err := json.Unmarshal(meta, &rec.Metadata); err != nil {
 		return nil, err
 	}
+	rec.Currency, rec.ToAmount, rec.ToCurrency, rec.IdempotencyKey = p.Currency, p.ToAmount, p.ToCurrency, p.IdempotencyKey
+
+	// record the payment in the ledger
+	if err := pg.insertEntry(ctx, tx, model.PaymentEntry(rec)); err != nil {
+		return nil, checkConflict(err)
+	}
 
 	// commit changes
-	return &rec, tx.Commit()
+	return &rec, checkConflict(tx.Commit())
+}
+
+// GetPaymentByIdempotencyKey returns a payment of the payer created with the idempotency key.
+//
+// If there is no such payment, the method will return `sql.ErrNoRows` error
+func (pg *PostgresClient) GetPaymentByIdempotencyKey(ctx context.Context, accountFromID, key string) (res *model.Payment, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT payments")
+	defer func() { tracing.End(span, err) }()
+
+	row := pg.db.QueryRowContext(ctx,
+		`SELECT p.id, p.account_from_id, p.account_to_id, p.trx_time, p.amount, a.currency,
+				p.amount_to, b.currency, coalesce(p.reference, ''), p.description, p.metadata, coalesce(p.group_id, 0)
+			FROM payments AS p
+				INNER JOIN accounts AS a ON
+					a.id = p.account_from_id
+				INNER JOIN accounts AS b ON
+					b.id = p.account_to_id
+			WHERE
+				p.account_from_id = $1 AND
+				p.idempotency_key = $2`, accountFromID, key)
+
+	var (
+		rec        model.Payment
+		amountTo   *currency.Amount
+		currencyTo currency.Currency
+		meta       []byte
+	)
+	err = row.Scan(&rec.ID, &rec.AccFromID, &rec.AccToID, &rec.DateTime, &rec.Amount, &rec.Currency,
+		&amountTo, &currencyTo, &rec.Reference, &rec.Description, &meta, &rec.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if amountTo != nil {
+		rec.ToAmount, rec.ToCurrency = *amountTo, currencyTo
+	}
+	if err := json.Unmarshal(meta, &rec.Metadata); err != nil {
+		return nil, err
+	}
+	rec.IdempotencyKey = key
+	return &rec, nil
+}
+
+// CreatePaymentGroup tries to create a split payment with a payment to each receiver of the group.
+//
+// lastChanged contains the last change time of the payer and each receiver account read before the payment was calculated.
+// If any of these accounts was changed meanwhile, the method will return `model.ErrConflict` error.
+// If the payer already has a split payment with the same reference, the method will return `model.ErrRowExists` error.
+// All payments and their journal entries are created in one transaction
+func (pg *PostgresClient) CreatePaymentGroup(ctx context.Context, g model.PaymentGroup, lastChanged map[string]*time.Time) (res *model.PaymentGroup, err error) {
+	ctx, span := pg.startSpan(ctx, "CreatePaymentGroup")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	tx, err := pg.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// update accounts in the same order in all transactions
+	ids := make([]string, 0, len(lastChanged))
+	for id := range lastChanged {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err = pg.updateLastChanged(ctx, tx, id, lastChanged[id], now); err != nil {
+			return nil, err
+		}
+	}
+
+	metadata, err := marshalMetadata(g.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := g
+	rec.DateTime = now
+	rec.Payments = make([]model.Payment, 0, len(g.Payments))
+
+	insCtx, insSpan := pg.startSpan(ctx, "INSERT payment_groups")
+	err = tx.QueryRowContext(insCtx, `
+		INSERT INTO payment_groups (account_from_id, trx_time, amount, reference, description, metadata)
+			VALUES($1, $2, $3, nullif($4, ''), $5, $6)
+			RETURNING id`,
+		g.AccFromID, now, g.Amount, g.Reference, g.Description, metadata).Scan(&rec.ID)
+	tracing.End(insSpan, err)
+	if err != nil {
+		var pqErr *pq.Error
+		if xerrors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return nil, model.ErrRowExists
+		}
+		return nil, checkConflict(err)
+	}
+
+	for _, p := range g.Payments {
+		p.AccFromID, p.DateTime, p.Currency, p.GroupID = g.AccFromID, now, g.Currency, rec.ID
+		p.Description, p.Metadata = g.Description, g.Metadata
+
+		insCtx, insSpan := pg.startSpan(ctx, "INSERT payments")
+		err = tx.QueryRowContext(insCtx, `
+			INSERT INTO payments (account_from_id, account_to_id, amount, trx_time, description, metadata, group_id)
+				VALUES($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id`,
+			p.AccFromID, p.AccToID, p.Amount, now, p.Description, metadata, p.GroupID).Scan(&p.ID)
+		tracing.End(insSpan, err)
+		if err != nil {
+			return nil, checkConflict(err)
+		}
+
+		// record the payment in the ledger
+		if err := pg.insertEntry(ctx, tx, model.PaymentEntry(p)); err != nil {
+			return nil, checkConflict(err)
+		}
+		rec.Payments = append(rec.Payments, p)
+	}
+
+	// commit changes
+	return &rec, checkConflict(tx.Commit())
+}
+
+// insertEntry adds the journal entry with its postings to the ledger.
+//
+// An entry without postings is skipped. Unbalanced entries are rejected by the database on commit
+func (pg *PostgresClient) insertEntry(ctx context.Context, tx *sql.Tx, e model.JournalEntry) (err error) {
+	if len(e.Postings) == 0 {
+		return nil
+	}
+
+	ctx, span := pg.startSpan(ctx, "INSERT postings")
+	defer func() { tracing.End(span, err) }()
+
+	var paymentID interface{}
+	if e.PaymentID != 0 {
+		paymentID = e.PaymentID
+	}
+
+	var entryID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO journal_entries (trx_time, kind, payment_id)
+			VALUES($1, $2, $3)
+			RETURNING id`, e.DateTime, e.Kind, paymentID).Scan(&entryID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range e.Postings {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO postings (entry_id, account_id, currency, amount)
+				VALUES($1, $2, $3, $4)`, entryID, p.AccountID, p.Currency, p.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLastChanged moves the account change time forward if the account wasn't changed since lastChanged.
+//
+// If the account was changed meanwhile, the method will return `model.ErrConflict` error
+func (pg *PostgresClient) updateLastChanged(ctx context.Context, tx *sql.Tx, accountID string, lastChanged *time.Time, now time.Time) (err error) {
+	ctx, span := pg.startSpan(ctx, "UPDATE accounts")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE accounts SET
+			last_update = $1
+		WHERE
+			id = $2 AND
+			last_update IS NOT DISTINCT FROM $3`, now, accountID, lastChanged)
+	if err != nil {
+		return checkConflict(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrConflict
+	}
+	return nil
+}
+
+// checkConflict converts Postgres serialization failures into `model.ErrConflict` error
+func checkConflict(err error) error {
+	var pqErr *pq.Error
+	if xerrors.As(err, &pqErr) {
+		// check Postgres errors class
+		switch pqErr.Code.Class() {
+		case "40": // transaction_rollback
+			return xerrors.Errorf("%s: %w", pqErr.Message, model.ErrConflict)
+		}
+	}
+	return err
 }
 
 // CreateAccount creates a new account.
 //
+// A non-zero opening balance is recorded in the ledger in the same transaction.
 // If the account already exists, the method will return `model.ErrRowExists` error
-func (pg *PostgresClient) CreateAccount(a model.Account) (*model.Account, error) {
+func (pg *PostgresClient) CreateAccount(ctx context.Context, a model.Account) (res *model.Account, err error) {
+	ctx, span := pg.startSpan(ctx, "INSERT accounts")
+	defer func() { tracing.End(span, err) }()
+
+	metadata, err := marshalMetadata(a.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	now := time.Now()
-	row := pg.db.QueryRow(`
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO accounts (id, last_update, currency, balance, balance_date, owner_id, display_name, labels, metadata, type)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id, last_update, balance, currency, owner_id, display_name, labels, metadata, type`,
+		a.ID, now, a.Currency, a.Balance, now, a.OwnerID, a.DisplayName, pq.Array(append([]string{}, a.Labels...)), metadata, a.Type)
+
+	rec, err := scanAccount(row)
+	if err != nil {
+		return nil, checkRowExists(err)
+	}
+
+	if err := pg.insertEntry(ctx, tx, model.OpeningEntry(*rec, now)); err != nil {
+		return nil, err
+	}
+
+	return rec, tx.Commit()
+}
+
+// UpdateAccountInfo changes descriptive data of an existing account.
+//
+// The balance and the last update time are not affected, so the update doesn't conflict with payments.
+// If the account doesn't exist, the method will return `sql.ErrNoRows` error
+func (pg *PostgresClient) UpdateAccountInfo(ctx context.Context, id string, patch model.AccountPatch) (res *model.Account, err error) {
+	ctx, span := pg.startSpan(ctx, "UPDATE accounts")
+	defer func() { tracing.End(span, err) }()
+
+	// nil parameters keep the current values
+	var labels, metadata interface{}
+	if patch.Labels != nil {
+		labels = pq.Array(append([]string{}, *patch.Labels...))
+	}
+	if patch.Metadata != nil {
+		if metadata, err = marshalMetadata(*patch.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := pg.db.ExecContext(ctx, `
+		UPDATE accounts SET
+			owner_id = coalesce($2, owner_id),
+			display_name = coalesce($3, display_name),
+			labels = coalesce($4::text[], labels),
+			metadata = coalesce($5::jsonb, metadata),
+			type = coalesce($6, type)
+		WHERE
+			id = $1`, id, patch.OwnerID, patch.DisplayName, labels, metadata, patch.Type)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return pg.GetAccount(ctx, id)
+}
+
+// CreateAccounts creates accounts in one transaction.
+//
+// Returns indexes of accounts that already exist. Opening balances are recorded in the ledger. The changes are committed only if there are no such accounts and it isn't a dry run
+func (pg *PostgresClient) CreateAccounts(ctx context.Context, accounts []model.Account, dryRun bool) (existing []int, err error) {
+	ctx, span := pg.startSpan(ctx, "INSERT accounts")
+	span.SetAttributes(attribute.Int("db.rows", len(accounts)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO accounts (id, last_update, currency, balance, balance_date)
 			VALUES($1, $2, $3, $4, $5)
-			RETURNING *`,
-		a.ID, now, a.Currency, a.Balance, now)
+			ON CONFLICT (id) DO NOTHING`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
 
-	var (
-		rec       model.Account
-		dateDummy time.Time
-	)
+	now := time.Now()
+	for i, a := range accounts {
+		res, err := stmt.ExecContext(ctx, a.ID, now, a.Currency, a.Balance, now)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			existing = append(existing, i)
+			continue
+		}
+		if err := pg.insertEntry(ctx, tx, model.OpeningEntry(a, now)); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := row.Scan(&rec.ID, &rec.LastUpdate, &rec.Currency, &rec.Balance, &dateDummy); err != nil {
-		var pqErr *pq.Error
-		if xerrors.As(err, &pqErr) {
-			// check Postgres errors class
-			switch pqErr.Code.Class() {
-			case "23": //integrity_constraint_violation
-				return nil, model.ErrRowExists
-			}
+	if len(existing) > 0 || dryRun {
+		return existing, nil
+	}
+	return nil, tx.Commit()
+}
+
+// CreateWallet creates a new wallet with its currency pockets in one transaction.
+//
+// If the wallet or any of the pocket accounts already exists, the method will return `model.ErrRowExists` error
+func (pg *PostgresClient) CreateWallet(ctx context.Context, w model.Wallet) (res *model.Wallet, err error) {
+	ctx, span := pg.startSpan(ctx, "INSERT wallets")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO wallets (id, owner_id, created)
+			VALUES($1, $2, $3)`, w.ID, w.OwnerID, now)
+	if err != nil {
+		return nil, checkRowExists(err)
+	}
+
+	for _, a := range w.Pockets {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO accounts (id, last_update, currency, balance, balance_date, owner_id, wallet_id)
+				VALUES($1, $2, $3, $4, $5, $6, $7)`,
+			a.ID, now, a.Currency, a.Balance, now, w.OwnerID, w.ID)
+		if err != nil {
+			return nil, checkRowExists(err)
 		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	return pg.GetWallet(ctx, w.ID)
+}
 
-	return &rec, nil
+// GetWallet returns an existing wallet with its currency pockets ordered by currency.
+//
+// If the wallet doesn't exist, the method will return `sql.ErrNoRows` error
+func (pg *PostgresClient) GetWallet(ctx context.Context, id string) (res *model.Wallet, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT wallets")
+	defer func() { tracing.End(span, err) }()
+
+	w := model.Wallet{ID: id}
+	err = pg.db.QueryRowContext(ctx, `
+		SELECT owner_id
+			FROM wallets
+			WHERE
+				id = $1`, id).Scan(&w.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.db.QueryContext(ctx,
+		`SELECT `+accountColumns+`
+			FROM v_accounts
+			WHERE
+				wallet_id = $1
+			ORDER BY currency`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		rec, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		w.Pockets = append(w.Pockets, *rec)
+	}
+
+	return &w, rows.Err()
+}
+
+// checkRowExists converts Postgres integrity constraint violations into `model.ErrRowExists` error
+func checkRowExists(err error) error {
+	var pqErr *pq.Error
+	if xerrors.As(err, &pqErr) {
+		// check Postgres errors class
+		switch pqErr.Code.Class() {
+		case "23": //integrity_constraint_violation
+			return model.ErrRowExists
+		}
+	}
+	return err
+}
+
+// GetTrialBalance returns sums of postings of each ledger account in each currency ordered by currency and account
+func (pg *PostgresClient) GetTrialBalance(ctx context.Context) (res []model.TrialBalanceAccount, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT postings")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := pg.db.QueryContext(ctx, `
+		SELECT
+			account_id,
+			currency,
+			coalesce(sum(amount) FILTER (WHERE amount < 0), 0) * -1,
+			coalesce(sum(amount) FILTER (WHERE amount > 0), 0)
+		FROM postings
+		GROUP BY
+			account_id,
+			currency
+		ORDER BY currency, account_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res = make([]model.TrialBalanceAccount, 0)
+
+	for rows.Next() {
+		rec := model.TrialBalanceAccount{}
+		if err := rows.Scan(&rec.AccountID, &rec.Currency, &rec.Debit, &rec.Credit); err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+
+	return res, rows.Err()
+}
+
+// GetCustomCurrencies returns custom currencies ordered by code
+func (pg *PostgresClient) GetCustomCurrencies(ctx context.Context) (res []model.CustomCurrency, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT currencies")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := pg.db.QueryContext(ctx, `
+		SELECT code, name, decimals
+		FROM currencies
+		ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res = make([]model.CustomCurrency, 0)
+
+	for rows.Next() {
+		rec := model.CustomCurrency{}
+		if err := rows.Scan(&rec.Code, &rec.Name, &rec.Decimals); err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+
+	return res, rows.Err()
+}
+
+// GetEndOfDayBalances returns accounts of the types with ledger balances at the end time ordered by ID
+func (pg *PostgresClient) GetEndOfDayBalances(ctx context.Context, types []string, end time.Time) (res []model.Account, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT postings")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := pg.db.QueryContext(ctx, `
+		SELECT a.id, a.currency, a.type, coalesce(sum(p.amount), 0)
+			FROM accounts AS a
+				LEFT OUTER JOIN (postings AS p
+					INNER JOIN journal_entries AS e ON
+						e.id = p.entry_id AND
+						e.trx_time < $2) ON
+					p.account_id = a.id
+			WHERE
+				a.type = ANY($1)
+			GROUP BY
+				a.id,
+				a.currency,
+				a.type
+			ORDER BY a.id`, pq.Array(types), end)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res = make([]model.Account, 0)
+
+	for rows.Next() {
+		rec := model.Account{}
+		if err := rows.Scan(&rec.ID, &rec.Currency, &rec.Type, &rec.Balance); err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+
+	return res, rows.Err()
+}
+
+// CreateInterestAccruals saves daily interest accruals in one transaction.
+//
+// Accruals of an account for a day that already has one are skipped, so the job can be rerun. Returns a number of saved accruals
+func (pg *PostgresClient) CreateInterestAccruals(ctx context.Context, accruals []model.InterestAccrual) (created int, err error) {
+	ctx, span := pg.startSpan(ctx, "INSERT interest_accruals")
+	span.SetAttributes(attribute.Int("db.rows", len(accruals)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO interest_accruals (account_id, accrual_date, balance, rate, amount)
+			VALUES($1, $2, $3, $4, $5)
+			ON CONFLICT (account_id, accrual_date) DO NOTHING`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, a := range accruals {
+		res, err := stmt.ExecContext(ctx, a.AccountID, a.Date, a.Balance, a.Rate, a.Amount)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		created += int(affected)
+	}
+
+	return created, tx.Commit()
+}
+
+// PayInterest pays unpaid interest accrued on the account up to the day from the expense account.
+//
+// The payout is a payment recorded in the ledger as an interest entry, the accruals refer to it afterwards.
+// Returns nil if there is nothing to pay. If the expense account doesn't exist, the method will return `sql.ErrNoRows` error
+func (pg *PostgresClient) PayInterest(ctx context.Context, accountID, expenseAccountID string, upTo time.Time) (res *model.Payment, err error) {
+	ctx, span := pg.startSpan(ctx, "PayInterest")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// lock unpaid accruals, so a concurrent run can't pay them twice
+	var amount currency.Amount
+	err = tx.QueryRowContext(ctx, `
+		SELECT coalesce(sum(amount), 0)
+			FROM (SELECT amount
+				FROM interest_accruals
+				WHERE
+					account_id = $1 AND
+					accrual_date <= $2 AND
+					payment_id IS NULL
+				FOR UPDATE) AS a`, accountID, upTo).Scan(&amount)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() <= 0 {
+		return nil, nil
+	}
+
+	var curr, expenseCurr currency.Currency
+	err = tx.QueryRowContext(ctx, `SELECT currency FROM accounts WHERE id = $1`, accountID).Scan(&curr)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx, `SELECT currency FROM accounts WHERE id = $1`, expenseAccountID).Scan(&expenseCurr)
+	if err != nil {
+		return nil, xerrors.Errorf("interest expense account %s: %w", expenseAccountID, err)
+	}
+	if curr != expenseCurr {
+		return nil, xerrors.Errorf("interest expense account %s currency %s doesn't match account %s currency %s",
+			expenseAccountID, string(expenseCurr), accountID, string(curr))
+	}
+
+	// concurrent payments of the accounts will conflict and retry
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE accounts SET
+			last_update = $1
+		WHERE
+			id IN ($2, $3)`, now, accountID, expenseAccountID)
+	if err != nil {
+		return nil, checkConflict(err)
+	}
+
+	rec := model.Payment{
+		AccFromID: expenseAccountID,
+		AccToID:   accountID,
+		DateTime:  now,
+		Amount:    amount,
+		Currency:  curr,
+		PaymentInfo: model.PaymentInfo{
+			Description: "interest to " + upTo.Format("2006-01-02"),
+		},
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO payments (account_from_id, account_to_id, amount, trx_time, description)
+			VALUES($1, $2, $3, $4, $5)
+			RETURNING id`, rec.AccFromID, rec.AccToID, rec.Amount, rec.DateTime, rec.Description).Scan(&rec.ID)
+	if err != nil {
+		return nil, checkConflict(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE interest_accruals SET
+			payment_id = $1
+		WHERE
+			account_id = $2 AND
+			accrual_date <= $3 AND
+			payment_id IS NULL`, rec.ID, accountID, upTo)
+	if err != nil {
+		return nil, err
+	}
+
+	e := model.PaymentEntry(rec)
+	e.Kind = model.EntryInterest
+	if err := pg.insertEntry(ctx, tx, e); err != nil {
+		return nil, err
+	}
+
+	return &rec, checkConflict(tx.Commit())
+}
+
+// GetInterestAccruals returns interest accrued on the account in historical order
+func (pg *PostgresClient) GetInterestAccruals(ctx context.Context, accountID string) (res []model.InterestAccrual, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT interest_accruals")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := pg.db.QueryContext(ctx, `
+		SELECT i.account_id, i.accrual_date, i.balance, i.rate, i.amount, a.currency, coalesce(i.payment_id, 0)
+			FROM interest_accruals AS i
+				INNER JOIN accounts AS a ON
+					a.id = i.account_id
+			WHERE
+				i.account_id = $1
+			ORDER BY i.accrual_date`, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res = make([]model.InterestAccrual, 0)
+
+	for rows.Next() {
+		rec := model.InterestAccrual{}
+		err := rows.Scan(&rec.AccountID, &rec.Date, &rec.Balance, &rec.Rate, &rec.Amount, &rec.Currency, &rec.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+
+	return res, rows.Err()
+}
+
+// CreateRates saves exchange rates in one transaction.
+//
+// A rate of the currency pair for the same time is replaced. Returns a number of saved rates
+func (pg *PostgresClient) CreateRates(ctx context.Context, rates []currency.Rate) (created int, err error) {
+	ctx, span := pg.startSpan(ctx, "INSERT exchange_rates")
+	span.SetAttributes(attribute.Int("db.rows", len(rates)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO exchange_rates (base, quote, rate, rate_time)
+			VALUES($1, $2, $3, $4)
+			ON CONFLICT (base, quote, rate_time) DO UPDATE
+				SET rate = excluded.rate`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, r := range rates {
+		rate := strconv.FormatFloat(r.Rate, 'g', -1, 64)
+		if _, err := stmt.ExecContext(ctx, r.Base, r.Quote, rate, r.Time.UTC()); err != nil {
+			return 0, err
+		}
+		created++
+	}
+
+	return created, tx.Commit()
+}
+
+// SeedRates saves exchange rates of currency pairs that have no stored rates yet in one transaction.
+//
+// Rates of pairs that already have any rate are skipped. Returns a number of saved rates
+func (pg *PostgresClient) SeedRates(ctx context.Context, rates []currency.Rate) (created int, err error) {
+	ctx, span := pg.startSpan(ctx, "INSERT exchange_rates")
+	span.SetAttributes(attribute.Int("db.rows", len(rates)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := pg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO exchange_rates (base, quote, rate, rate_time)
+			SELECT $1::varchar, $2::varchar, $3::numeric, $4::timestamp
+			WHERE NOT EXISTS (SELECT 1 FROM exchange_rates WHERE base = $1 AND quote = $2)
+			ON CONFLICT (base, quote, rate_time) DO NOTHING`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, r := range rates {
+		rate := strconv.FormatFloat(r.Rate, 'g', -1, 64)
+		res, err := stmt.ExecContext(ctx, r.Base, r.Quote, rate, r.Time.UTC())
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		created += int(n)
+	}
+
+	return created, tx.Commit()
+}
+
+// GetRate returns the last base/quote exchange rate set at or before the time.
+//
+// If there is no such rate, the method will return `sql.ErrNoRows` error
+func (pg *PostgresClient) GetRate(ctx context.Context, base, quote currency.Currency, at time.Time) (res *currency.Rate, err error) {
+	ctx, span := pg.startSpan(ctx, "SELECT exchange_rates")
+	defer func() { tracing.End(span, err) }()
+
+	res = &currency.Rate{}
+	err = pg.db.QueryRowContext(ctx, `
+		SELECT base, quote, rate, rate_time
+			FROM exchange_rates
+			WHERE
+				base = $1 AND
+				quote = $2 AND
+				rate_time <= $3
+			ORDER BY rate_time DESC
+			LIMIT 1`, base, quote, at.UTC()).Scan(&res.Base, &res.Quote, &res.Rate, &res.Time)
+	if err != nil {
+		return nil, err
+	}
+	res.Time = res.Time.UTC()
+	return res, nil
 }
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/migrations"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+// testDatabaseEnv is a variable with connection options of a database for integration tests
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// newTestClient connects to the test database and applies all migrations, the test is skipped without a database
+func newTestClient(t *testing.T) *PostgresClient {
+	t.Helper()
+	options := os.Getenv(testDatabaseEnv)
+	if options == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+	ctx := context.Background()
+	pg, err := NewPostgresClient(ctx, options, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pg.Close() })
+
+	m, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pg.NewMigrator(m).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return pg
+}
+
+func TestPostgresPaymentWithoutMetadata(t *testing.T) {
+	pg := newTestClient(t)
+	ctx := context.Background()
+
+	// unique IDs allow to rerun the test on the same database
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	from, err := pg.CreateAccount(ctx, model.Account{ID: "from-" + suffix, Balance: currency.NewAmount(1000), Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := pg.CreateAccount(ctx, model.Account{ID: "to-" + suffix, Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to.Metadata == nil || len(to.Metadata) != 0 {
+		t.Errorf("wrong account metadata %v, want empty", to.Metadata)
+	}
+
+	p, err := pg.CreatePayment(ctx, model.Payment{
+		AccFromID:   from.ID,
+		AccToID:     to.ID,
+		Amount:      currency.NewAmount(100),
+		Currency:    currency.USD,
+		PaymentInfo: model.PaymentInfo{Reference: "ref-" + suffix},
+	}, from.LastUpdate, to.LastUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter model.PaymentFilter
+		want   int
+	}{
+		{"no filter", model.PaymentFilter{Reference: "ref-" + suffix}, 1},
+		{"metadata filter", model.PaymentFilter{Reference: "ref-" + suffix, Metadata: map[string]string{"order": "1"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pg.GetAllPayments(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("wrong number of payments %d, want %d", len(got), tt.want)
+			}
+			if tt.want > 0 && got[0].ID != p.ID {
+				t.Errorf("wrong payment %v, want %v", got[0].ID, p.ID)
+			}
+		})
+	}
+}
+
+func TestPostgresPaymentIdempotencyKey(t *testing.T) {
+	pg := newTestClient(t)
+	ctx := context.Background()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	from, err := pg.CreateAccount(ctx, model.Account{ID: "from-" + suffix, Balance: currency.NewAmount(1000), Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := pg.CreateAccount(ctx, model.Account{ID: "to-" + suffix, Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := model.Payment{AccFromID: from.ID, AccToID: to.ID, Amount: currency.NewAmount(100), Currency: currency.USD, IdempotencyKey: "key-" + suffix}
+	p, err := pg.CreatePayment(ctx, payment, from.LastUpdate, to.LastUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pg.GetPaymentByIdempotencyKey(ctx, from.ID, payment.IdempotencyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != p.ID || got.Amount != currency.NewAmount(100) || got.Currency != currency.USD {
+		t.Errorf("wrong payment %+v, want %+v", got, p)
+	}
+	if _, err := pg.GetPaymentByIdempotencyKey(ctx, to.ID, payment.IdempotencyKey); err != sql.ErrNoRows {
+		t.Errorf("wrong error %v for a key of another payer, want %v", err, sql.ErrNoRows)
+	}
+
+	// the balances have changed, so the accounts are read again
+	if from, err = pg.GetAccount(ctx, from.ID); err != nil {
+		t.Fatal(err)
+	}
+	if to, err = pg.GetAccount(ctx, to.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pg.CreatePayment(ctx, payment, from.LastUpdate, to.LastUpdate); !xerrors.Is(err, model.ErrRowExists) {
+		t.Errorf("wrong error %v for a repeated key, want %v", err, model.ErrRowExists)
+	}
+}
+
+func TestPostgresBalanceWithIdenticalPayments(t *testing.T) {
+	pg := newTestClient(t)
+	ctx := context.Background()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	from, err := pg.CreateAccount(ctx, model.Account{ID: "from-" + suffix, Balance: currency.NewAmount(1000), Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := pg.CreateAccount(ctx, model.Account{ID: "to-" + suffix, Currency: currency.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// payments of a group have the same time, so both payments have the same accounts, time and amount
+	_, err = pg.CreatePaymentGroup(ctx, model.PaymentGroup{
+		AccFromID: from.ID,
+		Amount:    currency.NewAmount(200),
+		Currency:  currency.USD,
+		Payments: []model.Payment{
+			{AccToID: to.ID, Amount: currency.NewAmount(100)},
+			{AccToID: to.ID, Amount: currency.NewAmount(100)},
+		},
+	}, map[string]*time.Time{from.ID: from.LastUpdate, to.ID: to.LastUpdate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   string
+		want int64
+	}{
+		{from.ID, 800},
+		{to.ID, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			acc, err := pg.GetAccount(ctx, tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if acc.Balance != currency.NewAmount(tt.want) {
+				t.Errorf("wrong balance %v, want %v", acc.Balance, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresSeedRates(t *testing.T) {
+	pg := newTestClient(t)
+	ctx := context.Background()
+
+	// a new currency pair allows to rerun the test on the same database
+	suffix := fmt.Sprint(time.Now().UnixNano() % 1e6)
+	base, quote := currency.Currency("B"+suffix), currency.Currency("Q"+suffix)
+	seedTime := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	seed := func(rate float64) int {
+		t.Helper()
+		n, err := pg.SeedRates(ctx, []currency.Rate{{Base: base, Quote: quote, Rate: rate, Time: seedTime}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := seed(1.1); n != 1 {
+		t.Fatalf("wrong number of seeded rates %v, want 1", n)
+	}
+	// the rate saved through the API replaces the seeded one
+	if _, err := pg.CreateRates(ctx, []currency.Rate{{Base: base, Quote: quote, Rate: 1.2, Time: seedTime}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := seed(1.1); n != 0 {
+		t.Errorf("wrong number of seeded rates %v, want 0", n)
+	}
+
+	got, err := pg.GetRate(ctx, base, quote, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rate != 1.2 {
+		t.Errorf("wrong rate %v, want %v", got.Rate, 1.2)
+	}
+}
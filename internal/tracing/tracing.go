@@ -0,0 +1,122 @@
+// Package tracing contains thin wrappers around OpenTelemetry tracing.
+//
+// Spans are created with the OpenTelemetry SDK and propagated between services with W3C Trace Context `traceparent` header.
+// Exporters, HTTP and gRPC instrumentation come from the OpenTelemetry libraries, the package only wires them together.
+//
+// A nil `*Tracer` is a valid no-op tracer, so the tracing can be disabled by passing nil.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is a name of the tracer of the service spans
+const instrumentationName = "github.com/ilyakaznacheev/tiny-wallet"
+
+// Propagator reads and writes W3C Trace Context headers
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Tracer creates spans of the service
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// NewTracer creates a tracer of the span provider
+func NewTracer(provider *sdktrace.TracerProvider) *Tracer {
+	return &Tracer{
+		provider: provider,
+		tracer:   provider.Tracer(instrumentationName),
+	}
+}
+
+// NewProvider creates a span provider that sends spans to the exporter in batches
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+}
+
+// NewStdoutExporter creates an exporter that writes spans as JSON into w
+func NewStdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewOTLPExporter creates an exporter that sends spans to an OpenTelemetry collector over OTLP/HTTP.
+//
+// The endpoint is a collector base URL, e.g. `http://localhost:4318`
+func NewOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	opts, err := otlpOptions(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+// otlpOptions converts a collector URL into the exporter options
+func otlpOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q, expected http(s)://host:port", endpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path+"/v1/traces"))
+	}
+	return opts, nil
+}
+
+// Provider returns the span provider for the OpenTelemetry instrumentation, it is a no-op provider for a nil tracer
+func (t *Tracer) Provider() trace.TracerProvider {
+	if t == nil {
+		return trace.NewNoopTracerProvider()
+	}
+	return t.provider
+}
+
+// Start creates a new span, a child of the span in the context if there is one.
+//
+// On a nil tracer it returns a non-recording span, which is safe to use
+func (t *Tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if t == nil {
+		return trace.NewNoopTracerProvider().Tracer("").Start(ctx, name, opts...)
+	}
+	return t.tracer.Start(ctx, name, opts...)
+}
+
+// Shutdown flushes all pending spans and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// End marks the span as failed if err is not nil and finishes it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracerStart(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child", trace.WithSpanKind(trace.SpanKindClient))
+	End(child, errors.New("test error"))
+	End(root, nil)
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("wrong number of spans %d, want 2", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.Name != "child" || r.Name != "root" {
+		t.Errorf("wrong span order %s, %s", c.Name, r.Name)
+	}
+	if c.Parent.SpanID() != r.SpanContext.SpanID() {
+		t.Errorf("wrong child parent %s, want %s", c.Parent.SpanID(), r.SpanContext.SpanID())
+	}
+	if c.SpanKind != trace.SpanKindClient {
+		t.Errorf("wrong child kind %v, want %v", c.SpanKind, trace.SpanKindClient)
+	}
+	if c.Status.Code != codes.Error || c.Status.Description != "test error" {
+		t.Errorf("wrong child status %+v", c.Status)
+	}
+	if r.Status.Code != codes.Unset {
+		t.Errorf("wrong root status %+v", r.Status)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "span")
+	End(span, errors.New("test error"))
+
+	if span.SpanContext().IsValid() {
+		t.Error("nil tracer has created a recording span")
+	}
+	if tracer.Provider() == nil {
+		t.Error("nil tracer has no provider")
+	}
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("wrong path %s, want %s", r.URL.Path, "/v1/traces")
+		}
+		requests++
+	}))
+	defer srv.Close()
+
+	exp, err := NewOTLPExporter(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := NewTracer(NewProvider(exp, "test-service"))
+	_, span := tracer.Start(context.Background(), "span")
+	End(span, nil)
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("wrong number of requests %d, want 1", requests)
+	}
+}
+
+func TestOTLPOptions(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     int
+		wantErr  bool
+	}{
+		{"http://localhost:4318", 2, false},
+		{"https://collector.example.com", 1, false},
+		{"https://collector.example.com/otlp", 2, false},
+		{"localhost:4318", 0, true},
+		{"ftp://localhost", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			opts, err := otlpOptions(tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(opts) != tt.want {
+				t.Errorf("wrong number of options %d, want %d", len(opts), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+CREATE OR REPLACE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata,
+	a.wallet_id,
+	a.type
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, coalesce(amount_to, amount) as amount
+            FROM payments 
+		UNION SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
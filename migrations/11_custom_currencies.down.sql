@@ -0,0 +1,46 @@
+DROP VIEW v_accounts;
+
+ALTER TABLE interest_accruals
+    ALTER COLUMN balance TYPE bigint,
+    ALTER COLUMN amount TYPE bigint;
+
+ALTER TABLE postings
+    ALTER COLUMN currency TYPE character varying(3),
+    ALTER COLUMN amount TYPE bigint;
+
+ALTER TABLE payment_groups ALTER COLUMN amount TYPE bigint;
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE bigint,
+    ALTER COLUMN amount_to TYPE bigint;
+
+ALTER TABLE accounts
+    ALTER COLUMN currency TYPE character varying(3),
+    ALTER COLUMN balance TYPE bigint;
+
+CREATE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata,
+	a.wallet_id,
+	a.type
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, coalesce(amount_to, amount) as amount
+            FROM payments 
+		UNION ALL SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
+
+DROP TABLE currencies;
@@ -0,0 +1,53 @@
+CREATE TABLE currencies
+(
+    code character varying(10) PRIMARY KEY NOT NULL,
+    name character varying(255) NOT NULL,
+    decimals smallint NOT NULL CHECK (decimals BETWEEN 0 AND 18)
+);
+
+-- custom currency codes are longer than ISO ones, and amounts with up to 18 decimal places don't fit into bigint,
+-- the view depends on the account currency and balance, so it is recreated
+DROP VIEW v_accounts;
+
+ALTER TABLE accounts
+    ALTER COLUMN currency TYPE character varying(10),
+    ALTER COLUMN balance TYPE numeric(38, 0);
+
+ALTER TABLE payments
+    ALTER COLUMN amount TYPE numeric(38, 0),
+    ALTER COLUMN amount_to TYPE numeric(38, 0);
+
+ALTER TABLE payment_groups ALTER COLUMN amount TYPE numeric(38, 0);
+
+ALTER TABLE postings
+    ALTER COLUMN currency TYPE character varying(10),
+    ALTER COLUMN amount TYPE numeric(38, 0);
+
+ALTER TABLE interest_accruals
+    ALTER COLUMN balance TYPE numeric(38, 0),
+    ALTER COLUMN amount TYPE numeric(38, 0);
+
+CREATE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata,
+	a.wallet_id,
+	a.type
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, coalesce(amount_to, amount) as amount
+            FROM payments 
+		UNION ALL SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
@@ -0,0 +1,8 @@
+CREATE TABLE exchange_rates
+(
+    base character varying(10) NOT NULL,
+    quote character varying(10) NOT NULL,
+    rate numeric NOT NULL CHECK (rate > 0),
+    rate_time timestamp without time zone NOT NULL,
+    PRIMARY KEY (base, quote, rate_time)
+);
@@ -0,0 +1,5 @@
+DROP VIEW IF EXISTS v_accounts;
+
+DROP TABLE IF EXISTS accounts;
+
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1 @@
+-- schema_migrations is used by the migration runner itself, so it is kept on downgrade
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version integer PRIMARY KEY NOT NULL,
+    applied_at timestamp without time zone NOT NULL DEFAULT (now() at time zone 'utc')
+);
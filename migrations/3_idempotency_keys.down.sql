@@ -0,0 +1,3 @@
+DROP INDEX payments_idempotency_key_idx;
+
+ALTER TABLE payments DROP COLUMN idempotency_key;
@@ -0,0 +1,3 @@
+ALTER TABLE payments ADD COLUMN idempotency_key character varying(64);
+
+CREATE UNIQUE INDEX payments_idempotency_key_idx ON payments (account_from_id, idempotency_key);
@@ -0,0 +1,29 @@
+DROP VIEW v_accounts;
+
+CREATE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, amount
+            FROM payments 
+		UNION SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
+
+DROP INDEX accounts_labels_idx;
+DROP INDEX accounts_owner_id_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN metadata,
+    DROP COLUMN labels,
+    DROP COLUMN display_name,
+    DROP COLUMN owner_id;
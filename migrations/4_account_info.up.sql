@@ -0,0 +1,31 @@
+ALTER TABLE accounts
+    ADD COLUMN owner_id character varying(64) NOT NULL DEFAULT '',
+    ADD COLUMN display_name character varying(255) NOT NULL DEFAULT '',
+    ADD COLUMN labels text[] NOT NULL DEFAULT '{}',
+    ADD COLUMN metadata jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX accounts_owner_id_idx ON accounts (owner_id);
+CREATE INDEX accounts_labels_idx ON accounts USING gin (labels);
+
+CREATE OR REPLACE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, amount
+            FROM payments 
+		UNION SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
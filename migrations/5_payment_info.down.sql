@@ -0,0 +1,9 @@
+ALTER TABLE accounts DROP CONSTRAINT accounts_metadata_object;
+
+DROP INDEX payments_metadata_idx;
+DROP INDEX payments_reference_idx;
+
+ALTER TABLE payments
+    DROP COLUMN metadata,
+    DROP COLUMN description,
+    DROP COLUMN reference;
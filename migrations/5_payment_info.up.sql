@@ -0,0 +1,11 @@
+ALTER TABLE payments
+    ADD COLUMN reference character varying(64),
+    ADD COLUMN description character varying(255) NOT NULL DEFAULT '',
+    ADD COLUMN metadata jsonb NOT NULL DEFAULT '{}' CONSTRAINT payments_metadata_object CHECK (jsonb_typeof(metadata) = 'object');
+
+CREATE UNIQUE INDEX payments_reference_idx ON payments (account_from_id, reference);
+CREATE INDEX payments_metadata_idx ON payments USING gin (metadata);
+
+-- account metadata was stored as JSON null when it wasn't set
+UPDATE accounts SET metadata = '{}' WHERE jsonb_typeof(metadata) <> 'object';
+ALTER TABLE accounts ADD CONSTRAINT accounts_metadata_object CHECK (jsonb_typeof(metadata) = 'object');
@@ -0,0 +1,34 @@
+DROP VIEW v_accounts;
+
+CREATE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, amount
+            FROM payments 
+		UNION SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
+
+ALTER TABLE payments
+    DROP COLUMN amount_to;
+
+DROP INDEX accounts_wallet_currency_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN wallet_id;
+
+DROP TABLE wallets;
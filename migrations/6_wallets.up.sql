@@ -0,0 +1,38 @@
+CREATE TABLE wallets
+(
+    id character varying(26) PRIMARY KEY NOT NULL,
+    owner_id character varying(64) NOT NULL DEFAULT '',
+    created timestamp without time zone NOT NULL
+);
+
+ALTER TABLE accounts
+    ADD COLUMN wallet_id character varying(26) REFERENCES wallets (id);
+
+CREATE UNIQUE INDEX accounts_wallet_currency_idx ON accounts (wallet_id, currency);
+
+ALTER TABLE payments
+    ADD COLUMN amount_to bigint;
+
+CREATE OR REPLACE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata,
+	a.wallet_id
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, coalesce(amount_to, amount) as amount
+            FROM payments 
+		UNION SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
@@ -0,0 +1,7 @@
+DROP TRIGGER postings_balanced_trg ON postings;
+
+DROP FUNCTION check_journal_entry_balanced();
+
+DROP TABLE postings;
+
+DROP TABLE journal_entries;
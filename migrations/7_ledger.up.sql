@@ -0,0 +1,93 @@
+CREATE TABLE journal_entries
+(
+    id bigserial PRIMARY KEY NOT NULL,
+    trx_time timestamp without time zone NOT NULL,
+    kind character varying(16) NOT NULL,
+    payment_id bigint UNIQUE REFERENCES payments (id)
+);
+
+CREATE TABLE postings
+(
+    id bigserial PRIMARY KEY NOT NULL,
+    entry_id bigint NOT NULL REFERENCES journal_entries (id),
+    account_id character varying(30) NOT NULL,
+    currency character varying(3) NOT NULL,
+    amount bigint NOT NULL
+);
+
+CREATE INDEX postings_entry_idx ON postings (entry_id);
+CREATE INDEX postings_account_idx ON postings (account_id, currency);
+
+-- postings of a journal entry must sum to zero in each currency when the transaction commits
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+            FROM postings
+            WHERE
+                entry_id = NEW.entry_id
+            GROUP BY currency
+            HAVING sum(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced_trg
+    AFTER INSERT OR UPDATE ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE PROCEDURE check_journal_entry_balanced();
+
+-- existing payments
+INSERT INTO journal_entries (trx_time, kind, payment_id)
+SELECT
+    trx_time,
+    CASE WHEN amount_to IS NULL THEN 'payment' ELSE 'conversion' END,
+    id
+FROM payments
+ORDER BY id;
+
+INSERT INTO postings (entry_id, account_id, currency, amount)
+SELECT e.id, p.account_from_id, a.currency, p.amount * -1
+FROM journal_entries AS e
+    INNER JOIN payments AS p ON p.id = e.payment_id
+    INNER JOIN accounts AS a ON a.id = p.account_from_id
+UNION ALL SELECT e.id, p.account_to_id, b.currency, coalesce(p.amount_to, p.amount)
+FROM journal_entries AS e
+    INNER JOIN payments AS p ON p.id = e.payment_id
+    INNER JOIN accounts AS b ON b.id = p.account_to_id
+UNION ALL SELECT e.id, '@fx/' || a.currency, a.currency, p.amount
+FROM journal_entries AS e
+    INNER JOIN payments AS p ON p.id = e.payment_id AND p.amount_to IS NOT NULL
+    INNER JOIN accounts AS a ON a.id = p.account_from_id
+UNION ALL SELECT e.id, '@fx/' || b.currency, b.currency, p.amount_to * -1
+FROM journal_entries AS e
+    INNER JOIN payments AS p ON p.id = e.payment_id AND p.amount_to IS NOT NULL
+    INNER JOIN accounts AS b ON b.id = p.account_to_id;
+
+-- opening balances make ledger balances equal to v_accounts balances
+DO $$
+DECLARE
+    acc record;
+    entry bigint;
+BEGIN
+    FOR acc IN
+        SELECT v.id, v.currency, a.balance_date, v.balance - coalesce(sum(p.amount), 0) AS amount
+            FROM v_accounts AS v
+                INNER JOIN accounts AS a ON a.id = v.id
+                LEFT OUTER JOIN postings AS p ON p.account_id = v.id
+            GROUP BY v.id, v.currency, v.balance, a.balance_date
+            HAVING v.balance - coalesce(sum(p.amount), 0) <> 0
+    LOOP
+        INSERT INTO journal_entries (trx_time, kind)
+            VALUES (acc.balance_date, 'opening')
+            RETURNING id INTO entry;
+        INSERT INTO postings (entry_id, account_id, currency, amount)
+            VALUES
+                (entry, '@suspense/' || acc.currency, acc.currency, acc.amount * -1),
+                (entry, acc.id, acc.currency, acc.amount);
+    END LOOP;
+END;
+$$;
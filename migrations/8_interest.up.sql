@@ -0,0 +1,42 @@
+ALTER TABLE accounts
+    ADD COLUMN type character varying(32) NOT NULL DEFAULT '';
+
+CREATE INDEX accounts_type_idx ON accounts (type);
+
+CREATE TABLE interest_accruals
+(
+    account_id character varying(30) NOT NULL REFERENCES accounts (id),
+    accrual_date date NOT NULL,
+    balance bigint NOT NULL,
+    rate double precision NOT NULL,
+    amount bigint NOT NULL,
+    payment_id bigint REFERENCES payments (id),
+    PRIMARY KEY (account_id, accrual_date)
+);
+
+CREATE INDEX interest_accruals_unpaid_idx ON interest_accruals (account_id) WHERE payment_id IS NULL;
+
+CREATE OR REPLACE VIEW v_accounts AS
+SELECT
+	a.id, 
+	last_update, 
+	coalesce((a.balance + sum(p.amount)), a.balance) as balance,
+	a.currency,
+	a.owner_id,
+	a.display_name,
+	a.labels,
+	a.metadata,
+	a.wallet_id,
+	a.type
+FROM accounts AS a
+	LEFT OUTER JOIN 
+        (SELECT account_to_id as id, trx_time, coalesce(amount_to, amount) as amount
+            FROM payments 
+		UNION SELECT account_from_id as id, trx_time, amount * -1 as amount
+            FROM payments) AS p ON
+			p.id = a.id AND
+			p.trx_time > a.balance_date	
+GROUP BY
+	a.id,
+	a.last_update,
+	a.currency;
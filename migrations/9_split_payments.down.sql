@@ -0,0 +1,6 @@
+DROP INDEX payments_group_idx;
+
+ALTER TABLE payments
+    DROP COLUMN group_id;
+
+DROP TABLE payment_groups;
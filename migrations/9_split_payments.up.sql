@@ -0,0 +1,18 @@
+CREATE TABLE payment_groups
+(
+    id bigserial PRIMARY KEY NOT NULL,
+    account_from_id character varying(30) NOT NULL REFERENCES accounts (id),
+    trx_time timestamp without time zone NOT NULL,
+    amount bigint NOT NULL,
+    reference character varying(64),
+    description character varying(255) NOT NULL DEFAULT '',
+    metadata jsonb NOT NULL DEFAULT '{}' CONSTRAINT payment_groups_metadata_object CHECK (jsonb_typeof(metadata) = 'object')
+);
+
+CREATE UNIQUE INDEX payment_groups_reference_idx ON payment_groups (account_from_id, reference);
+
+ALTER TABLE payments
+    ADD COLUMN group_id bigint REFERENCES payment_groups (id);
+
+CREATE INDEX payments_group_idx ON payments (group_id) WHERE group_id IS NOT NULL;
+
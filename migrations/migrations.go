@@ -0,0 +1,14 @@
+// Package migrations contains database schema migrations.
+//
+// Each migration consists of two files: `<version>_<name>.up.sql` applies the change and `<version>_<name>.down.sql` reverts it.
+// Migrations are applied in order of their versions.
+//
+// The files are embedded into the application binary, see `tiny-wallet migrate` command
+package migrations
+
+import "embed"
+
+// FS contains migration SQL files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,6 @@
+// Package pb contains protobuf messages and gRPC stubs of the wallet service.
+//
+// The code is generated from wallet.proto, to regenerate it install protoc, protoc-gen-go and protoc-gen-go-grpc and run `go generate ./pb`
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative wallet.proto
@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: wallet.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// WalletClient is the client API for Wallet service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WalletClient interface {
+	// GetAllPayments returns all payments in the system
+	GetAllPayments(ctx context.Context, in *GetAllPaymentsRequest, opts ...grpc.CallOption) (*GetAllPaymentsResponse, error)
+	// GetAllAccounts returns all accounts in the system
+	GetAllAccounts(ctx context.Context, in *GetAllAccountsRequest, opts ...grpc.CallOption) (*GetAllAccountsResponse, error)
+	// PostPayment sends money from one account to another
+	PostPayment(ctx context.Context, in *PostPaymentRequest, opts ...grpc.CallOption) (*Payment, error)
+	// PostAccount creates a new account
+	PostAccount(ctx context.Context, in *PostAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	// StreamPayments sends an event for each new payment until the client cancels the call
+	StreamPayments(ctx context.Context, in *StreamPaymentsRequest, opts ...grpc.CallOption) (Wallet_StreamPaymentsClient, error)
+}
+
+type walletClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletClient(cc grpc.ClientConnInterface) WalletClient {
+	return &walletClient{cc}
+}
+
+func (c *walletClient) GetAllPayments(ctx context.Context, in *GetAllPaymentsRequest, opts ...grpc.CallOption) (*GetAllPaymentsResponse, error) {
+	out := new(GetAllPaymentsResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.Wallet/GetAllPayments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletClient) GetAllAccounts(ctx context.Context, in *GetAllAccountsRequest, opts ...grpc.CallOption) (*GetAllAccountsResponse, error) {
+	out := new(GetAllAccountsResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.Wallet/GetAllAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletClient) PostPayment(ctx context.Context, in *PostPaymentRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.Wallet/PostPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletClient) PostAccount(ctx context.Context, in *PostAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.Wallet/PostAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletClient) StreamPayments(ctx context.Context, in *StreamPaymentsRequest, opts ...grpc.CallOption) (Wallet_StreamPaymentsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Wallet_ServiceDesc.Streams[0], "/wallet.v1.Wallet/StreamPayments", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &walletStreamPaymentsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Wallet_StreamPaymentsClient interface {
+	Recv() (*PaymentEvent, error)
+	grpc.ClientStream
+}
+
+type walletStreamPaymentsClient struct {
+	grpc.ClientStream
+}
+
+func (x *walletStreamPaymentsClient) Recv() (*PaymentEvent, error) {
+	m := new(PaymentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WalletServer is the server API for Wallet service.
+// All implementations must embed UnimplementedWalletServer
+// for forward compatibility
+type WalletServer interface {
+	// GetAllPayments returns all payments in the system
+	GetAllPayments(context.Context, *GetAllPaymentsRequest) (*GetAllPaymentsResponse, error)
+	// GetAllAccounts returns all accounts in the system
+	GetAllAccounts(context.Context, *GetAllAccountsRequest) (*GetAllAccountsResponse, error)
+	// PostPayment sends money from one account to another
+	PostPayment(context.Context, *PostPaymentRequest) (*Payment, error)
+	// PostAccount creates a new account
+	PostAccount(context.Context, *PostAccountRequest) (*Account, error)
+	// StreamPayments sends an event for each new payment until the client cancels the call
+	StreamPayments(*StreamPaymentsRequest, Wallet_StreamPaymentsServer) error
+	mustEmbedUnimplementedWalletServer()
+}
+
+// UnimplementedWalletServer must be embedded to have forward compatible implementations.
+type UnimplementedWalletServer struct {
+}
+
+func (UnimplementedWalletServer) GetAllPayments(context.Context, *GetAllPaymentsRequest) (*GetAllPaymentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllPayments not implemented")
+}
+func (UnimplementedWalletServer) GetAllAccounts(context.Context, *GetAllAccountsRequest) (*GetAllAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllAccounts not implemented")
+}
+func (UnimplementedWalletServer) PostPayment(context.Context, *PostPaymentRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostPayment not implemented")
+}
+func (UnimplementedWalletServer) PostAccount(context.Context, *PostAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostAccount not implemented")
+}
+func (UnimplementedWalletServer) StreamPayments(*StreamPaymentsRequest, Wallet_StreamPaymentsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPayments not implemented")
+}
+func (UnimplementedWalletServer) mustEmbedUnimplementedWalletServer() {}
+
+// UnsafeWalletServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WalletServer will
+// result in compilation errors.
+type UnsafeWalletServer interface {
+	mustEmbedUnimplementedWalletServer()
+}
+
+func RegisterWalletServer(s grpc.ServiceRegistrar, srv WalletServer) {
+	s.RegisterService(&Wallet_ServiceDesc, srv)
+}
+
+func _Wallet_GetAllPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllPaymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).GetAllPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.Wallet/GetAllPayments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).GetAllPayments(ctx, req.(*GetAllPaymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wallet_GetAllAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).GetAllAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.Wallet/GetAllAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).GetAllAccounts(ctx, req.(*GetAllAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wallet_PostPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).PostPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.Wallet/PostPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).PostPayment(ctx, req.(*PostPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wallet_PostAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServer).PostAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.Wallet/PostAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServer).PostAccount(ctx, req.(*PostAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wallet_StreamPayments_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPaymentsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WalletServer).StreamPayments(m, &walletStreamPaymentsServer{stream})
+}
+
+type Wallet_StreamPaymentsServer interface {
+	Send(*PaymentEvent) error
+	grpc.ServerStream
+}
+
+type walletStreamPaymentsServer struct {
+	grpc.ServerStream
+}
+
+func (x *walletStreamPaymentsServer) Send(m *PaymentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Wallet_ServiceDesc is the grpc.ServiceDesc for Wallet service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Wallet_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wallet.v1.Wallet",
+	HandlerType: (*WalletServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAllPayments",
+			Handler:    _Wallet_GetAllPayments_Handler,
+		},
+		{
+			MethodName: "GetAllAccounts",
+			Handler:    _Wallet_GetAllAccounts_Handler,
+		},
+		{
+			MethodName: "PostPayment",
+			Handler:    _Wallet_PostPayment_Handler,
+		},
+		{
+			MethodName: "PostAccount",
+			Handler:    _Wallet_PostAccount_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPayments",
+			Handler:       _Wallet_StreamPayments_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "wallet.proto",
+}
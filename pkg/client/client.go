@@ -0,0 +1,704 @@
+// Package client contains a Go client of the Tiny Wallet HTTP API.
+//
+// The client implements `wallet.Service` interface, so it can be used in place of the local service.
+// Request and response types of the interface are defined in package `model`:
+//
+//	c, err := client.New("http://localhost:8080", client.WithRetries(3, 100*time.Millisecond))
+//	if err != nil {
+//		...
+//	}
+//	p, err := c.PostPayment(ctx, "alice", "bob", 10.5, model.PaymentInfo{Reference: "invoice-42"})
+//
+// Service errors are returned as `wallet.HTTPError` with the status code of the response.
+// Known rejection reasons, e.g. `wallet.ErrInsufficientFunds`, can be checked with `xerrors.Is`.
+// Invalid request fields are listed in `wallet.ValidationError`, get it with `xerrors.As`.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+// defaultTimeout is a default time limit of a single call including retries
+const defaultTimeout = 30 * time.Second
+
+// Option sets an optional parameter of the client
+type Option func(*options)
+
+// options is a set of optional client parameters
+type options struct {
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+	autoKeys   bool
+}
+
+// WithHTTPClient sets an HTTP client to send requests with
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithTimeout sets a time limit of a single call including all retries. The default is 30 seconds
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetries makes the client retry failed calls up to max times.
+//
+// The pause before the n-th retry is n*backoff. See `Client` for the list of errors that are retried
+func WithRetries(max int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = max
+		o.backoff = backoff
+	}
+}
+
+// WithIdempotencyKeys makes the client generate an idempotency key for each payment,
+// unless the key is already set with `wallet.ContextWithIdempotencyKey`
+func WithIdempotencyKeys() Option {
+	return func(o *options) {
+		o.autoKeys = true
+	}
+}
+
+// Client is a remote wallet service.
+//
+// Read calls are retried on network errors and 502, 503 and 504 responses.
+// Create calls are retried only if the request was not processed: on 503 responses of a draining service and payment conflicts.
+// They are not retried on network errors and 502 and 504 responses, because the request could have been processed and would be applied twice.
+// Payments with an idempotency key are retried like read calls, the service returns the original payment for a repeated key
+type Client struct {
+	getAllPayments endpoint.Endpoint
+	getAllAccounts endpoint.Endpoint
+	postPayment    endpoint.Endpoint
+	postSplit      endpoint.Endpoint
+	postAccount    endpoint.Endpoint
+	patchAccount   endpoint.Endpoint
+	exportPayments endpoint.Endpoint
+	exportAccounts endpoint.Endpoint
+	importAccounts endpoint.Endpoint
+	postWallet     endpoint.Endpoint
+	getWallet      endpoint.Endpoint
+	convertFunds   endpoint.Endpoint
+	trialBalance   endpoint.Endpoint
+	interest       endpoint.Endpoint
+	postRates      endpoint.Endpoint
+	getRate        endpoint.Endpoint
+}
+
+var _ wallet.Service = (*Client)(nil)
+
+// New creates a new client of the service instance.
+//
+// The instance is a service URL like `http://localhost:8080`
+func New(instance string, opts ...Option) (*Client, error) {
+	o := options{
+		timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var clientOpts []httptransport.ClientOption
+	if o.httpClient != nil {
+		clientOpts = append(clientOpts, httptransport.SetClient(o.httpClient))
+	}
+
+	e, err := wallet.MakeClientEndpoints(instance, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	read := endpoint.Chain(
+		timeout(o.timeout),
+		retry(o.retries, o.backoff, always),
+	)
+	// export responses are read after the endpoint returns, so the call time is limited only by the caller context
+	export := retry(o.retries, o.backoff, always)
+	create := endpoint.Chain(
+		timeout(o.timeout),
+		retry(o.retries, o.backoff, never),
+	)
+	// the key is set before the retries, so all of them send the same key
+	pay := endpoint.Chain(
+		timeout(o.timeout),
+		idempotencyKey(o.autoKeys),
+		retry(o.retries, o.backoff, hasIdempotencyKey),
+	)
+
+	return &Client{
+		getAllPayments: read(e.GetAllPaymentsEndpoint),
+		getAllAccounts: read(e.GetAllAccountsEndpoint),
+		postPayment:    pay(e.PostPayment),
+		postSplit:      create(e.PostSplitPayment),
+		postAccount:    create(e.PostAccount),
+		patchAccount:   create(e.PatchAccount),
+		exportPayments: export(e.ExportPaymentsEndpoint),
+		exportAccounts: export(e.ExportAccountsEndpoint),
+		importAccounts: create(e.ImportAccountsEndpoint),
+		postWallet:     create(e.PostWallet),
+		getWallet:      read(e.GetWallet),
+		convertFunds:   create(e.ConvertFunds),
+		trialBalance:   read(e.GetTrialBalance),
+		interest:       read(e.GetInterestAccruals),
+		postRates:      create(e.PostRates),
+		getRate:        read(e.GetRate),
+	}, nil
+}
+
+// GetAllPayments returns payments in the system matching the filter
+func (c *Client) GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error) {
+	resp, err := c.getAllPayments(ctx, wallet.GetAllPaymentsRequest{
+		Reference: filter.Reference,
+		Search:    filter.Search,
+		Metadata:  filter.Metadata,
+		GroupID:   filter.GroupID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	payments := resp.(wallet.GetAllPaymentsResponse).Payments
+
+	res := make([]model.Payment, 0, len(payments))
+	for _, p := range payments {
+		rec, err := convertPayment(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+// GetAllAccounts returns accounts in the system matching the filter
+func (c *Client) GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error) {
+	resp, err := c.getAllAccounts(ctx, wallet.GetAllAccountsRequest{
+		OwnerID:   filter.OwnerID,
+		Labels:    filter.Labels,
+		ConvertTo: filter.ConvertTo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	accounts := resp.(wallet.GetAllAccountsResponse).Accounts
+
+	res := make([]model.Account, 0, len(accounts))
+	for _, a := range accounts {
+		rec, err := convertAccount(a)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+// PostPayment sends money from one account to another
+func (c *Client) PostPayment(ctx context.Context, from, to string, amount float64, info model.PaymentInfo) (*model.Payment, error) {
+	resp, err := c.postPayment(ctx, wallet.PostPaymentRequest{
+		AccountFromID: from,
+		AccountToID:   to,
+		Amount:        amount,
+		Reference:     info.Reference,
+		Description:   info.Description,
+		Metadata:      info.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p, err := convertPayment(*resp.(*wallet.Payment))
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PostSplitPayment processes a payment from one payer to many receivers
+func (c *Client) PostSplitPayment(ctx context.Context, from string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error) {
+	req := wallet.PostSplitPaymentRequest{
+		AccountFromID: from,
+		Amount:        amount,
+		Receivers:     make([]wallet.SplitReceiver, 0, len(receivers)),
+		Reference:     info.Reference,
+		Description:   info.Description,
+		Metadata:      info.Metadata,
+	}
+	for _, r := range receivers {
+		req.Receivers = append(req.Receivers, wallet.SplitReceiver{
+			AccountID: r.AccountID,
+			Amount:    r.Amount,
+			Percent:   r.Percent,
+		})
+	}
+	resp, err := c.postSplit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	g := resp.(*wallet.PaymentGroup)
+
+	var conv amountConverter
+	res := model.PaymentGroup{
+		ID:        g.ID,
+		AccFromID: g.AccFromID,
+		DateTime:  g.DateTime,
+		Amount:    conv.toInternal(g.Amount, g.Currency),
+		Currency:  g.Currency,
+		Payments:  make([]model.Payment, 0, len(g.Payments)),
+		PaymentInfo: model.PaymentInfo{
+			Reference:   g.Reference,
+			Description: g.Description,
+			Metadata:    g.Metadata,
+		},
+	}
+	if conv.err != nil {
+		return nil, conv.err
+	}
+	for _, p := range g.Payments {
+		rec, err := convertPayment(p)
+		if err != nil {
+			return nil, err
+		}
+		res.Payments = append(res.Payments, rec)
+	}
+	return &res, nil
+}
+
+// PostAccount creates a new account
+func (c *Client) PostAccount(ctx context.Context, id string, balance float64, curr string, info model.AccountInfo) (*model.Account, error) {
+	resp, err := c.postAccount(ctx, wallet.PostAccountRequest{
+		ID:          id,
+		Balance:     balance,
+		Currency:    curr,
+		OwnerID:     info.OwnerID,
+		DisplayName: info.DisplayName,
+		Labels:      info.Labels,
+		Metadata:    info.Metadata,
+		Type:        info.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a, err := convertAccount(*resp.(*wallet.Account))
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// PatchAccount changes the account owner, display name, labels or metadata
+func (c *Client) PatchAccount(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error) {
+	resp, err := c.patchAccount(ctx, wallet.PatchAccountRequest{
+		ID:          id,
+		OwnerID:     patch.OwnerID,
+		DisplayName: patch.DisplayName,
+		Labels:      patch.Labels,
+		Metadata:    patch.Metadata,
+		Type:        patch.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a, err := convertAccount(*resp.(*wallet.Account))
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ExportPayments reads payments of the period one by one from the export stream.
+//
+// Unlike other calls, the export time is not limited by the client timeout
+func (c *Client) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error {
+	resp, err := c.exportPayments(ctx, wallet.ExportPaymentsRequest{
+		Format: "jsonl",
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		return err
+	}
+	body := resp.(io.ReadCloser)
+	defer body.Close()
+
+	dec := json.NewDecoder(body)
+	for {
+		var rec wallet.PaymentRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		amount, err := rec.Currency.ParseDecimal(rec.Amount)
+		if err != nil {
+			return err
+		}
+		// the receiver amount is set only for conversions
+		var toAmount currency.Amount
+		if rec.ToCurrency != "" {
+			if toAmount, err = rec.ToCurrency.ParseDecimal(rec.ToAmount); err != nil {
+				return err
+			}
+		}
+		err = fn(model.Payment{
+			ID:         rec.ID,
+			AccFromID:  rec.AccFromID,
+			AccToID:    rec.AccToID,
+			DateTime:   rec.DateTime,
+			Amount:     amount,
+			Currency:   rec.Currency,
+			ToAmount:   toAmount,
+			ToCurrency: rec.ToCurrency,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ExportAccounts reads accounts one by one from the export stream.
+//
+// Unlike other calls, the export time is not limited by the client timeout
+func (c *Client) ExportAccounts(ctx context.Context, fn func(model.Account) error) error {
+	resp, err := c.exportAccounts(ctx, wallet.ExportAccountsRequest{
+		Format: "jsonl",
+	})
+	if err != nil {
+		return err
+	}
+	body := resp.(io.ReadCloser)
+	defer body.Close()
+
+	dec := json.NewDecoder(body)
+	for {
+		var rec wallet.AccountRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		balance, err := rec.Currency.ParseDecimal(rec.Balance)
+		if err != nil {
+			return err
+		}
+		err = fn(model.Account{
+			ID:         rec.ID,
+			LastUpdate: rec.LastUpdate,
+			Balance:    balance,
+			Currency:   rec.Currency,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ImportAccounts creates accounts in bulk.
+//
+// The rows are sent as JSON Lines, line numbers of rejected rows are restored from the given rows
+func (c *Client) ImportAccounts(ctx context.Context, rows []model.AccountImport, dryRun bool) (*model.ImportResult, error) {
+	resp, err := c.importAccounts(ctx, wallet.ImportAccountsRequest{
+		Rows:   rows,
+		DryRun: dryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := resp.(wallet.ImportAccountsResponse)
+
+	res := &model.ImportResult{
+		Imported: r.Imported,
+		DryRun:   r.DryRun,
+	}
+	for _, e := range r.Errors {
+		line := e.Line
+		if line > 0 && line <= len(rows) {
+			line = rows[line-1].Line
+		}
+		res.Errors = append(res.Errors, model.ImportError{
+			Line:  line,
+			ID:    e.ID,
+			Error: e.Error,
+		})
+	}
+	return res, nil
+}
+
+// PostWallet creates a new wallet with a pocket in each currency
+func (c *Client) PostWallet(ctx context.Context, id, ownerID string, currencies []string) (*model.Wallet, error) {
+	resp, err := c.postWallet(ctx, wallet.PostWalletRequest{
+		ID:         id,
+		OwnerID:    ownerID,
+		Currencies: currencies,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w, err := convertWallet(*resp.(*wallet.Wallet))
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetWallet returns a wallet with pocket balances and the total in totalCurrency, or in the service base currency if it is empty
+func (c *Client) GetWallet(ctx context.Context, id, totalCurrency string) (*model.Wallet, error) {
+	resp, err := c.getWallet(ctx, wallet.GetWalletRequest{
+		ID:            id,
+		TotalCurrency: totalCurrency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w, err := convertWallet(*resp.(*wallet.Wallet))
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// ConvertFunds moves money between two pockets of the wallet
+func (c *Client) ConvertFunds(ctx context.Context, walletID, from, to string, amount float64) (*model.Payment, error) {
+	resp, err := c.convertFunds(ctx, wallet.ConvertFundsRequest{
+		WalletID: walletID,
+		From:     from,
+		To:       to,
+		Amount:   amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p, err := convertPayment(*resp.(*wallet.Payment))
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetTrialBalance returns sums of ledger accounts
+func (c *Client) GetTrialBalance(ctx context.Context) (*model.TrialBalance, error) {
+	resp, err := c.trialBalance(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	tb := resp.(*wallet.TrialBalance)
+
+	var conv amountConverter
+	res := model.TrialBalance{
+		Accounts: make([]model.TrialBalanceAccount, 0, len(tb.Accounts)),
+		Totals:   make([]model.TrialBalanceTotal, 0, len(tb.Totals)),
+		Balanced: tb.Balanced,
+	}
+	for _, a := range tb.Accounts {
+		res.Accounts = append(res.Accounts, model.TrialBalanceAccount{
+			AccountID: a.AccountID,
+			Currency:  a.Currency,
+			Debit:     conv.toInternal(a.Debit, a.Currency),
+			Credit:    conv.toInternal(a.Credit, a.Currency),
+			Balance:   conv.toInternal(a.Balance, a.Currency),
+			System:    a.System,
+		})
+	}
+	for _, t := range tb.Totals {
+		res.Totals = append(res.Totals, model.TrialBalanceTotal{
+			Currency: t.Currency,
+			Debit:    conv.toInternal(t.Debit, t.Currency),
+			Credit:   conv.toInternal(t.Credit, t.Currency),
+		})
+	}
+	if conv.err != nil {
+		return nil, conv.err
+	}
+	return &res, nil
+}
+
+// GetInterestAccruals returns daily interest accrued on the account in historical order
+func (c *Client) GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error) {
+	resp, err := c.interest(ctx, wallet.GetInterestAccrualsRequest{AccountID: accountID})
+	if err != nil {
+		return nil, err
+	}
+	accruals := resp.(wallet.GetInterestAccrualsResponse).Accruals
+
+	var conv amountConverter
+	res := make([]model.InterestAccrual, 0, len(accruals))
+	for _, a := range accruals {
+		date, err := time.Parse("2006-01-02", a.Date)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, model.InterestAccrual{
+			AccountID: accountID,
+			Date:      date,
+			Balance:   conv.toInternal(a.Balance, a.Currency),
+			Rate:      a.Rate,
+			Amount:    conv.toInternal(a.Amount, a.Currency),
+			Currency:  a.Currency,
+			PaymentID: a.PaymentID,
+		})
+	}
+	if conv.err != nil {
+		return nil, conv.err
+	}
+	return res, nil
+}
+
+// amountConverter converts API amounts into the lowest currency units and keeps the first error.
+//
+// API amounts are already rounded by the server, so the rounding mode only compensates the float error
+type amountConverter struct {
+	err error
+}
+
+// toInternal converts the amount, it returns zero after an error
+func (c *amountConverter) toInternal(m float64, curr currency.Currency) currency.Amount {
+	if c.err != nil {
+		return currency.Amount{}
+	}
+	res, err := currency.ConvertToInternal(m, curr, currency.RoundHalfEven)
+	if err != nil {
+		c.err = fmt.Errorf("invalid amount %v %s in the response: %w", m, curr, err)
+	}
+	return res
+}
+
+// convertPayment converts an API payment into the internal representation
+func convertPayment(p wallet.Payment) (model.Payment, error) {
+	var conv amountConverter
+	res := model.Payment{
+		AccFromID:  p.AccFromID,
+		AccToID:    p.AccToID,
+		DateTime:   p.DateTime,
+		Amount:     conv.toInternal(p.Amount, p.Currency),
+		Currency:   p.Currency,
+		ToAmount:   conv.toInternal(p.ToAmount, p.ToCurrency),
+		ToCurrency: p.ToCurrency,
+		GroupID:    p.GroupID,
+		PaymentInfo: model.PaymentInfo{
+			Reference:   p.Reference,
+			Description: p.Description,
+			Metadata:    p.Metadata,
+		},
+	}
+	return res, conv.err
+}
+
+// PostRates saves historical exchange rates, rates without a time are set now
+func (c *Client) PostRates(ctx context.Context, rates []currency.Rate) (int, error) {
+	req := wallet.PostRatesRequest{
+		Rates: make([]wallet.Rate, 0, len(rates)),
+	}
+	for _, r := range rates {
+		rate := wallet.Rate{
+			Base:  r.Base,
+			Quote: r.Quote,
+			Rate:  r.Rate,
+		}
+		if !r.Time.IsZero() {
+			t := r.Time
+			rate.Time = &t
+		}
+		req.Rates = append(req.Rates, rate)
+	}
+	resp, err := c.postRates(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return resp.(wallet.PostRatesResponse).Saved, nil
+}
+
+// GetRate returns an exchange rate from base to quote currency at the time, or now if the time is zero
+func (c *Client) GetRate(ctx context.Context, base, quote string, at time.Time) (*currency.Rate, error) {
+	req := wallet.GetRateRequest{
+		Base:  base,
+		Quote: quote,
+	}
+	if !at.IsZero() {
+		req.At = &at
+	}
+	resp, err := c.getRate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r := convertRate(*resp.(*wallet.Rate))
+	return &r, nil
+}
+
+// convertAccount converts an API account into the internal representation
+func convertAccount(a wallet.Account) (model.Account, error) {
+	var conv amountConverter
+	res := model.Account{
+		ID:       a.ID,
+		Balance:  conv.toInternal(a.Balance, a.Currency),
+		Currency: a.Currency,
+		AccountInfo: model.AccountInfo{
+			OwnerID:     a.OwnerID,
+			DisplayName: a.DisplayName,
+			Labels:      a.Labels,
+			Metadata:    a.Metadata,
+			Type:        a.Type,
+		},
+	}
+	if c := a.Converted; c != nil {
+		res.Converted = &model.ConvertedBalance{
+			Balance:  conv.toInternal(c.Balance, c.Currency),
+			Currency: c.Currency,
+			Rate: currency.Rate{
+				Base:  a.Currency,
+				Quote: c.Currency,
+				Rate:  c.Rate,
+				Time:  c.RateTime,
+			},
+		}
+	}
+	return res, conv.err
+}
+
+// convertRate converts an API exchange rate into the internal representation
+func convertRate(r wallet.Rate) currency.Rate {
+	res := currency.Rate{
+		Base:  r.Base,
+		Quote: r.Quote,
+		Rate:  r.Rate,
+	}
+	if r.Time != nil {
+		res.Time = *r.Time
+	}
+	return res
+}
+
+// convertWallet converts an API wallet into the internal representation
+func convertWallet(w wallet.Wallet) (model.Wallet, error) {
+	var conv amountConverter
+	res := model.Wallet{
+		ID:            w.ID,
+		OwnerID:       w.OwnerID,
+		Total:         conv.toInternal(w.Total, w.TotalCurrency),
+		TotalCurrency: w.TotalCurrency,
+	}
+	if conv.err != nil {
+		return model.Wallet{}, conv.err
+	}
+	for _, a := range w.Pockets {
+		pocket, err := convertAccount(a)
+		if err != nil {
+			return model.Wallet{}, err
+		}
+		res.Pockets = append(res.Pockets, pocket)
+	}
+	return res, nil
+}
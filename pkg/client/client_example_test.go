@@ -0,0 +1,37 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/client"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+func Example() {
+	c, err := client.New("http://localhost:8080",
+		client.WithTimeout(5*time.Second),
+		client.WithRetries(3, 100*time.Millisecond),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// the client can be used in place of the local service
+	var s wallet.Service = c
+
+	ctx := context.Background()
+	p, err := s.PostPayment(ctx, "alice", "bob", 10.5, model.PaymentInfo{Reference: "invoice-42"})
+	if xerrors.Is(err, wallet.ErrInsufficientFunds) {
+		fmt.Println("not enough money")
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(p.AccFromID, p.AccToID, p.Amount)
+}
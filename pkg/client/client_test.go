@@ -0,0 +1,791 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+// testService is a wallet service stub
+type testService struct {
+	mu        sync.Mutex
+	calls     int
+	accounts  []model.Account
+	payErrs   []error
+	delay     time.Duration
+	payments  []model.Payment
+	exportErr error
+	rates     []currency.Rate
+	// keyed are created payments by idempotency key
+	keyed map[string]*model.Payment
+}
+
+func (s *testService) GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error) {
+	var res []model.Payment
+	for _, p := range s.payments {
+		if filter.Reference != "" && p.Reference != filter.Reference {
+			continue
+		}
+		if !hasMetadata(p.Metadata, filter.Metadata) {
+			continue
+		}
+		if filter.GroupID != 0 && p.GroupID != filter.GroupID {
+			continue
+		}
+		res = append(res, p)
+	}
+	if len(res) == 0 {
+		return nil, wallet.NewErrHTTPStatusf(http.StatusNotFound, nil, "no payment found")
+	}
+	return res, nil
+}
+
+func hasMetadata(metadata, filter map[string]string) bool {
+	for k, v := range filter {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *testService) GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error) {
+	time.Sleep(s.delay)
+	var res []model.Account
+accounts:
+	for _, a := range s.accounts {
+		if filter.OwnerID != "" && a.OwnerID != filter.OwnerID {
+			continue
+		}
+		for _, l := range filter.Labels {
+			if !hasLabel(a.Labels, l) {
+				continue accounts
+			}
+		}
+		if filter.ConvertTo != "" {
+			to := currency.Currency(filter.ConvertTo)
+			balance, err := a.Balance.Mul(2)
+			if err != nil {
+				return nil, err
+			}
+			a.Converted = &model.ConvertedBalance{
+				Balance:  balance,
+				Currency: to,
+				Rate:     currency.Rate{Base: a.Currency, Quote: to, Rate: 2, Time: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+			}
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *testService) PatchAccount(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error) {
+	a := model.Account{ID: id, Currency: currency.USD}
+	if patch.DisplayName != nil {
+		a.DisplayName = *patch.DisplayName
+	}
+	if patch.Labels != nil {
+		a.Labels = *patch.Labels
+	}
+	if patch.Metadata != nil {
+		a.Metadata = *patch.Metadata
+	}
+	return &a, nil
+}
+
+func (s *testService) PostPayment(ctx context.Context, from, to string, amount float64, info model.PaymentInfo) (*model.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if len(s.payErrs) > 0 {
+		err := s.payErrs[0]
+		s.payErrs = s.payErrs[1:]
+		return nil, err
+	}
+	key := wallet.IdempotencyKeyFromContext(ctx)
+	if p, ok := s.keyed[key]; ok {
+		return p, nil
+	}
+	p := &model.Payment{
+		ID:             len(s.keyed) + 1,
+		AccFromID:      from,
+		AccToID:        to,
+		Amount:         toInternal(amount, currency.USD),
+		Currency:       currency.USD,
+		IdempotencyKey: key,
+		PaymentInfo:    info,
+	}
+	if key != "" {
+		if s.keyed == nil {
+			s.keyed = make(map[string]*model.Payment)
+		}
+		s.keyed[key] = p
+	}
+	return p, nil
+}
+
+func (s *testService) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error {
+	for _, p := range s.payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return s.exportErr
+}
+
+func (s *testService) ExportAccounts(ctx context.Context, fn func(model.Account) error) error {
+	for _, a := range s.accounts {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return s.exportErr
+}
+
+func (s *testService) ImportAccounts(ctx context.Context, rows []model.AccountImport, dryRun bool) (*model.ImportResult, error) {
+	res := &model.ImportResult{DryRun: dryRun}
+	for _, r := range rows {
+		if r.Currency != "USD" {
+			res.Errors = append(res.Errors, model.ImportError{Line: r.Line, ID: r.ID, Error: "unknown currency"})
+		}
+	}
+	if len(res.Errors) == 0 {
+		res.Imported = len(rows)
+	}
+	return res, nil
+}
+
+func (s *testService) PostAccount(ctx context.Context, id string, balance float64, curr string, info model.AccountInfo) (*model.Account, error) {
+	return nil, wallet.NewErrHTTPStatusf(http.StatusConflict, nil, "account %s already exists", id)
+}
+
+func (s *testService) PostWallet(ctx context.Context, id, ownerID string, currencies []string) (*model.Wallet, error) {
+	return nil, wallet.NewErrHTTPStatusf(http.StatusConflict, nil, "wallet %s already exists", id)
+}
+
+func (s *testService) GetWallet(ctx context.Context, id, totalCurrency string) (*model.Wallet, error) {
+	if totalCurrency == "" {
+		totalCurrency = "USD"
+	}
+	return &model.Wallet{
+		ID: id,
+		Pockets: []model.Account{
+			{ID: id + "/EUR", Balance: currency.NewAmount(1000), Currency: currency.EUR},
+			{ID: id + "/USD", Balance: currency.NewAmount(500), Currency: currency.USD},
+		},
+		Total:         currency.NewAmount(1600),
+		TotalCurrency: currency.Currency(totalCurrency),
+	}, nil
+}
+
+func (s *testService) ConvertFunds(ctx context.Context, walletID, from, to string, amount float64) (*model.Payment, error) {
+	return &model.Payment{
+		AccFromID:  walletID + "/" + from,
+		AccToID:    walletID + "/" + to,
+		Amount:     toInternal(amount, currency.Currency(from)),
+		Currency:   currency.Currency(from),
+		ToAmount:   toInternal(amount*1.1, currency.Currency(to)),
+		ToCurrency: currency.Currency(to),
+	}, nil
+}
+
+func (s *testService) PostSplitPayment(ctx context.Context, from string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error) {
+	g := model.PaymentGroup{
+		ID:          7,
+		AccFromID:   from,
+		Amount:      toInternal(amount, currency.USD),
+		Currency:    currency.USD,
+		PaymentInfo: info,
+	}
+	for _, r := range receivers {
+		g.Payments = append(g.Payments, model.Payment{
+			AccFromID: from,
+			AccToID:   r.AccountID,
+			Amount:    toInternal(r.Amount+amount*r.Percent/100, currency.USD),
+			Currency:  currency.USD,
+			GroupID:   g.ID,
+		})
+	}
+	return &g, nil
+}
+
+func (s *testService) GetTrialBalance(ctx context.Context) (*model.TrialBalance, error) {
+	tb := model.NewTrialBalance([]model.TrialBalanceAccount{
+		{AccountID: "@fx/USD", Currency: currency.USD, Credit: currency.NewAmount(1000)},
+		{AccountID: "alice/USD", Currency: currency.USD, Debit: currency.NewAmount(1000)},
+		{AccountID: "@fx/EUR", Currency: currency.EUR, Debit: currency.NewAmount(909)},
+		{AccountID: "alice/EUR", Currency: currency.EUR, Credit: currency.NewAmount(909)},
+	})
+	return &tb, nil
+}
+
+func (s *testService) GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error) {
+	return []model.InterestAccrual{
+		{AccountID: accountID, Date: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC), Balance: currency.NewAmount(100000), Rate: 0.0365, Amount: currency.NewAmount(10), Currency: currency.USD, PaymentID: 7},
+		{AccountID: accountID, Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Balance: currency.NewAmount(100010), Rate: 0.0365, Amount: currency.NewAmount(10), Currency: currency.USD},
+	}, nil
+}
+
+func (s *testService) PostRates(ctx context.Context, rates []currency.Rate) (int, error) {
+	s.rates = append(s.rates, rates...)
+	return len(rates), nil
+}
+
+func (s *testService) GetRate(ctx context.Context, base, quote string, at time.Time) (*currency.Rate, error) {
+	for _, r := range s.rates {
+		if string(r.Base) == base && string(r.Quote) == quote && !r.Time.After(at) {
+			return &r, nil
+		}
+	}
+	return nil, wallet.NewErrHTTPStatusf(http.StatusNotFound, wallet.ErrNoExchangeRate, "no %s/%s exchange rate", base, quote)
+}
+
+func newTestServer(t *testing.T, s wallet.Service) *httptest.Server {
+	srv := httptest.NewServer(wallet.MakeHTTPHandler(s, log.NewNopLogger()))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientGetAllAccounts(t *testing.T) {
+	accounts := []model.Account{
+		{ID: "alice", Balance: currency.NewAmount(12345), Currency: currency.USD},
+		{ID: "bob", Balance: currency.NewAmount(12345), Currency: currency.BHD},
+		{ID: "carol", Balance: currency.NewAmount(100), Currency: currency.USD, AccountInfo: model.AccountInfo{
+			OwnerID:     "customer-1",
+			DisplayName: "Carol",
+			Labels:      []string{"vip", "partner"},
+			Metadata:    map[string]string{"tier": "gold"},
+		}},
+	}
+	srv := newTestServer(t, &testService{accounts: accounts})
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetAllAccounts(context.Background(), model.AccountFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, accounts) {
+		t.Errorf("wrong accounts %v, want %v", got, accounts)
+	}
+
+	got, err = c.GetAllAccounts(context.Background(), model.AccountFilter{OwnerID: "customer-1", Labels: []string{"partner", "vip"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, accounts[2:]) {
+		t.Errorf("wrong filtered accounts %v, want %v", got, accounts[2:])
+	}
+}
+
+func TestClientGetAllPayments(t *testing.T) {
+	now := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	payments := []model.Payment{
+		{AccFromID: "alice", AccToID: "bob", DateTime: now, Amount: currency.NewAmount(100), Currency: currency.USD},
+		{AccFromID: "alice", AccToID: "bob", DateTime: now, Amount: currency.NewAmount(200), Currency: currency.USD, PaymentInfo: model.PaymentInfo{
+			Reference:   "invoice-42",
+			Description: "May rent",
+			Metadata:    map[string]string{"order": "42", "channel": "web"},
+		}},
+		{AccFromID: "alice", AccToID: "carol", DateTime: now, Amount: currency.NewAmount(300), Currency: currency.USD, GroupID: 7},
+	}
+	srv := newTestServer(t, &testService{payments: payments})
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetAllPayments(context.Background(), model.PaymentFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, payments) {
+		t.Errorf("wrong payments %v, want %v", got, payments)
+	}
+
+	got, err = c.GetAllPayments(context.Background(), model.PaymentFilter{Reference: "invoice-42", Metadata: map[string]string{"order": "42"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, payments[1:2]) {
+		t.Errorf("wrong filtered payments %v, want %v", got, payments[1:2])
+	}
+
+	got, err = c.GetAllPayments(context.Background(), model.PaymentFilter{GroupID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, payments[2:]) {
+		t.Errorf("wrong group payments %v, want %v", got, payments[2:])
+	}
+}
+
+func TestClientPostSplitPayment(t *testing.T) {
+	srv := newTestServer(t, &testService{})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := c.PostSplitPayment(context.Background(), "buyer", 100, []model.SplitReceiver{
+		{AccountID: "seller", Amount: 90},
+		{AccountID: "platform", Percent: 10},
+	}, model.PaymentInfo{Reference: "order-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ID != 7 || g.Amount != currency.NewAmount(10000) || g.Reference != "order-1" || len(g.Payments) != 2 {
+		t.Fatalf("wrong split payment %+v", g)
+	}
+	for i, want := range []int64{9000, 1000} {
+		if p := g.Payments[i]; p.Amount != currency.NewAmount(want) || p.GroupID != 7 {
+			t.Errorf("wrong payment %+v, want amount %v in group 7", p, want)
+		}
+	}
+}
+
+func TestClientWallet(t *testing.T) {
+	srv := newTestServer(t, &testService{})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := c.GetWallet(context.Background(), "alice", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Pockets) != 2 || w.Pockets[0].ID != "alice/EUR" || w.TotalCurrency != currency.EUR || w.Total != currency.NewAmount(1600) {
+		t.Errorf("wrong wallet %+v", w)
+	}
+
+	p, err := c.ConvertFunds(context.Background(), "alice", "USD", "EUR", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Amount != currency.NewAmount(1000) || p.ToAmount != currency.NewAmount(1100) || p.ToCurrency != currency.EUR || p.AccToID != "alice/EUR" {
+		t.Errorf("wrong conversion %+v", p)
+	}
+}
+
+func TestClientGetTrialBalance(t *testing.T) {
+	srv := newTestServer(t, &testService{})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetTrialBalance(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := (&testService{}).GetTrialBalance(context.Background())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong trial balance %+v, want %+v", got, want)
+	}
+}
+
+func TestClientGetAllAccountsConverted(t *testing.T) {
+	srv := newTestServer(t, &testService{accounts: []model.Account{{ID: "alice", Balance: currency.NewAmount(12345), Currency: currency.USD}}})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetAllAccounts(context.Background(), model.AccountFilter{ConvertTo: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &model.ConvertedBalance{
+		Balance:  currency.NewAmount(24690),
+		Currency: currency.EUR,
+		Rate:     currency.Rate{Base: currency.USD, Quote: currency.EUR, Rate: 2, Time: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Converted, want) {
+		t.Errorf("wrong converted balance %+v, want %+v", got, want)
+	}
+}
+
+func TestClientRates(t *testing.T) {
+	s := &testService{}
+	srv := newTestServer(t, s)
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rates := []currency.Rate{
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)},
+		{Base: currency.GBP, Quote: currency.USD, Rate: 1.27},
+	}
+	saved, err := c.PostRates(context.Background(), rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 2 {
+		t.Errorf("wrong saved rates %v, want %v", saved, 2)
+	}
+	if !reflect.DeepEqual(s.rates, rates) {
+		t.Errorf("wrong rates %v, want %v", s.rates, rates)
+	}
+
+	got, err := c.GetRate(context.Background(), "EUR", "USD", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, rates[0]) {
+		t.Errorf("wrong rate %v, want %v", *got, rates[0])
+	}
+
+	_, err = c.GetRate(context.Background(), "EUR", "USD", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !xerrors.Is(err, wallet.ErrNoExchangeRate) {
+		t.Errorf("wrong error %v, want %v", err, wallet.ErrNoExchangeRate)
+	}
+}
+
+func TestClientGetInterestAccruals(t *testing.T) {
+	srv := newTestServer(t, &testService{})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetInterestAccruals(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := (&testService{}).GetInterestAccruals(context.Background(), "alice")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong accruals %+v, want %+v", got, want)
+	}
+}
+
+func TestClientPatchAccount(t *testing.T) {
+	srv := newTestServer(t, &testService{})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Alice"
+	labels := []string{"vip"}
+	got, err := c.PatchAccount(context.Background(), "alice 1", model.AccountPatch{DisplayName: &name, Labels: &labels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &model.Account{ID: "alice 1", Currency: currency.USD, AccountInfo: model.AccountInfo{DisplayName: name, Labels: labels}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong account %v, want %v", got, want)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	s := &testService{
+		payErrs: []error{
+			wallet.NewErrHTTPStatusf(http.StatusBadRequest, wallet.ErrInsufficientFunds, "account alice has not enough money"),
+		},
+	}
+	srv := newTestServer(t, s)
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode int
+		wantText string
+		wantIs   error
+	}{
+		{
+			name: "payment rejected",
+			call: func() error {
+				_, err := c.PostPayment(context.Background(), "alice", "bob", 1, model.PaymentInfo{})
+				return err
+			},
+			wantCode: http.StatusBadRequest,
+			wantText: "account alice has not enough money",
+			wantIs:   wallet.ErrInsufficientFunds,
+		},
+		{
+			name: "account exists",
+			call: func() error {
+				_, err := c.PostAccount(context.Background(), "alice", 1, "USD", model.AccountInfo{})
+				return err
+			},
+			wantCode: http.StatusConflict,
+			wantText: "account alice already exists",
+		},
+		{
+			name: "wallet exists",
+			call: func() error {
+				_, err := c.PostWallet(context.Background(), "alice", "", []string{"USD"})
+				return err
+			},
+			wantCode: http.StatusConflict,
+			wantText: "wallet alice already exists",
+		},
+		{
+			name:     "not found",
+			call:     func() error { _, err := c.GetAllPayments(context.Background(), model.PaymentFilter{}); return err },
+			wantCode: http.StatusNotFound,
+			wantText: "no payment found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var httpErr wallet.HTTPError
+			if !xerrors.As(err, &httpErr) {
+				t.Fatalf("wrong error type %T, want wallet.HTTPError", err)
+			}
+			if httpErr.Code() != tt.wantCode {
+				t.Errorf("wrong code %v, want %v", httpErr.Code(), tt.wantCode)
+			}
+			if err.Error() != tt.wantText {
+				t.Errorf("wrong error %v, want %v", err.Error(), tt.wantText)
+			}
+			if tt.wantIs != nil && !xerrors.Is(err, tt.wantIs) {
+				t.Errorf("wrong wrapped error %v, want %v", xerrors.Unwrap(err), tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	conflict := wallet.NewErrHTTPStatusf(http.StatusConflict, model.ErrConflict, "account alice or bob was changed by a concurrent payment, please retry")
+	tests := []struct {
+		name      string
+		errs      []error
+		retries   int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"no retries", []error{conflict}, 0, true, 1},
+		{"conflict retried", []error{conflict, conflict}, 3, false, 3},
+		{"retries exceeded", []error{conflict, conflict, conflict}, 2, true, 3},
+		{"draining retried", []error{wallet.NewErrHTTPStatusf(http.StatusServiceUnavailable, wallet.ErrDraining, "service unavailable")}, 3, false, 2},
+		{"internal error not retried", []error{wallet.NewErrHTTPStatusf(http.StatusInternalServerError, nil, "unexpected error")}, 3, true, 1},
+		// the payment could have been processed before the proxy failed
+		{"bad gateway not retried", []error{wallet.NewErrHTTPStatusf(http.StatusBadGateway, nil, "bad gateway")}, 3, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &testService{payErrs: tt.errs}
+			srv := newTestServer(t, s)
+
+			c, err := New(srv.URL, WithRetries(tt.retries, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := c.PostPayment(context.Background(), "alice", "bob", 10.5, model.PaymentInfo{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if s.calls != tt.wantCalls {
+				t.Errorf("wrong number of calls %v, want %v", s.calls, tt.wantCalls)
+			}
+			if !tt.wantErr && p.Amount != currency.NewAmount(1050) {
+				t.Errorf("wrong amount %v, want %v", p.Amount, 1050)
+			}
+		})
+	}
+}
+
+func TestClientIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		opts      []Option
+		wantKey   string
+		wantGen   bool
+		wantErr   bool
+		wantCalls int
+	}{
+		// the payment could have been processed, so it isn't retried without a key
+		{"no key", context.Background(), nil, "", false, true, 1},
+		{"key from context", wallet.ContextWithIdempotencyKey(context.Background(), "key-1"), nil, "key-1", false, false, 2},
+		{"context key preferred", wallet.ContextWithIdempotencyKey(context.Background(), "key-1"), []Option{WithIdempotencyKeys()}, "key-1", false, false, 2},
+		{"generated key", context.Background(), []Option{WithIdempotencyKeys()}, "", true, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &testService{}
+			h := wallet.MakeHTTPHandler(s, log.NewNopLogger())
+			var (
+				mu   sync.Mutex
+				keys []string
+			)
+			// the first response is lost after the payment is processed
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				keys = append(keys, r.Header.Get(wallet.IdempotencyKeyHeader))
+				first := len(keys) == 1
+				mu.Unlock()
+				if first {
+					h.ServeHTTP(httptest.NewRecorder(), r)
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				h.ServeHTTP(w, r)
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL, append(tt.opts, WithRetries(3, time.Millisecond))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := c.PostPayment(tt.ctx, "alice", "bob", 10.5, model.PaymentInfo{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if len(keys) != tt.wantCalls {
+				t.Fatalf("wrong number of calls %v, want %v", len(keys), tt.wantCalls)
+			}
+			for _, got := range keys {
+				switch {
+				case tt.wantGen && (len(got) != 32 || got != keys[0]):
+					t.Errorf("wrong generated key %q, first %q", got, keys[0])
+				case !tt.wantGen && got != tt.wantKey:
+					t.Errorf("wrong key %q, want %q", got, tt.wantKey)
+				}
+			}
+			if s.calls != tt.wantCalls {
+				t.Errorf("wrong number of service calls %v, want %v", s.calls, tt.wantCalls)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.keyed) != 1 {
+				t.Errorf("wrong number of created payments %v, want %v", len(s.keyed), 1)
+			}
+			if p.Amount != currency.NewAmount(1050) {
+				t.Errorf("wrong amount %v, want %v", p.Amount, 1050)
+			}
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	srv := newTestServer(t, &testService{delay: 100 * time.Millisecond})
+
+	c, err := New(srv.URL, WithTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetAllAccounts(context.Background(), model.AccountFilter{})
+	if !xerrors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientInvalidAmount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"accounts":[{"id":"alice","balance":1e300,"currency":"USD"}]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetAllAccounts(context.Background(), model.AccountFilter{})
+	if !xerrors.Is(err, currency.ErrOverflow) {
+		t.Errorf("wrong error %v, want %v", err, currency.ErrOverflow)
+	}
+}
+
+func TestClientExportPayments(t *testing.T) {
+	payments := []model.Payment{
+		{ID: 1, AccFromID: "alice", AccToID: "bob", DateTime: time.Date(2019, 6, 23, 0, 37, 47, 0, time.UTC), Amount: currency.NewAmount(1230), Currency: currency.USD},
+		{ID: 2, AccFromID: "carol", AccToID: "dave", DateTime: time.Date(2019, 6, 24, 0, 0, 0, 0, time.UTC), Amount: currency.NewAmount(5), Currency: currency.BHD},
+		{ID: 3, AccFromID: "alice/USD", AccToID: "alice/EUR", DateTime: time.Date(2019, 6, 24, 0, 0, 0, 0, time.UTC), Amount: currency.NewAmount(1000), Currency: currency.USD, ToAmount: currency.NewAmount(920), ToCurrency: currency.EUR},
+	}
+	tests := []struct {
+		name    string
+		s       *testService
+		want    []model.Payment
+		wantErr bool
+	}{
+		{"payments", &testService{payments: payments}, payments, false},
+		{"empty", &testService{}, nil, false},
+		{"error", &testService{exportErr: wallet.NewErrHTTPStatusf(http.StatusInternalServerError, nil, "payment export failed")}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.s)
+			c, err := New(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []model.Payment
+			err = c.ExportPayments(context.Background(), nil, nil, func(p model.Payment) error {
+				got = append(got, p)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong payments %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientImportAccounts(t *testing.T) {
+	srv := newTestServer(t, &testService{})
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []model.AccountImport{
+		{Line: 2, ID: "alice", Currency: "USD", Balance: "1.50"},
+		{Line: 5, ID: "bob", Currency: "EUR", Balance: "abc"},
+	}
+	got, err := c.ImportAccounts(context.Background(), rows, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &model.ImportResult{DryRun: true, Errors: []model.ImportError{
+		{Line: 5, ID: "bob", Error: "unknown currency"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result %+v, want %+v", got, want)
+	}
+}
+
+// toInternal converts a test amount into the lowest currency units
+func toInternal(m float64, c currency.Currency) currency.Amount {
+	res, err := currency.ConvertToInternal(m, c, currency.RoundHalfEven)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
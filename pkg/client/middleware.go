@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	wallet "github.com/ilyakaznacheev/tiny-wallet"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+// idempotencyKey is an endpoint middleware that generates an idempotency key if enabled and not set yet.
+//
+// It should be placed before the retry middleware, so all retries use the same key
+func idempotencyKey(enabled bool) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		if !enabled {
+			return next
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if wallet.IdempotencyKeyFromContext(ctx) == "" {
+				ctx = wallet.ContextWithIdempotencyKey(ctx, newIdempotencyKey())
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// timeout is an endpoint middleware that limits the call duration
+func timeout(d time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
+
+// retry is an endpoint middleware that retries failed calls up to max times.
+//
+// idempotent tells if the call can be safely repeated after any transient error.
+// Other calls are repeated only if the service hasn't processed them
+func retry(max int, backoff time.Duration, idempotent func(context.Context) bool) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			for attempt := 1; ; attempt++ {
+				response, err = next(ctx, request)
+				if err == nil || attempt > max || ctx.Err() != nil {
+					return response, err
+				}
+				if !retryable(err, idempotent(ctx)) {
+					return response, err
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(backoff * time.Duration(attempt)):
+				}
+			}
+		}
+	}
+}
+
+// retryable checks if the failed call can be repeated.
+//
+// replayable means that the call can be repeated even if it could have been processed by the service
+func retryable(err error, replayable bool) bool {
+	var httpErr wallet.HTTPError
+	if xerrors.As(err, &httpErr) {
+		switch {
+		case xerrors.Is(err, model.ErrConflict):
+			// the payment was declined and can be repeated
+			return true
+		case httpErr.Code() == http.StatusServiceUnavailable:
+			return true
+		case httpErr.Code() == http.StatusBadGateway, httpErr.Code() == http.StatusGatewayTimeout:
+			return replayable
+		default:
+			return false
+		}
+	}
+
+	var netErr net.Error
+	if xerrors.As(err, &netErr) {
+		return replayable
+	}
+	return false
+}
+
+// always and never tell that the call is always or never idempotent
+func always(context.Context) bool { return true }
+func never(context.Context) bool  { return false }
+
+// hasIdempotencyKey tells that the call is idempotent if it has an idempotency key
+func hasIdempotencyKey(ctx context.Context) bool {
+	return wallet.IdempotencyKeyFromContext(ctx) != ""
+}
+
+// newIdempotencyKey generates a random idempotency key
+func newIdempotencyKey() string {
+	var key [16]byte
+	rand.Read(key[:])
+	return hex.EncodeToString(key[:])
+}
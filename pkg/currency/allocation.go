@@ -0,0 +1,74 @@
+package currency
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// Allocate splits an amount in the lowest unit of the currency by ratios without losing a single unit.
+//
+// Each part gets its share rounded toward zero, then leftover units are given one by one to parts with the largest rounded off remainders, ties go to the earlier part.
+// So parts always add up to the amount. E.g. 100 by 1:1:1 -> 34, 33, 33
+func Allocate(amount Amount, ratios []int) ([]Amount, error) {
+	if len(ratios) == 0 {
+		return nil, errors.New("no ratios to allocate by")
+	}
+	total := new(big.Int)
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, errors.New("negative allocation ratio")
+		}
+		total.Add(total, big.NewInt(int64(r)))
+	}
+	if total.Sign() == 0 {
+		return nil, errors.New("allocation ratios sum to zero")
+	}
+
+	// allocate the absolute amount, so leftover units are given the same way to both debits and credits
+	abs := new(big.Int).Abs(amount.Big())
+	left := new(big.Int).Set(abs)
+	parts := make([]*big.Int, len(ratios))
+	rems := make([]*big.Int, len(ratios))
+	for i, r := range ratios {
+		share := new(big.Int).Mul(abs, big.NewInt(int64(r)))
+		parts[i], rems[i] = share.QuoRem(share, total, new(big.Int))
+		left.Sub(left, parts[i])
+	}
+
+	order := make([]int, len(ratios))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rems[order[i]].Cmp(rems[order[j]]) > 0
+	})
+	// there are always less leftover units than parts
+	for i := int64(0); i < left.Int64(); i++ {
+		parts[order[i]].Add(parts[order[i]], big.NewInt(1))
+	}
+
+	// parts are never larger than the amount, so they are in the safe range
+	res := make([]Amount, len(parts))
+	for i, p := range parts {
+		if amount.Sign() < 0 {
+			p.Neg(p)
+		}
+		res[i] = bigAmount(p)
+	}
+	return res, nil
+}
+
+// Split splits an amount in the lowest unit of the currency into n even parts.
+//
+// Leftover units are given to the first parts. E.g. 100 into 3 -> 34, 33, 33
+func Split(amount Amount, n int) ([]Amount, error) {
+	if n <= 0 {
+		return nil, errors.New("amount should be split into at least one part")
+	}
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return Allocate(amount, ratios)
+}
@@ -0,0 +1,133 @@
+package currency
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		ratios  []int
+		want    []int64
+		wantErr bool
+	}{
+		{"even", 100, []int{1, 1, 1}, []int64{34, 33, 33}, false},
+		{"ratios", 1000, []int{70, 20, 10}, []int64{700, 200, 100}, false},
+		{"largest remainder", 100, []int{1, 2, 3}, []int64{17, 33, 50}, false},
+		{"remainder ties go first", 5, []int{1, 1, 1, 1}, []int64{2, 1, 1, 1}, false},
+		{"zero ratio", 10, []int{0, 1, 2}, []int64{0, 3, 7}, false},
+		{"negative", -100, []int{1, 1, 1}, []int64{-34, -33, -33}, false},
+		{"zero amount", 0, []int{1, 2}, []int64{0, 0}, false},
+		{"max int64", math.MaxInt64, []int{1, 1}, []int64{math.MaxInt64/2 + 1, math.MaxInt64 / 2}, false},
+		{"no ratios", 100, nil, nil, true},
+		{"negative ratio", 100, []int{1, -1}, nil, true},
+		{"zero ratios", 100, []int{0, 0}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(NewAmount(tt.amount), tt.ratios)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, amounts(tt.want...)) {
+				t.Errorf("wrong parts %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		n       int
+		want    []int64
+		wantErr bool
+	}{
+		{"one part", 100, 1, []int64{100}, false},
+		{"even", 100, 4, []int64{25, 25, 25, 25}, false},
+		{"leftover", 1000, 3, []int64{334, 333, 333}, false},
+		{"more parts than units", 2, 3, []int64{1, 1, 0}, false},
+		{"no parts", 100, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(NewAmount(tt.amount), tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, amounts(tt.want...)) {
+				t.Errorf("wrong parts %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAllocateProperties checks that parts of any amount of each currency add up to the amount
+// and each part differs from its exact share by less than one unit
+func TestAllocateProperties(t *testing.T) {
+	for c := range currencyProperties {
+		c := c
+		t.Run(string(c), func(t *testing.T) {
+			unit := int(math.Pow10(c.Decimals()))
+			allocate := func(units int32, minor uint16, ratios []uint8) bool {
+				amount := int(units)*unit + int(minor)%unit
+				rs := make([]int, len(ratios))
+				total := 0
+				for i, r := range ratios {
+					rs[i] = int(r)
+					total += int(r)
+				}
+
+				parts, err := Allocate(NewAmount(int64(amount)), rs)
+				if total == 0 {
+					return err != nil
+				}
+				if err != nil || len(parts) != len(rs) {
+					return false
+				}
+				sum := 0
+				for i, part := range parts {
+					p := int(mustInt64(t, part))
+					sum += p
+					// |part - amount * ratio / total| < 1
+					if diff := p*total - amount*rs[i]; diff <= -total || diff >= total {
+						return false
+					}
+				}
+				return sum == amount
+			}
+			if err := quick.Check(allocate, nil); err != nil {
+				t.Error(err)
+			}
+
+			split := func(units int32, minor uint16, n uint8) bool {
+				amount := int(units)*unit + int(minor)%unit
+				parts, err := Split(NewAmount(int64(amount)), int(n))
+				if n == 0 {
+					return err != nil
+				}
+				if err != nil || len(parts) != int(n) {
+					return false
+				}
+				sum := 0
+				for _, part := range parts {
+					p := int(mustInt64(t, part))
+					sum += p
+					// even parts differ from each other by one unit at most
+					if d := abs(p) - abs(int(mustInt64(t, parts[0]))); d > 0 || d < -1 {
+						return false
+					}
+				}
+				return sum == amount
+			}
+			if err := quick.Check(split, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package currency
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Amount is an integer amount in the lowest unit of the currency.
+//
+// It is a 128-bit integer, so it holds amounts of currencies with up to 18 decimal places, e.g. 10^20 units of a currency with 18 decimals.
+// The zero value is zero. Amounts can be compared with ==, use Cmp to order them
+type Amount struct {
+	hi int64
+	lo uint64
+}
+
+// NewAmount returns the integer as an amount
+func NewAmount(v int64) Amount {
+	a := Amount{lo: uint64(v)}
+	if v < 0 {
+		a.hi = -1
+	}
+	return a
+}
+
+// AmountFromBig converts a big integer into an amount.
+//
+// It returns ErrOverflow if the integer is out of the safe range
+func AmountFromBig(v *big.Int) (Amount, error) {
+	if v.CmpAbs(maxSafeAmount) > 0 {
+		return Amount{}, ErrOverflow
+	}
+	return bigAmount(v), nil
+}
+
+// bigAmount converts a big integer that fits into 128 bits into an amount
+func bigAmount(v *big.Int) Amount {
+	abs := new(big.Int).Abs(v)
+	a := Amount{
+		hi: int64(new(big.Int).Rsh(abs, 64).Uint64()),
+		lo: abs.Uint64(),
+	}
+	if v.Sign() < 0 {
+		return a.Neg()
+	}
+	return a
+}
+
+// ParseAmount converts a decimal integer string into an amount in the lowest unit of the currency.
+//
+// E.g. "-1230" -> -1230. It returns ErrOverflow if the amount is out of the safe range
+func ParseAmount(s string) (Amount, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return AmountFromBig(v)
+}
+
+// Big returns the amount as a big integer
+func (a Amount) Big() *big.Int {
+	if a.hi < 0 {
+		v := a.Neg().Big()
+		return v.Neg(v)
+	}
+	v := new(big.Int).SetUint64(uint64(a.hi))
+	v.Lsh(v, 64)
+	return v.Or(v, new(big.Int).SetUint64(a.lo))
+}
+
+// Int64 returns the amount as int64 and true if it fits into int64
+func (a Amount) Int64() (int64, bool) {
+	v := int64(a.lo)
+	return v, a.hi == v>>63
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount
+func (a Amount) Sign() int {
+	switch {
+	case a.hi < 0:
+		return -1
+	case a.hi == 0 && a.lo == 0:
+		return 0
+	}
+	return 1
+}
+
+// Cmp compares the amounts and returns -1 if a < b, 0 if a == b and +1 if a > b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.hi < b.hi || a.hi == b.hi && a.lo < b.lo:
+		return -1
+	case a == b:
+		return 0
+	}
+	return 1
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	// two's complement, the safe range is symmetric, so the negation never overflows
+	lo := ^a.lo + 1
+	hi := ^a.hi
+	if lo == 0 {
+		hi++
+	}
+	return Amount{hi: hi, lo: lo}
+}
+
+// String returns the amount as a decimal integer, e.g. "-1230"
+func (a Amount) String() string {
+	if v, ok := a.Int64(); ok {
+		return strconv.FormatInt(v, 10)
+	}
+	return a.Big().String()
+}
+
+// Value stores the amount in a numeric database column
+func (a Amount) Value() (driver.Value, error) {
+	if v, ok := a.Int64(); ok {
+		return v, nil
+	}
+	return a.String(), nil
+}
+
+// Scan reads the amount from an integer or a numeric database column
+func (a *Amount) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case int64:
+		*a = NewAmount(v)
+	case []byte:
+		*a, err = ParseAmount(string(v))
+	case string:
+		*a, err = ParseAmount(v)
+	default:
+		return fmt.Errorf("can't scan %T into an amount", src)
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package currency
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// amounts converts integers into amounts, nil stays nil
+func amounts(vs ...int64) []Amount {
+	if vs == nil {
+		return nil
+	}
+	res := make([]Amount, len(vs))
+	for i, v := range vs {
+		res[i] = NewAmount(v)
+	}
+	return res
+}
+
+// mustInt64 returns the amount as int64 or fails the test
+func mustInt64(t *testing.T, a Amount) int64 {
+	t.Helper()
+	v, ok := a.Int64()
+	if !ok {
+		t.Fatalf("amount %v doesn't fit into int64", a)
+	}
+	return v
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"zero", "0"},
+		{"positive", "1230"},
+		{"negative", "-1230"},
+		{"max int64", "9223372036854775807"},
+		{"min int64", "-9223372036854775808"},
+		{"above int64", "9223372036854775808"},
+		{"ether", "12500000000000000000"},
+		{"negative ether", "-12500000000000000000"},
+		{"two words", "18446744073709551616"},
+		{"max", "99999999999999999999999999999999999999"},
+		{"min", "-99999999999999999999999999999999999999"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAmount(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.String(); got != tt.s {
+				t.Errorf("wrong string %v, want %v", got, tt.s)
+			}
+			want, _ := new(big.Int).SetString(tt.s, 10)
+			if got := a.Big(); got.Cmp(want) != 0 {
+				t.Errorf("wrong big integer %v, want %v", got, want)
+			}
+			if got := a.Sign(); got != want.Sign() {
+				t.Errorf("wrong sign %v, want %v", got, want.Sign())
+			}
+			if got := a.Neg().Neg(); got != a {
+				t.Errorf("wrong double negation %v, want %v", got, a)
+			}
+			if v, ok := a.Int64(); ok != want.IsInt64() || ok && v != want.Int64() {
+				t.Errorf("wrong int64 %v, %v, want %v, %v", v, ok, want.Int64(), want.IsInt64())
+			}
+
+			var scanned Amount
+			if err := scanned.Scan([]byte(tt.s)); err != nil || scanned != a {
+				t.Errorf("wrong scanned amount %v (%v), want %v", scanned, err, a)
+			}
+			v, err := a.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := scanned.Scan(v); err != nil || scanned != a {
+				t.Errorf("wrong amount %v (%v) scanned from value %v, want %v", scanned, err, v, a)
+			}
+		})
+	}
+}
+
+func TestParseAmountInvalid(t *testing.T) {
+	for _, s := range []string{"", "1.5", "abc", "100000000000000000000000000000000000000", "-100000000000000000000000000000000000000"} {
+		if a, err := ParseAmount(s); err == nil {
+			t.Errorf("invalid amount %q is parsed as %v", s, a)
+		}
+	}
+}
+
+func TestAmountCmp(t *testing.T) {
+	ordered := []Amount{
+		MaxSafeAmount.Neg(),
+		NewAmount(math.MinInt64),
+		NewAmount(-1),
+		{},
+		NewAmount(1),
+		NewAmount(math.MaxInt64),
+		MaxSafeAmount,
+	}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got := a.Cmp(b); got != want {
+				t.Errorf("wrong comparison of %v and %v: %v, want %v", a, b, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package currency
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrOverflow is returned when an amount is out of the safe range
+var ErrOverflow = errors.New("amount is out of the safe range")
+
+// maxSafeAmount is a maximum absolute amount in the lowest unit of the currency, 10^38 - 1.
+//
+// Amounts are stored in numeric(38,0) database columns, and the range fits into 128 bits of Amount
+var maxSafeAmount = new(big.Int).Sub(new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil), big.NewInt(1))
+
+// MaxSafeAmount is a maximum absolute amount in the lowest unit of the currency, 10^38 - 1
+var MaxSafeAmount = bigAmount(maxSafeAmount)
+
+// Add returns a + b, or ErrOverflow if the sum is out of the safe range
+func (a Amount) Add(b Amount) (Amount, error) {
+	return AmountFromBig(new(big.Int).Add(a.Big(), b.Big()))
+}
+
+// Sub returns a - b, or ErrOverflow if the difference is out of the safe range
+func (a Amount) Sub(b Amount) (Amount, error) {
+	return AmountFromBig(new(big.Int).Sub(a.Big(), b.Big()))
+}
+
+// Mul returns a * n, or ErrOverflow if the product is out of the safe range
+func (a Amount) Mul(n int64) (Amount, error) {
+	return AmountFromBig(new(big.Int).Mul(a.Big(), big.NewInt(n)))
+}
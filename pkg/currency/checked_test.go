@@ -0,0 +1,65 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCheckedArithmetic(t *testing.T) {
+	add := func(a, b Amount) (Amount, error) { return a.Add(b) }
+	sub := func(a, b Amount) (Amount, error) { return a.Sub(b) }
+	mul := func(a, b Amount) (Amount, error) {
+		n, _ := b.Int64()
+		return a.Mul(n)
+	}
+	belowMax, err := MaxSafeAmount.Sub(NewAmount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fn      func(a, b Amount) (Amount, error)
+		a, b    Amount
+		want    Amount
+		wantErr bool
+	}{
+		{"add", add, NewAmount(2), NewAmount(3), NewAmount(5), false},
+		{"add negative", add, NewAmount(-2), NewAmount(-3), NewAmount(-5), false},
+		{"add above int64", add, NewAmount(math.MaxInt64), NewAmount(1), mustParseAmount(t, "9223372036854775808"), false},
+		{"add max", add, belowMax, NewAmount(1), MaxSafeAmount, false},
+		{"add overflow", add, MaxSafeAmount, NewAmount(1), Amount{}, true},
+		{"sub", sub, NewAmount(2), NewAmount(3), NewAmount(-1), false},
+		{"sub below int64", sub, NewAmount(math.MinInt64), NewAmount(1), mustParseAmount(t, "-9223372036854775809"), false},
+		{"sub min", sub, belowMax.Neg(), NewAmount(1), MaxSafeAmount.Neg(), false},
+		{"sub overflow", sub, MaxSafeAmount.Neg(), NewAmount(1), Amount{}, true},
+		{"mul", mul, NewAmount(-4), NewAmount(5), NewAmount(-20), false},
+		{"mul above int64", mul, NewAmount(math.MaxInt64), NewAmount(2), mustParseAmount(t, "18446744073709551614"), false},
+		{"mul overflow", mul, MaxSafeAmount, NewAmount(2), Amount{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil && !errors.Is(err, ErrOverflow) {
+				t.Errorf("wrong error %v, want %v", err, ErrOverflow)
+			}
+			if got != tt.want {
+				t.Errorf("wrong result %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mustParseAmount parses a decimal integer amount or fails the test
+func mustParseAmount(t *testing.T, s string) Amount {
+	t.Helper()
+	a, err := ParseAmount(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
@@ -3,28 +3,59 @@ package currency
 import (
 	"fmt"
 	"math"
+	"math/big"
 )
 
 // ConvertToInternal converts external floating point currency amount to internal integer in the lowest unit of the currency
-// E.g. USD (2): 15.25 -> 1525
-func ConvertToInternal(m float64, c Currency) int {
-	return int(m * math.Pow10(c.Decimals()))
+//
+// The amount is rounded to the lowest unit with the rounding mode. E.g. USD (2): 15.25 -> 1525, 15.255 -> 1526 half up.
+// It returns ErrOverflow if the amount is out of the safe range or isn't a finite number
+func ConvertToInternal(m float64, c Currency, mode RoundingMode) (Amount, error) {
+	if math.IsInf(m, 0) || math.IsNaN(m) {
+		return Amount{}, ErrOverflow
+	}
+	return AmountFromBig(ConvertToInternalBig(Rat(m), c, mode))
+}
+
+// ConvertToInternalBig converts an exact decimal amount to a big integer in the lowest unit of the currency.
+//
+// Unlike ConvertToInternal, the result isn't limited to the safe range. E.g. 18 decimals: 12.5 -> 12500000000000000000
+func ConvertToInternalBig(m *big.Rat, c Currency, mode RoundingMode) *big.Int {
+	return mode.RoundRatBig(new(big.Rat).Mul(m, pow10Rat(c.Decimals())))
 }
 
 // ConvertToExternal converts internal integer amount in the lowest unit of the currency  to external floating point format
 //
-// E.g. USD (2): 1525 -> 15.25
-func ConvertToExternal(m int, c Currency) float64 {
-	return float64(m) / math.Pow10(c.Decimals())
+// E.g. USD (2): 1525 -> 15.25. The result is the nearest float to the exact amount
+func ConvertToExternal(m Amount, c Currency) float64 {
+	f, _ := ConvertToExternalBig(m.Big(), c).Float64()
+	return f
 }
 
-// AtoCurrency converts string to ISO 4216 currency.
+// ConvertToExternalBig converts a big integer amount in the lowest unit of the currency to an exact decimal
+func ConvertToExternalBig(m *big.Int, c Currency) *big.Rat {
+	return new(big.Rat).Mul(new(big.Rat).SetInt(m), pow10Rat(-c.Decimals()))
+}
+
+// AtoCurrency converts string to ISO 4217 or registered custom currency.
 //
-// If there is no such currency code, the method will return an error
+// If there is no such currency code or the currency is withdrawn, the method will return an error
 func AtoCurrency(a string) (*Currency, error) {
+	c, err := AtoAnyCurrency(a)
+	if err != nil {
+		return nil, err
+	}
+	if w, ok := c.Withdrawn(); ok {
+		return nil, fmt.Errorf("currency %s is withdrawn since %s", a, w.Format("2006-01"))
+	}
+	return c, nil
+}
+
+// AtoAnyCurrency converts string to ISO 4217 or registered custom currency, including withdrawn ones
+func AtoAnyCurrency(a string) (*Currency, error) {
 	c := Currency(a)
-	if _, ok := currencyProperties[c]; ok {
+	if _, ok := lookup(c); ok {
 		return &c, nil
 	}
-	return nil, fmt.Errorf("non-ISO 4216 currency (%s)", a)
+	return nil, fmt.Errorf("unknown currency (%s)", a)
 }
@@ -1,25 +1,49 @@
 package currency
 
 import (
+	"math"
 	"testing"
 )
 
 func TestConvertToInternal(t *testing.T) {
 	tests := []struct {
-		name string
-		c    Currency
-		m    float64
-		want int
+		name    string
+		c       Currency
+		m       float64
+		mode    RoundingMode
+		want    int64
+		wantErr bool
 	}{
-		{"USD", USD, 123.45, 12345},
-		{"IQD", IQD, 12.345, 12345},
-		{"UYW", UYW, 1.2345, 12345},
-		{"CLP", CLP, 12345.0, 12345},
-		{"AAA", "AAA", 12345.0, 12345},
+		{"USD", USD, 123.45, RoundHalfEven, 12345, false},
+		{"IQD", IQD, 12.345, RoundHalfEven, 12345, false},
+		{"UYW", UYW, 1.2345, RoundHalfEven, 12345, false},
+		{"CLP", CLP, 12345.0, RoundHalfEven, 12345, false},
+		{"AAA", "AAA", 12345.0, RoundHalfEven, 12345, false},
+		{"float error", USD, 0.29, RoundDown, 29, false},
+		{"float error ceiling", USD, 1.1, RoundCeiling, 110, false},
+		{"negative", USD, -0.29, RoundDown, -29, false},
+		{"half even", USD, 10.005, RoundHalfEven, 1000, false},
+		{"half up", USD, 10.005, RoundHalfUp, 1001, false},
+		{"down", USD, 10.009, RoundDown, 1000, false},
+		{"up", USD, 10.001, RoundUp, 1001, false},
+		{"negative floor", USD, -10.001, RoundFloor, -1001, false},
+		{"negative ceiling", USD, -10.009, RoundCeiling, -1000, false},
+		{"large dong", VND, 9e18, RoundHalfEven, 9000000000000000000, false},
+		{"too large dong", VND, 1e38, RoundHalfEven, 0, true},
+		{"too large dollars", USD, 1e36, RoundHalfEven, 0, true},
+		{"too small rupiah", IDR, -1e38, RoundHalfEven, 0, true},
+		{"huge", USD, 1e300, RoundHalfEven, 0, true},
+		{"infinity", USD, math.Inf(1), RoundHalfEven, 0, true},
+		{"negative infinity", USD, math.Inf(-1), RoundHalfEven, 0, true},
+		{"NaN", USD, math.NaN(), RoundHalfEven, 0, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ConvertToInternal(tt.m, tt.c); got != tt.want {
+			got, err := ConvertToInternal(tt.m, tt.c, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != NewAmount(tt.want) {
 				t.Errorf("wrong result %v, want %v", got, tt.want)
 			}
 		})
@@ -30,7 +54,7 @@ func TestConvertToExternal(t *testing.T) {
 	tests := []struct {
 		name string
 		c    Currency
-		m    int
+		m    int64
 		want float64
 	}{
 		{"USD", USD, 12345, 123.45},
@@ -41,7 +65,7 @@ func TestConvertToExternal(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ConvertToExternal(tt.m, tt.c); got != tt.want {
+			if got := ConvertToExternal(NewAmount(tt.m), tt.c); got != tt.want {
 				t.Errorf("wrong result %v, want %v", got, tt.want)
 			}
 		})
@@ -59,6 +83,7 @@ func TestAtoCurrency(t *testing.T) {
 		{"IQD", "IQD", IQD, false},
 		{"UYW", "UYW", UYW, false},
 		{"AAA", "AAA", "", true},
+		{"withdrawn", "DEM", "", true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -73,3 +98,28 @@ func TestAtoCurrency(t *testing.T) {
 		})
 	}
 }
+
+func TestAtoAnyCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		want    Currency
+		wantErr bool
+	}{
+		{"active", "EUR", EUR, false},
+		{"withdrawn", "DEM", DEM, false},
+		{"unknown", "AAA", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AtoAnyCurrency(tt.a)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error state = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("wrong value %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Rate is an exchange rate of a currency pair set at a point of time.
+//
+// One unit of the base currency costs Rate units of the quote currency, e.g. EUR/USD 1.1 means that 1 EUR costs 1.1 USD
+type Rate struct {
+	Base  Currency
+	Quote Currency
+	Rate  float64
+	Time  time.Time
+}
+
+// Validate checks that the rate is a positive number between two different known currencies
+func (r Rate) Validate() error {
+	if _, ok := lookup(r.Base); !ok {
+		return fmt.Errorf("unknown base currency %q", string(r.Base))
+	}
+	if _, ok := lookup(r.Quote); !ok {
+		return fmt.Errorf("unknown quote currency %q", string(r.Quote))
+	}
+	if r.Base == r.Quote {
+		return fmt.Errorf("base and quote currencies are the same (%s)", string(r.Base))
+	}
+	if r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) {
+		return fmt.Errorf("invalid %s/%s exchange rate %v", string(r.Base), string(r.Quote), r.Rate)
+	}
+	return nil
+}
+
+// RateSource is a history of exchange rates
+type RateSource interface {
+	// LatestRate returns the last base/quote rate set at or before the time.
+	// It returns ErrNoRate if there is no such rate
+	LatestRate(ctx context.Context, base, quote Currency, at time.Time) (*Rate, error)
+}
+
+// RateHistory is an in-memory history of exchange rates
+type RateHistory struct {
+	mu    sync.RWMutex
+	pairs map[[2]Currency][]Rate
+}
+
+// NewRateHistory creates an empty in-memory rate history
+func NewRateHistory() *RateHistory {
+	return &RateHistory{pairs: map[[2]Currency][]Rate{}}
+}
+
+// Add adds rates to the history, a rate of the pair with the same time is replaced
+func (h *RateHistory) Add(rates ...Rate) error {
+	for _, r := range rates {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range rates {
+		key := [2]Currency{r.Base, r.Quote}
+		list := h.pairs[key]
+		i := sort.Search(len(list), func(i int) bool { return !list[i].Time.Before(r.Time) })
+		if i < len(list) && list[i].Time.Equal(r.Time) {
+			list[i] = r
+			continue
+		}
+		list = append(list, Rate{})
+		copy(list[i+1:], list[i:])
+		list[i] = r
+		h.pairs[key] = list
+	}
+	return nil
+}
+
+// LatestRate returns the last base/quote rate set at or before the time
+func (h *RateHistory) LatestRate(_ context.Context, base, quote Currency, at time.Time) (*Rate, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	list := h.pairs[[2]Currency{base, quote}]
+	// the first rate set after the time, the previous one is the latest before it
+	i := sort.Search(len(list), func(i int) bool { return list[i].Time.After(at) })
+	if i == 0 {
+		return nil, fmt.Errorf("%w from %s to %s at %s", ErrNoRate, string(base), string(quote), at.Format(time.RFC3339))
+	}
+	r := list[i-1]
+	return &r, nil
+}
+
+// Converter converts money with historical exchange rates.
+//
+// A rate between two currencies is found as a direct rate, an inverse one, or a cross rate through the pivot currency
+type Converter struct {
+	source RateSource
+	pivot  Currency
+}
+
+// NewConverter creates a converter with a rate history.
+//
+// The pivot currency is used to triangulate currencies that have no rate between each other, it can be empty
+func NewConverter(source RateSource, pivot Currency) *Converter {
+	return &Converter{
+		source: source,
+		pivot:  pivot,
+	}
+}
+
+// Rate returns an exchange rate from one currency to another at the time.
+//
+// The rate time is the time of the oldest rate it is derived from. If there is no way to get the rate, the method will return `ErrNoRate` error
+func (c *Converter) Rate(ctx context.Context, from, to Currency, at time.Time) (*Rate, error) {
+	x, t, err := c.rate(ctx, from, to, at)
+	if err != nil {
+		return nil, err
+	}
+	f, _ := x.Float64()
+	return &Rate{Base: from, Quote: to, Rate: f, Time: t}, nil
+}
+
+// Convert converts an amount in the lowest unit of from currency into the lowest unit of to currency with the rate at the time.
+//
+// The result is rounded to the lowest unit with the rounding mode. It returns ErrOverflow if the result is out of the safe range
+func (c *Converter) Convert(ctx context.Context, amount Amount, from, to Currency, at time.Time, mode RoundingMode) (Amount, error) {
+	x, _, err := c.rate(ctx, from, to, at)
+	if err != nil {
+		return Amount{}, err
+	}
+	if from == to {
+		return amount, nil
+	}
+	x.Mul(x, new(big.Rat).SetInt(amount.Big()))
+	x.Mul(x, pow10Rat(to.Decimals()-from.Decimals()))
+	return AmountFromBig(mode.RoundRatBig(x))
+}
+
+// rate returns an exact rate from one currency to another and the time of the oldest rate it is derived from
+func (c *Converter) rate(ctx context.Context, from, to Currency, at time.Time) (*big.Rat, time.Time, error) {
+	if from == to {
+		return big.NewRat(1, 1), at, nil
+	}
+	x, t, err := c.pair(ctx, from, to, at)
+	if !errors.Is(err, ErrNoRate) || c.pivot == "" || from == c.pivot || to == c.pivot {
+		return x, t, err
+	}
+
+	// cross rate: from -> pivot -> to
+	x1, t1, err := c.pair(ctx, from, c.pivot, at)
+	if err != nil {
+		return nil, time.Time{}, noRate(err, from, to)
+	}
+	x2, t2, err := c.pair(ctx, c.pivot, to, at)
+	if err != nil {
+		return nil, time.Time{}, noRate(err, from, to)
+	}
+	if t2.Before(t1) {
+		t1 = t2
+	}
+	return x1.Mul(x1, x2), t1, nil
+}
+
+// pair returns a direct or an inverse rate of the currency pair, whichever is set later
+func (c *Converter) pair(ctx context.Context, from, to Currency, at time.Time) (*big.Rat, time.Time, error) {
+	direct, err := c.source.LatestRate(ctx, from, to, at)
+	if err != nil && !errors.Is(err, ErrNoRate) {
+		return nil, time.Time{}, err
+	}
+	inverse, err := c.source.LatestRate(ctx, to, from, at)
+	if err != nil && !errors.Is(err, ErrNoRate) {
+		return nil, time.Time{}, err
+	}
+
+	switch {
+	case direct != nil && (inverse == nil || !inverse.Time.After(direct.Time)):
+		return Rat(direct.Rate), direct.Time, nil
+	case inverse != nil:
+		return new(big.Rat).Inv(Rat(inverse.Rate)), inverse.Time, nil
+	}
+	return nil, time.Time{}, fmt.Errorf("%w from %s to %s at %s", ErrNoRate, string(from), string(to), at.Format(time.RFC3339))
+}
+
+// noRate reports a missing cross rate as a missing rate between the requested currencies
+func noRate(err error, from, to Currency) error {
+	if errors.Is(err, ErrNoRate) {
+		return fmt.Errorf("%w from %s to %s", ErrNoRate, string(from), string(to))
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2020, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testHistory(t *testing.T) *RateHistory {
+	t.Helper()
+	h := NewRateHistory()
+	err := h.Add(
+		Rate{EUR, USD, 1.1, day(1)},
+		Rate{EUR, USD, 1.2, day(10)},
+		Rate{USD, JPY, 110, day(5)},
+		Rate{GBP, EUR, 1.25, day(3)},
+		Rate{CHF, GBP, 0.8, day(2)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestRateHistoryLatestRate(t *testing.T) {
+	h := testHistory(t)
+	tests := []struct {
+		name    string
+		base    Currency
+		quote   Currency
+		at      time.Time
+		want    float64
+		wantErr error
+	}{
+		{"exact time", EUR, USD, day(1), 1.1, nil},
+		{"between", EUR, USD, day(9), 1.1, nil},
+		{"later", EUR, USD, day(20), 1.2, nil},
+		{"before first", EUR, USD, day(1).Add(-time.Second), 0, ErrNoRate},
+		{"inverse isn't derived", USD, EUR, day(20), 0, ErrNoRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.LatestRate(context.Background(), tt.base, tt.quote, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Rate != tt.want {
+				t.Errorf("wrong rate %v, want %v", got.Rate, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateHistoryAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    Rate
+		wantErr bool
+	}{
+		{"valid", Rate{EUR, USD, 1.1, day(1)}, false},
+		{"unknown base", Rate{"AAA", USD, 1.1, day(1)}, true},
+		{"unknown quote", Rate{EUR, "AAA", 1.1, day(1)}, true},
+		{"same currency", Rate{EUR, EUR, 1, day(1)}, true},
+		{"zero rate", Rate{EUR, USD, 0, day(1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewRateHistory().Add(tt.rate); (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+
+	// a rate with the same time replaces the old one
+	h := testHistory(t)
+	if err := h.Add(Rate{EUR, USD, 1.15, day(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := h.LatestRate(context.Background(), EUR, USD, day(2)); got.Rate != 1.15 {
+		t.Errorf("wrong rate %v, want %v", got.Rate, 1.15)
+	}
+}
+
+func TestConverterRate(t *testing.T) {
+	c := NewConverter(testHistory(t), USD)
+	tests := []struct {
+		name     string
+		from     Currency
+		to       Currency
+		at       time.Time
+		want     float64
+		wantTime time.Time
+		wantErr  error
+	}{
+		{"same currency", EUR, EUR, day(7), 1, day(7), nil},
+		{"direct", EUR, USD, day(12), 1.2, day(10), nil},
+		{"inverse", USD, EUR, day(2), 1 / 1.1, day(1), nil},
+		{"cross through pivot", EUR, JPY, day(6), 121, day(1), nil},
+		{"inverse cross", JPY, EUR, day(6), 1 / 121.0, day(1), nil},
+		{"no pivot path", CHF, EUR, day(6), 0, time.Time{}, ErrNoRate},
+		{"too early", EUR, JPY, day(4), 0, time.Time{}, ErrNoRate},
+		{"unknown", EUR, KWD, day(20), 0, time.Time{}, ErrNoRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Rate(context.Background(), tt.from, tt.to, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Rate != tt.want {
+				t.Errorf("wrong rate %v, want %v", got.Rate, tt.want)
+			}
+			if !got.Time.Equal(tt.wantTime) {
+				t.Errorf("wrong time %v, want %v", got.Time, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestConverterConvert(t *testing.T) {
+	c := NewConverter(testHistory(t), USD)
+	tests := []struct {
+		name    string
+		amount  Amount
+		from    Currency
+		to      Currency
+		at      time.Time
+		mode    RoundingMode
+		want    Amount
+		wantErr error
+	}{
+		{"same currency", NewAmount(12345), EUR, EUR, day(1), RoundHalfEven, NewAmount(12345), nil},
+		{"direct", NewAmount(1000), EUR, USD, day(1), RoundHalfEven, NewAmount(1100), nil},
+		{"later rate", NewAmount(1000), EUR, USD, day(10), RoundHalfEven, NewAmount(1200), nil},
+		{"inverse", NewAmount(1100), USD, EUR, day(1), RoundHalfEven, NewAmount(1000), nil},
+		{"less decimals", NewAmount(100), EUR, JPY, day(6), RoundHalfEven, NewAmount(121), nil},
+		{"inverse down", NewAmount(100), JPY, EUR, day(6), RoundDown, NewAmount(82), nil},
+		{"no rate", NewAmount(100), EUR, KWD, day(6), RoundHalfEven, Amount{}, ErrNoRate},
+		{"overflow", MaxSafeAmount, USD, JPY, day(6), RoundHalfEven, Amount{}, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Convert(context.Background(), tt.amount, tt.from, tt.to, tt.at, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("wrong result %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -13,18 +13,24 @@ func ExampleConvertToInternal() {
 
 	// convert external (normal) money representations
 	// into internal (integer) format
-	fmt.Println(currency.ConvertToInternal(amountExtUSD, currency.USD))
-	fmt.Println(currency.ConvertToInternal(amountExtIQD, currency.IQD))
-	fmt.Println(currency.ConvertToInternal(amountExtISK, currency.ISK))
-	// Output: 123450
-	// 145345
-	// 25
+	fmt.Println(currency.ConvertToInternal(amountExtUSD, currency.USD, currency.RoundHalfEven))
+	fmt.Println(currency.ConvertToInternal(amountExtIQD, currency.IQD, currency.RoundHalfEven))
+	fmt.Println(currency.ConvertToInternal(amountExtISK, currency.ISK, currency.RoundHalfEven))
+	// the amount is rounded to the lowest currency unit
+	fmt.Println(currency.ConvertToInternal(10.005, currency.USD, currency.RoundHalfUp))
+	// amounts out of the safe range are rejected
+	fmt.Println(currency.ConvertToInternal(1e300, currency.USD, currency.RoundHalfEven))
+	// Output: 123450 <nil>
+	// 145345 <nil>
+	// 25 <nil>
+	// 1001 <nil>
+	// 0 amount is out of the safe range
 }
 
 func ExampleConvertToExternal() {
-	amountIntUSD := 123450
-	amountIntIQD := 145345
-	amountIntISK := 25
+	amountIntUSD := currency.NewAmount(123450)
+	amountIntIQD := currency.NewAmount(145345)
+	amountIntISK := currency.NewAmount(25)
 
 	// convert external (normal) money representations
 	// into internal (integer) format
@@ -36,6 +42,18 @@ func ExampleConvertToExternal() {
 	// 25
 }
 
+func ExampleAllocate() {
+	// split 100.00 USD between a seller and a platform commission of 7.5%
+	parts, _ := currency.Allocate(currency.NewAmount(10000), []int{925, 75})
+	fmt.Println(parts)
+
+	// divide 100.00 USD among 3 parties, the leftover cent goes to the first one
+	parts, _ = currency.Split(currency.NewAmount(10000), 3)
+	fmt.Println(parts)
+	// Output: [9250 750]
+	// [3334 3333 3333]
+}
+
 func ExampleAtoCurrency() {
 	strUSD := "USD"
 	strCLP := "CLP"
@@ -77,3 +95,20 @@ func Example() {
 	// Chilean Peso
 	// Iceland Krona
 }
+
+func ExampleCurrency_Format() {
+	de, _ := currency.LookupLocale("de-DE")
+
+	// format amounts in the lowest currency unit by locale rules
+	fmt.Println(currency.USD.Format(currency.NewAmount(123456), nil))
+	// de-DE separates the symbol with a no-break space
+	fmt.Println(currency.EUR.Format(currency.NewAmount(123456), de))
+	fmt.Println(currency.JPY.Format(currency.NewAmount(1235), nil))
+
+	// and parse them back
+	fmt.Println(currency.EUR.Parse("1.234,56 €", de))
+	// Output: $1,234.56
+	// 1.234,56 €
+	// ¥1,235
+	// 123456 <nil>
+}
@@ -3,7 +3,9 @@ package currency
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCurrencyString(t *testing.T) {
@@ -16,7 +18,7 @@ func TestCurrencyString(t *testing.T) {
 		{"BWP", BWP, "Pula"},
 		{"NOK", NOK, "Norwegian Krone"},
 		{"UYI", UYI, "Uruguay Peso en Unidades Indexadas (UI)"},
-		{"000", "000", "non-ISO 4216 currency (000)"},
+		{"000", "000", "unknown currency (000)"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -28,7 +30,7 @@ func TestCurrencyString(t *testing.T) {
 }
 
 func TestCurrencyFormatAmount(t *testing.T) {
-	amount := 123456789
+	amount := NewAmount(123456789)
 	tests := []struct {
 		name string
 		c    Currency
@@ -49,3 +51,146 @@ func TestCurrencyFormatAmount(t *testing.T) {
 		})
 	}
 }
+
+func TestCurrencyFormatDecimal(t *testing.T) {
+	tests := []struct {
+		name   string
+		c      Currency
+		amount int64
+		want   string
+	}{
+		{"USD", USD, 123450, "1234.50"},
+		{"USD cents", USD, 5, "0.05"},
+		{"USD negative", USD, -1230, "-12.30"},
+		{"BHD", BHD, 1234, "1.234"},
+		{"CLP", CLP, 123456789, "123456789"},
+		{"UYW", UYW, 12, "0.0012"},
+		{"zero", USD, 0, "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.FormatDecimal(NewAmount(tt.amount)); got != tt.want {
+				t.Errorf("wrong formatting %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrencyParseDecimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Currency
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"USD", USD, "1234.50", 123450, false},
+		{"USD short", USD, "12.3", 1230, false},
+		{"USD integer", USD, "12", 1200, false},
+		{"USD negative", USD, "-0.05", -5, false},
+		{"BHD", BHD, "1.234", 1234, false},
+		{"CLP", CLP, "123456789", 123456789, false},
+		{"too many decimals", USD, "1.234", 0, true},
+		{"CLP decimals", CLP, "1.5", 0, true},
+		{"empty", USD, "", 0, true},
+		{"no integer part", USD, ".5", 0, true},
+		{"no fraction", USD, "5.", 0, true},
+		{"letters", USD, "1a.00", 0, true},
+		{"large", USD, "9999999999999999", 999999999999999900, false},
+		{"overflow", USD, "1000000000000000000000000000000000000", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.c.ParseDecimal(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != NewAmount(tt.want) {
+				t.Errorf("wrong value %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByNumeric(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		want    Currency
+		wantErr bool
+	}{
+		{"USD", 840, USD, false},
+		{"EUR", 978, EUR, false},
+		{"leading zero", 8, ALL, false},
+		{"withdrawn", 276, DEM, false},
+		{"unknown", 1, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ByNumeric(tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("wrong currency %v, want %v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumericCodesUnique(t *testing.T) {
+	seen := make(map[int]Currency, len(currencyProperties))
+	for c, p := range currencyProperties {
+		if p.Numeric <= 0 || p.Numeric > 999 {
+			t.Errorf("wrong %s numeric code %v", c, p.Numeric)
+		}
+		if prev, ok := seen[p.Numeric]; ok {
+			t.Errorf("%s and %s have the same numeric code %03d", prev, c, p.Numeric)
+		}
+		seen[p.Numeric] = c
+	}
+}
+
+func TestByCountry(t *testing.T) {
+	tests := []struct {
+		country string
+		want    []Currency
+	}{
+		{"US", []Currency{USD, USN}},
+		{"ch", []Currency{CHE, CHF, CHW}},
+		{"DE", []Currency{EUR}},
+		{"HR", []Currency{EUR}},
+		{"XX", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			if got := ByCountry(tt.country); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong currencies %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrencyIsActive(t *testing.T) {
+	tests := []struct {
+		c             Currency
+		want          bool
+		wantWithdrawn time.Time
+	}{
+		{USD, true, time.Time{}},
+		{DEM, false, time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{HRK, false, time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"AAA", false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.c), func(t *testing.T) {
+			if got := tt.c.IsActive(); got != tt.want {
+				t.Errorf("wrong active state %v, want %v", got, tt.want)
+			}
+			got, _ := tt.c.Withdrawn()
+			if !got.Equal(tt.wantWithdrawn) {
+				t.Errorf("wrong withdrawal date %v, want %v", got, tt.wantWithdrawn)
+			}
+		})
+	}
+}
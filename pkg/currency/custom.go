@@ -0,0 +1,80 @@
+package currency
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// MaxCustomDecimals is a maximum number of decimal places of a custom currency.
+//
+// Amounts are 128-bit integers in the lowest currency unit, so a currency with 18 decimal places still holds amounts up to 10^20
+const MaxCustomDecimals = 18
+
+var customCodeRe = regexp.MustCompile(`^[A-Z][A-Z0-9]{2,9}$`)
+
+var (
+	customMu         sync.RWMutex
+	customCurrencies = map[Currency]property{}
+)
+
+// Register adds a custom non-ISO currency, e.g. loyalty points or a crypto asset.
+//
+// The code is 3 to 10 upper case letters and digits and can't be an ISO 4217 code.
+// Registering the same currency again is allowed, but its name and decimals can't be changed
+func Register(code, name string, decimals int) (Currency, error) {
+	c := Currency(code)
+	if !customCodeRe.MatchString(code) {
+		return "", fmt.Errorf("invalid custom currency code %q", code)
+	}
+	if _, ok := currencyProperties[c]; ok {
+		return "", fmt.Errorf("custom currency %s clashes with ISO 4217 code", code)
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty custom currency %s name", code)
+	}
+	if decimals < 0 || decimals > MaxCustomDecimals {
+		return "", fmt.Errorf("custom currency %s should have 0 to %d decimal places, got %d", code, MaxCustomDecimals, decimals)
+	}
+
+	p := property{Code: code, Name: name, Decimals: uint(decimals)}
+	customMu.Lock()
+	defer customMu.Unlock()
+	if prev, ok := customCurrencies[c]; ok && (prev.Name != p.Name || prev.Decimals != p.Decimals) {
+		return "", fmt.Errorf("custom currency %s is already registered as %q with %d decimal places", code, prev.Name, prev.Decimals)
+	}
+	customCurrencies[c] = p
+	return c, nil
+}
+
+// Custom returns registered custom currencies sorted by code
+func Custom() []Currency {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	list := make([]Currency, 0, len(customCurrencies))
+	for c := range customCurrencies {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// IsCustom returns true if a currency is a registered custom currency
+func (c Currency) IsCustom() bool {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	_, ok := customCurrencies[c]
+	return ok
+}
+
+// lookup returns properties of an ISO or a custom currency
+func lookup(c Currency) (property, bool) {
+	if p, ok := currencyProperties[c]; ok {
+		return p, true
+	}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	p, ok := customCurrencies[c]
+	return p, ok
+}
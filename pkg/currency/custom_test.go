@@ -0,0 +1,94 @@
+package currency
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		currName string
+		decimals int
+		wantErr  bool
+	}{
+		{"points", "TSTPTS", "Test Points", 0, false},
+		{"stablecoin", "TSTUSDC", "Test USD Coin", 6, false},
+		{"ether decimals", "TSTETHER", "Test Ether", 18, false},
+		{"same again", "TSTPTS", "Test Points", 0, false},
+		{"changed decimals", "TSTPTS", "Test Points", 2, true},
+		{"iso code", "USD", "Dollar", 2, true},
+		{"lower case", "tstpts", "Test Points", 0, true},
+		{"too short", "TS", "Test", 0, true},
+		{"too long", "TSTPOINTS12", "Test", 0, true},
+		{"empty name", "TSTNONAME", "", 0, true},
+		{"too many decimals", "TSTWEI", "Test Wei", 19, true},
+		{"negative decimals", "TSTNEG", "Test", -1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Register(tt.code, tt.currName, tt.decimals)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got != Currency(tt.code) || !got.IsCustom() || !got.IsActive() {
+				t.Errorf("wrong currency %v", got)
+			}
+			if got.Decimals() != tt.decimals || got.String() != tt.currName {
+				t.Errorf("wrong currency %v with %v decimals, want %v with %v", got.String(), got.Decimals(), tt.currName, tt.decimals)
+			}
+			if a, err := AtoCurrency(tt.code); err != nil || *a != got {
+				t.Errorf("custom currency is not parsed: %v", err)
+			}
+		})
+	}
+	if USD.IsCustom() {
+		t.Error("ISO currency is custom")
+	}
+}
+
+func TestCustomCurrencyBigAmounts(t *testing.T) {
+	c, err := Register("TSTETH", "Test Ether", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.ParseDecimal("12.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "12500000000000000000" {
+		t.Errorf("wrong amount %v, want %v", v, "12500000000000000000")
+	}
+	if got := c.FormatDecimal(v); got != "12.500000000000000000" {
+		t.Errorf("wrong formatted amount %v, want %v", got, "12.500000000000000000")
+	}
+	if got := ConvertToExternal(v, c); got != 12.5 {
+		t.Errorf("wrong external amount %v, want %v", got, 12.5)
+	}
+	if got, err := ConvertToInternal(12.5, c, RoundHalfEven); err != nil || got != v {
+		t.Errorf("wrong internal amount %v (%v), want %v", got, err, v)
+	}
+
+	// 10^20 units are 10^38 in the lowest unit
+	max := "99999999999999999999.999999999999999999"
+	if v, err := c.ParseDecimal(max); err != nil || v != MaxSafeAmount {
+		t.Errorf("wrong max amount %v (%v), want %v", v, err, MaxSafeAmount)
+	}
+	if _, err := c.ParseDecimal("100000000000000000000"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("wrong error %v, want %v", err, ErrOverflow)
+	}
+
+	got := ConvertToInternalBig(big.NewRat(1, 3), c, RoundHalfEven)
+	if got.String() != "333333333333333333" {
+		t.Errorf("wrong converted amount %v, want %v", got, "333333333333333333")
+	}
+	if ext := ConvertToExternalBig(v.Big(), c); ext.Cmp(big.NewRat(25, 2)) != 0 {
+		t.Errorf("wrong external amount %v, want %v", ext, "25/2")
+	}
+}
@@ -0,0 +1,311 @@
+// Command gen generates the ISO 4217 currency table of the currency package.
+//
+// The table is built from the lists published by the ISO 4217 maintenance agency, checked in the data directory:
+//   - list-one.xml: current currencies and funds
+//   - list-three.xml: historic denominations
+//   - countries.csv: ISO 3166 alpha-2 codes of country names used in the lists
+//   - historic-minor-units.csv: minor units of withdrawn currencies from the last list one that had them
+//
+// The published historic list has no minor units, so they are taken from the historic list entry if it has them, or from historic-minor-units.csv.
+// The generation fails if a withdrawn currency is missing in both, so a new withdrawal is never given wrong decimal places silently.
+//
+// To update the table, replace the lists with the new ones and run `go generate ./pkg/currency`
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// skipCodes are codes reserved for testing and transactions without currency
+var skipCodes = map[string]bool{
+	"XTS": true,
+	"XXX": true,
+}
+
+type entry struct {
+	Country    string `xml:"CtryNm"`
+	Name       string `xml:"CcyNm"`
+	Code       string `xml:"Ccy"`
+	Numeric    string `xml:"CcyNmbr"`
+	MinorUnits string `xml:"CcyMnrUnts"`
+	Withdrawn  string `xml:"WthdrwlDt"`
+}
+
+type list struct {
+	Published string  `xml:"Pblshd,attr"`
+	Current   []entry `xml:"CcyTbl>CcyNtry"`
+	Historic  []entry `xml:"HstrcCcyTbl>HstrcCcyNtry"`
+}
+
+type currency struct {
+	Code      string
+	Name      string
+	Decimals  int
+	Numeric   int
+	Countries []string
+	Withdrawn string
+}
+
+func main() {
+	dataDir := flag.String("data", "data", "directory with ISO 4217 lists")
+	out := flag.String("o", "iso4217.go", "output file")
+	flag.Parse()
+
+	src, err := generate(*dataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the source of the currency table
+func generate(dataDir string) ([]byte, error) {
+	countries, err := readCountries(filepath.Join(dataDir, "countries.csv"))
+	if err != nil {
+		return nil, err
+	}
+	one, err := readList(filepath.Join(dataDir, "list-one.xml"))
+	if err != nil {
+		return nil, err
+	}
+	three, err := readList(filepath.Join(dataDir, "list-three.xml"))
+	if err != nil {
+		return nil, err
+	}
+	historicUnits, err := readMinorUnits(filepath.Join(dataDir, "historic-minor-units.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := collect(one.Current, countries, false)
+	if err != nil {
+		return nil, fmt.Errorf("list one: %w", err)
+	}
+	withdrawn, err := collect(three.Historic, countries, true)
+	if err != nil {
+		return nil, fmt.Errorf("list three: %w", err)
+	}
+	// codes of active currencies may appear in the historic list for countries that stopped using them
+	for code := range withdrawn {
+		if _, ok := active[code]; ok {
+			delete(withdrawn, code)
+		}
+	}
+	if err := setHistoricDecimals(withdrawn, historicUnits); err != nil {
+		return nil, fmt.Errorf("list three: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by internal/gen from ISO 4217 lists published %s and %s. DO NOT EDIT.\n\n", one.Published, three.Published)
+	b.WriteString("package currency\n\n")
+	writeConsts(&b, "ISO 4217 currencies", active)
+	writeConsts(&b, "Withdrawn currencies", withdrawn)
+	b.WriteString("// currencyProperties ISO currency property\n")
+	b.WriteString("var currencyProperties = map[Currency]property{\n")
+	writeProperties(&b, active)
+	b.WriteString("\n// withdrawn currencies\n")
+	writeProperties(&b, withdrawn)
+	b.WriteString("}\n")
+
+	return format.Source(b.Bytes())
+}
+
+func readList(path string) (*list, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l list
+	if err := xml.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &l, nil
+}
+
+// readCountries returns ISO 3166 alpha-2 codes by country names, the code is empty for entries that are not countries
+func readCountries(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	countries := make(map[string]string, len(rows))
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(row) != 2 {
+			return nil, fmt.Errorf("%s: line %d: want name and alpha-2 code", path, i+1)
+		}
+		countries[row[0]] = row[1]
+	}
+	return countries, nil
+}
+
+// collect merges list entries of each currency
+func collect(entries []entry, countries map[string]string, historic bool) (map[string]*currency, error) {
+	res := make(map[string]*currency)
+	for _, e := range entries {
+		code := strings.TrimSpace(e.Code)
+		if code == "" || skipCodes[code] {
+			continue
+		}
+		c, ok := res[code]
+		if !ok {
+			num, err := strconv.Atoi(strings.TrimSpace(e.Numeric))
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid numeric code %q", code, e.Numeric)
+			}
+			c = &currency{Code: code, Name: strings.TrimSpace(e.Name), Numeric: num}
+			switch units := strings.TrimSpace(e.MinorUnits); {
+			case units == "" && historic:
+				// set from the historic minor units later
+				c.Decimals = -1
+			case units != "N.A.":
+				if c.Decimals, err = strconv.Atoi(units); err != nil {
+					return nil, fmt.Errorf("%s: invalid minor units %q", code, e.MinorUnits)
+				}
+			}
+			res[code] = c
+		}
+
+		country, ok := countries[strings.TrimSpace(e.Country)]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown country %q", code, e.Country)
+		}
+		if country != "" && !contains(c.Countries, country) {
+			c.Countries = append(c.Countries, country)
+		}
+		if historic {
+			w, err := parseWithdrawal(e.Withdrawn)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", code, err)
+			}
+			// the currency is withdrawn when the last country stopped using it
+			if w > c.Withdrawn {
+				c.Withdrawn = w
+			}
+		}
+	}
+	for _, c := range res {
+		sort.Strings(c.Countries)
+	}
+	return res, nil
+}
+
+// readMinorUnits returns minor units by currency codes
+func readMinorUnits(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	units := make(map[string]int, len(rows))
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(row) != 2 {
+			return nil, fmt.Errorf("%s: line %d: want code and minor units", path, i+1)
+		}
+		if units[row[0]], err = strconv.Atoi(row[1]); err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid minor units %q", path, i+1, row[1])
+		}
+	}
+	return units, nil
+}
+
+// setHistoricDecimals sets decimal places of withdrawn currencies that have no minor units in the historic list
+func setHistoricDecimals(withdrawn map[string]*currency, units map[string]int) error {
+	for _, code := range sortedCodes(withdrawn) {
+		c := withdrawn[code]
+		if c.Decimals >= 0 {
+			continue
+		}
+		d, ok := units[code]
+		if !ok {
+			return fmt.Errorf("%s: unknown minor units, add them to historic-minor-units.csv", code)
+		}
+		c.Decimals = d
+	}
+	return nil
+}
+
+var (
+	yearMonthRe = regexp.MustCompile(`\d{4}-\d{2}`)
+	yearRe      = regexp.MustCompile(`\d{4}`)
+)
+
+// parseWithdrawal returns a year and month of withdrawal.
+//
+// Some historic entries have a period like "1989 to 1990" or only a year, the last date is taken then, and a year is taken as its January
+func parseWithdrawal(s string) (string, error) {
+	if m := yearMonthRe.FindAllString(s, -1); len(m) > 0 {
+		return m[len(m)-1], nil
+	}
+	if m := yearRe.FindAllString(s, -1); len(m) > 0 {
+		return m[len(m)-1] + "-01", nil
+	}
+	return "", fmt.Errorf("invalid withdrawal date %q", s)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedCodes(m map[string]*currency) []string {
+	codes := make([]string, 0, len(m))
+	for code := range m {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func writeConsts(b *bytes.Buffer, comment string, m map[string]*currency) {
+	fmt.Fprintf(b, "// %s\nconst (\n", comment)
+	for _, code := range sortedCodes(m) {
+		fmt.Fprintf(b, "%s Currency = %q\n", code, code)
+	}
+	b.WriteString(")\n\n")
+}
+
+func writeProperties(b *bytes.Buffer, m map[string]*currency) {
+	for _, code := range sortedCodes(m) {
+		c := m[code]
+		countries := "nil"
+		if len(c.Countries) > 0 {
+			countries = fmt.Sprintf("%#v", c.Countries)
+		}
+		fmt.Fprintf(b, "%s: {%q, %q, %d, %d, %s, %q},\n", code, c.Code, c.Name, c.Decimals, c.Numeric, countries, c.Withdrawn)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	got, err := generate("../../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../../iso4217.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("iso4217.go is out of date with ISO 4217 lists, run go generate ./pkg/currency")
+	}
+}
+
+func TestParseWithdrawal(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"2002-03", "2002-03", false},
+		{"1989 to 1990", "1990-01", false},
+		{"1993-01 to 1993-12", "1993-12", false},
+		{"1990", "1990-01", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseWithdrawal(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("wrong date %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoricDecimals(t *testing.T) {
+	countries := map[string]string{"MAURITANIA": "MR", "ZAMBIA": "ZM"}
+	units := map[string]int{"MRO": 2}
+	tests := []struct {
+		name    string
+		entry   entry
+		want    int
+		wantErr bool
+	}{
+		{"minor units in the list", entry{Country: "ZAMBIA", Code: "ZMK", Numeric: "894", MinorUnits: "0", Withdrawn: "2012-12"}, 0, false},
+		{"minor units in the table", entry{Country: "MAURITANIA", Code: "MRO", Numeric: "478", Withdrawn: "2017-12"}, 2, false},
+		{"unknown minor units", entry{Country: "ZAMBIA", Code: "ZMK", Numeric: "894", Withdrawn: "2012-12"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withdrawn, err := collect([]entry{tt.entry}, countries, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = setHistoricDecimals(withdrawn, units)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got := withdrawn[tt.entry.Code].Decimals; !tt.wantErr && got != tt.want {
+				t.Errorf("wrong decimals %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
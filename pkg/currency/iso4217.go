@@ -0,0 +1,403 @@
+// Code generated by internal/gen from ISO 4217 lists published 2024-06-25 and 2024-06-25. DO NOT EDIT.
+
+package currency
+
+// ISO 4217 currencies
+const (
+	AED Currency = "AED"
+	AFN Currency = "AFN"
+	ALL Currency = "ALL"
+	AMD Currency = "AMD"
+	ANG Currency = "ANG"
+	AOA Currency = "AOA"
+	ARS Currency = "ARS"
+	AUD Currency = "AUD"
+	AWG Currency = "AWG"
+	AZN Currency = "AZN"
+	BAM Currency = "BAM"
+	BBD Currency = "BBD"
+	BDT Currency = "BDT"
+	BGN Currency = "BGN"
+	BHD Currency = "BHD"
+	BIF Currency = "BIF"
+	BMD Currency = "BMD"
+	BND Currency = "BND"
+	BOB Currency = "BOB"
+	BOV Currency = "BOV"
+	BRL Currency = "BRL"
+	BSD Currency = "BSD"
+	BTN Currency = "BTN"
+	BWP Currency = "BWP"
+	BYN Currency = "BYN"
+	BZD Currency = "BZD"
+	CAD Currency = "CAD"
+	CDF Currency = "CDF"
+	CHE Currency = "CHE"
+	CHF Currency = "CHF"
+	CHW Currency = "CHW"
+	CLF Currency = "CLF"
+	CLP Currency = "CLP"
+	CNY Currency = "CNY"
+	COP Currency = "COP"
+	COU Currency = "COU"
+	CRC Currency = "CRC"
+	CUC Currency = "CUC"
+	CUP Currency = "CUP"
+	CVE Currency = "CVE"
+	CZK Currency = "CZK"
+	DJF Currency = "DJF"
+	DKK Currency = "DKK"
+	DOP Currency = "DOP"
+	DZD Currency = "DZD"
+	EGP Currency = "EGP"
+	ERN Currency = "ERN"
+	ETB Currency = "ETB"
+	EUR Currency = "EUR"
+	FJD Currency = "FJD"
+	FKP Currency = "FKP"
+	GBP Currency = "GBP"
+	GEL Currency = "GEL"
+	GHS Currency = "GHS"
+	GIP Currency = "GIP"
+	GMD Currency = "GMD"
+	GNF Currency = "GNF"
+	GTQ Currency = "GTQ"
+	GYD Currency = "GYD"
+	HKD Currency = "HKD"
+	HNL Currency = "HNL"
+	HTG Currency = "HTG"
+	HUF Currency = "HUF"
+	IDR Currency = "IDR"
+	ILS Currency = "ILS"
+	INR Currency = "INR"
+	IQD Currency = "IQD"
+	IRR Currency = "IRR"
+	ISK Currency = "ISK"
+	JMD Currency = "JMD"
+	JOD Currency = "JOD"
+	JPY Currency = "JPY"
+	KES Currency = "KES"
+	KGS Currency = "KGS"
+	KHR Currency = "KHR"
+	KMF Currency = "KMF"
+	KPW Currency = "KPW"
+	KRW Currency = "KRW"
+	KWD Currency = "KWD"
+	KYD Currency = "KYD"
+	KZT Currency = "KZT"
+	LAK Currency = "LAK"
+	LBP Currency = "LBP"
+	LKR Currency = "LKR"
+	LRD Currency = "LRD"
+	LSL Currency = "LSL"
+	LYD Currency = "LYD"
+	MAD Currency = "MAD"
+	MDL Currency = "MDL"
+	MGA Currency = "MGA"
+	MKD Currency = "MKD"
+	MMK Currency = "MMK"
+	MNT Currency = "MNT"
+	MOP Currency = "MOP"
+	MRU Currency = "MRU"
+	MUR Currency = "MUR"
+	MVR Currency = "MVR"
+	MWK Currency = "MWK"
+	MXN Currency = "MXN"
+	MXV Currency = "MXV"
+	MYR Currency = "MYR"
+	MZN Currency = "MZN"
+	NAD Currency = "NAD"
+	NGN Currency = "NGN"
+	NIO Currency = "NIO"
+	NOK Currency = "NOK"
+	NPR Currency = "NPR"
+	NZD Currency = "NZD"
+	OMR Currency = "OMR"
+	PAB Currency = "PAB"
+	PEN Currency = "PEN"
+	PGK Currency = "PGK"
+	PHP Currency = "PHP"
+	PKR Currency = "PKR"
+	PLN Currency = "PLN"
+	PYG Currency = "PYG"
+	QAR Currency = "QAR"
+	RON Currency = "RON"
+	RSD Currency = "RSD"
+	RUB Currency = "RUB"
+	RWF Currency = "RWF"
+	SAR Currency = "SAR"
+	SBD Currency = "SBD"
+	SCR Currency = "SCR"
+	SDG Currency = "SDG"
+	SEK Currency = "SEK"
+	SGD Currency = "SGD"
+	SHP Currency = "SHP"
+	SLL Currency = "SLL"
+	SOS Currency = "SOS"
+	SRD Currency = "SRD"
+	SSP Currency = "SSP"
+	STN Currency = "STN"
+	SVC Currency = "SVC"
+	SYP Currency = "SYP"
+	SZL Currency = "SZL"
+	THB Currency = "THB"
+	TJS Currency = "TJS"
+	TMT Currency = "TMT"
+	TND Currency = "TND"
+	TOP Currency = "TOP"
+	TRY Currency = "TRY"
+	TTD Currency = "TTD"
+	TWD Currency = "TWD"
+	TZS Currency = "TZS"
+	UAH Currency = "UAH"
+	UGX Currency = "UGX"
+	USD Currency = "USD"
+	USN Currency = "USN"
+	UYI Currency = "UYI"
+	UYU Currency = "UYU"
+	UYW Currency = "UYW"
+	UZS Currency = "UZS"
+	VES Currency = "VES"
+	VND Currency = "VND"
+	VUV Currency = "VUV"
+	WST Currency = "WST"
+	XAF Currency = "XAF"
+	XCD Currency = "XCD"
+	XDR Currency = "XDR"
+	XOF Currency = "XOF"
+	XPF Currency = "XPF"
+	XSU Currency = "XSU"
+	XUA Currency = "XUA"
+	YER Currency = "YER"
+	ZAR Currency = "ZAR"
+	ZMW Currency = "ZMW"
+	ZWL Currency = "ZWL"
+)
+
+// Withdrawn currencies
+const (
+	ATS Currency = "ATS"
+	BEF Currency = "BEF"
+	BYR Currency = "BYR"
+	CYP Currency = "CYP"
+	DEM Currency = "DEM"
+	EEK Currency = "EEK"
+	ESP Currency = "ESP"
+	FIM Currency = "FIM"
+	FRF Currency = "FRF"
+	GRD Currency = "GRD"
+	HRK Currency = "HRK"
+	IEP Currency = "IEP"
+	ITL Currency = "ITL"
+	LTL Currency = "LTL"
+	LUF Currency = "LUF"
+	LVL Currency = "LVL"
+	MRO Currency = "MRO"
+	MTL Currency = "MTL"
+	NLG Currency = "NLG"
+	PTE Currency = "PTE"
+	SIT Currency = "SIT"
+	SKK Currency = "SKK"
+	STD Currency = "STD"
+	TRL Currency = "TRL"
+	ZMK Currency = "ZMK"
+)
+
+// currencyProperties ISO currency property
+var currencyProperties = map[Currency]property{
+	AED: {"AED", "UAE Dirham", 2, 784, []string{"AE"}, ""},
+	AFN: {"AFN", "Afghani", 2, 971, []string{"AF"}, ""},
+	ALL: {"ALL", "Lek", 2, 8, []string{"AL"}, ""},
+	AMD: {"AMD", "Armenian Dram", 2, 51, []string{"AM"}, ""},
+	ANG: {"ANG", "Netherlands Antillean Guilder", 2, 532, []string{"CW", "SX"}, ""},
+	AOA: {"AOA", "Kwanza", 2, 973, []string{"AO"}, ""},
+	ARS: {"ARS", "Argentine Peso", 2, 32, []string{"AR"}, ""},
+	AUD: {"AUD", "Australian Dollar", 2, 36, []string{"AU", "CC", "CX", "HM", "KI", "NF", "NR", "TV"}, ""},
+	AWG: {"AWG", "Aruban Florin", 2, 533, []string{"AW"}, ""},
+	AZN: {"AZN", "Azerbaijan Manat", 2, 944, []string{"AZ"}, ""},
+	BAM: {"BAM", "Convertible Mark", 2, 977, []string{"BA"}, ""},
+	BBD: {"BBD", "Barbados Dollar", 2, 52, []string{"BB"}, ""},
+	BDT: {"BDT", "Taka", 2, 50, []string{"BD"}, ""},
+	BGN: {"BGN", "Bulgarian Lev", 2, 975, []string{"BG"}, ""},
+	BHD: {"BHD", "Bahraini Dinar", 3, 48, []string{"BH"}, ""},
+	BIF: {"BIF", "Burundi Franc", 0, 108, []string{"BI"}, ""},
+	BMD: {"BMD", "Bermudian Dollar", 2, 60, []string{"BM"}, ""},
+	BND: {"BND", "Brunei Dollar", 2, 96, []string{"BN"}, ""},
+	BOB: {"BOB", "Boliviano", 2, 68, []string{"BO"}, ""},
+	BOV: {"BOV", "Mvdol", 2, 984, []string{"BO"}, ""},
+	BRL: {"BRL", "Brazilian Real", 2, 986, []string{"BR"}, ""},
+	BSD: {"BSD", "Bahamian Dollar", 2, 44, []string{"BS"}, ""},
+	BTN: {"BTN", "Ngultrum", 2, 64, []string{"BT"}, ""},
+	BWP: {"BWP", "Pula", 2, 72, []string{"BW"}, ""},
+	BYN: {"BYN", "Belarusian Ruble", 2, 933, []string{"BY"}, ""},
+	BZD: {"BZD", "Belize Dollar", 2, 84, []string{"BZ"}, ""},
+	CAD: {"CAD", "Canadian Dollar", 2, 124, []string{"CA"}, ""},
+	CDF: {"CDF", "Congolese Franc", 2, 976, []string{"CD"}, ""},
+	CHE: {"CHE", "WIR Euro", 2, 947, []string{"CH"}, ""},
+	CHF: {"CHF", "Swiss Franc", 2, 756, []string{"CH", "LI"}, ""},
+	CHW: {"CHW", "WIR Franc", 2, 948, []string{"CH"}, ""},
+	CLF: {"CLF", "Unidad de Fomento", 4, 990, []string{"CL"}, ""},
+	CLP: {"CLP", "Chilean Peso", 0, 152, []string{"CL"}, ""},
+	CNY: {"CNY", "Yuan Renminbi", 2, 156, []string{"CN"}, ""},
+	COP: {"COP", "Colombian Peso", 2, 170, []string{"CO"}, ""},
+	COU: {"COU", "Unidad de Valor Real", 2, 970, []string{"CO"}, ""},
+	CRC: {"CRC", "Costa Rican Colon", 2, 188, []string{"CR"}, ""},
+	CUC: {"CUC", "Peso Convertible", 2, 931, []string{"CU"}, ""},
+	CUP: {"CUP", "Cuban Peso", 2, 192, []string{"CU"}, ""},
+	CVE: {"CVE", "Cabo Verde Escudo", 2, 132, []string{"CV"}, ""},
+	CZK: {"CZK", "Czech Koruna", 2, 203, []string{"CZ"}, ""},
+	DJF: {"DJF", "Djibouti Franc", 0, 262, []string{"DJ"}, ""},
+	DKK: {"DKK", "Danish Krone", 2, 208, []string{"DK", "FO", "GL"}, ""},
+	DOP: {"DOP", "Dominican Peso", 2, 214, []string{"DO"}, ""},
+	DZD: {"DZD", "Algerian Dinar", 2, 12, []string{"DZ"}, ""},
+	EGP: {"EGP", "Egyptian Pound", 2, 818, []string{"EG"}, ""},
+	ERN: {"ERN", "Nakfa", 2, 232, []string{"ER"}, ""},
+	ETB: {"ETB", "Ethiopian Birr", 2, 230, []string{"ET"}, ""},
+	EUR: {"EUR", "Euro", 2, 978, []string{"AD", "AT", "AX", "BE", "BL", "CY", "DE", "EE", "ES", "FI", "FR", "GF", "GP", "GR", "HR", "IE", "IT", "LT", "LU", "LV", "MC", "ME", "MF", "MQ", "MT", "NL", "PM", "PT", "RE", "SI", "SK", "SM", "TF", "VA", "YT"}, ""},
+	FJD: {"FJD", "Fiji Dollar", 2, 242, []string{"FJ"}, ""},
+	FKP: {"FKP", "Falkland Islands Pound", 2, 238, []string{"FK"}, ""},
+	GBP: {"GBP", "Pound Sterling", 2, 826, []string{"GB", "GG", "IM", "JE"}, ""},
+	GEL: {"GEL", "Lari", 2, 981, []string{"GE"}, ""},
+	GHS: {"GHS", "Ghana Cedi", 2, 936, []string{"GH"}, ""},
+	GIP: {"GIP", "Gibraltar Pound", 2, 292, []string{"GI"}, ""},
+	GMD: {"GMD", "Dalasi", 2, 270, []string{"GM"}, ""},
+	GNF: {"GNF", "Guinean Franc", 0, 324, []string{"GN"}, ""},
+	GTQ: {"GTQ", "Quetzal", 2, 320, []string{"GT"}, ""},
+	GYD: {"GYD", "Guyana Dollar", 2, 328, []string{"GY"}, ""},
+	HKD: {"HKD", "Hong Kong Dollar", 2, 344, []string{"HK"}, ""},
+	HNL: {"HNL", "Lempira", 2, 340, []string{"HN"}, ""},
+	HTG: {"HTG", "Gourde", 2, 332, []string{"HT"}, ""},
+	HUF: {"HUF", "Forint", 2, 348, []string{"HU"}, ""},
+	IDR: {"IDR", "Rupiah", 2, 360, []string{"ID"}, ""},
+	ILS: {"ILS", "New Israeli Sheqel", 2, 376, []string{"IL"}, ""},
+	INR: {"INR", "Indian Rupee", 2, 356, []string{"BT", "IN"}, ""},
+	IQD: {"IQD", "Iraqi Dinar", 3, 368, []string{"IQ"}, ""},
+	IRR: {"IRR", "Iranian Rial", 2, 364, []string{"IR"}, ""},
+	ISK: {"ISK", "Iceland Krona", 0, 352, []string{"IS"}, ""},
+	JMD: {"JMD", "Jamaican Dollar", 2, 388, []string{"JM"}, ""},
+	JOD: {"JOD", "Jordanian Dinar", 3, 400, []string{"JO"}, ""},
+	JPY: {"JPY", "Yen", 0, 392, []string{"JP"}, ""},
+	KES: {"KES", "Kenyan Shilling", 2, 404, []string{"KE"}, ""},
+	KGS: {"KGS", "Som", 2, 417, []string{"KG"}, ""},
+	KHR: {"KHR", "Riel", 2, 116, []string{"KH"}, ""},
+	KMF: {"KMF", "Comorian Franc", 0, 174, []string{"KM"}, ""},
+	KPW: {"KPW", "North Korean Won", 2, 408, []string{"KP"}, ""},
+	KRW: {"KRW", "Won", 0, 410, []string{"KR"}, ""},
+	KWD: {"KWD", "Kuwaiti Dinar", 3, 414, []string{"KW"}, ""},
+	KYD: {"KYD", "Cayman Islands Dollar", 2, 136, []string{"KY"}, ""},
+	KZT: {"KZT", "Tenge", 2, 398, []string{"KZ"}, ""},
+	LAK: {"LAK", "Lao Kip", 2, 418, []string{"LA"}, ""},
+	LBP: {"LBP", "Lebanese Pound", 2, 422, []string{"LB"}, ""},
+	LKR: {"LKR", "Sri Lanka Rupee", 2, 144, []string{"LK"}, ""},
+	LRD: {"LRD", "Liberian Dollar", 2, 430, []string{"LR"}, ""},
+	LSL: {"LSL", "Loti", 2, 426, []string{"LS"}, ""},
+	LYD: {"LYD", "Libyan Dinar", 3, 434, []string{"LY"}, ""},
+	MAD: {"MAD", "Moroccan Dirham", 2, 504, []string{"EH", "MA"}, ""},
+	MDL: {"MDL", "Moldovan Leu", 2, 498, []string{"MD"}, ""},
+	MGA: {"MGA", "Malagasy Ariary", 2, 969, []string{"MG"}, ""},
+	MKD: {"MKD", "Denar", 2, 807, []string{"MK"}, ""},
+	MMK: {"MMK", "Kyat", 2, 104, []string{"MM"}, ""},
+	MNT: {"MNT", "Tugrik", 2, 496, []string{"MN"}, ""},
+	MOP: {"MOP", "Pataca", 2, 446, []string{"MO"}, ""},
+	MRU: {"MRU", "Ouguiya", 2, 929, []string{"MR"}, ""},
+	MUR: {"MUR", "Mauritius Rupee", 2, 480, []string{"MU"}, ""},
+	MVR: {"MVR", "Rufiyaa", 2, 462, []string{"MV"}, ""},
+	MWK: {"MWK", "Malawi Kwacha", 2, 454, []string{"MW"}, ""},
+	MXN: {"MXN", "Mexican Peso", 2, 484, []string{"MX"}, ""},
+	MXV: {"MXV", "Mexican Unidad de Inversion (UDI)", 2, 979, []string{"MX"}, ""},
+	MYR: {"MYR", "Malaysian Ringgit", 2, 458, []string{"MY"}, ""},
+	MZN: {"MZN", "Mozambique Metical", 2, 943, []string{"MZ"}, ""},
+	NAD: {"NAD", "Namibia Dollar", 2, 516, []string{"NA"}, ""},
+	NGN: {"NGN", "Naira", 2, 566, []string{"NG"}, ""},
+	NIO: {"NIO", "Cordoba Oro", 2, 558, []string{"NI"}, ""},
+	NOK: {"NOK", "Norwegian Krone", 2, 578, []string{"BV", "NO", "SJ"}, ""},
+	NPR: {"NPR", "Nepalese Rupee", 2, 524, []string{"NP"}, ""},
+	NZD: {"NZD", "New Zealand Dollar", 2, 554, []string{"CK", "NU", "NZ", "PN", "TK"}, ""},
+	OMR: {"OMR", "Rial Omani", 3, 512, []string{"OM"}, ""},
+	PAB: {"PAB", "Balboa", 2, 590, []string{"PA"}, ""},
+	PEN: {"PEN", "Sol", 2, 604, []string{"PE"}, ""},
+	PGK: {"PGK", "Kina", 2, 598, []string{"PG"}, ""},
+	PHP: {"PHP", "Philippine Peso", 2, 608, []string{"PH"}, ""},
+	PKR: {"PKR", "Pakistan Rupee", 2, 586, []string{"PK"}, ""},
+	PLN: {"PLN", "Zloty", 2, 985, []string{"PL"}, ""},
+	PYG: {"PYG", "Guarani", 0, 600, []string{"PY"}, ""},
+	QAR: {"QAR", "Qatari Rial", 2, 634, []string{"QA"}, ""},
+	RON: {"RON", "Romanian Leu", 2, 946, []string{"RO"}, ""},
+	RSD: {"RSD", "Serbian Dinar", 2, 941, []string{"RS"}, ""},
+	RUB: {"RUB", "Russian Ruble", 2, 643, []string{"RU"}, ""},
+	RWF: {"RWF", "Rwanda Franc", 0, 646, []string{"RW"}, ""},
+	SAR: {"SAR", "Saudi Riyal", 2, 682, []string{"SA"}, ""},
+	SBD: {"SBD", "Solomon Islands Dollar", 2, 90, []string{"SB"}, ""},
+	SCR: {"SCR", "Seychelles Rupee", 2, 690, []string{"SC"}, ""},
+	SDG: {"SDG", "Sudanese Pound", 2, 938, []string{"SD"}, ""},
+	SEK: {"SEK", "Swedish Krona", 2, 752, []string{"SE"}, ""},
+	SGD: {"SGD", "Singapore Dollar", 2, 702, []string{"SG"}, ""},
+	SHP: {"SHP", "Saint Helena Pound", 2, 654, []string{"SH"}, ""},
+	SLL: {"SLL", "Leone", 2, 694, []string{"SL"}, ""},
+	SOS: {"SOS", "Somali Shilling", 2, 706, []string{"SO"}, ""},
+	SRD: {"SRD", "Surinam Dollar", 2, 968, []string{"SR"}, ""},
+	SSP: {"SSP", "South Sudanese Pound", 2, 728, []string{"SS"}, ""},
+	STN: {"STN", "Dobra", 2, 930, []string{"ST"}, ""},
+	SVC: {"SVC", "El Salvador Colon", 2, 222, []string{"SV"}, ""},
+	SYP: {"SYP", "Syrian Pound", 2, 760, []string{"SY"}, ""},
+	SZL: {"SZL", "Lilangeni", 2, 748, []string{"SZ"}, ""},
+	THB: {"THB", "Baht", 2, 764, []string{"TH"}, ""},
+	TJS: {"TJS", "Somoni", 2, 972, []string{"TJ"}, ""},
+	TMT: {"TMT", "Turkmenistan New Manat", 2, 934, []string{"TM"}, ""},
+	TND: {"TND", "Tunisian Dinar", 3, 788, []string{"TN"}, ""},
+	TOP: {"TOP", "Pa’anga", 2, 776, []string{"TO"}, ""},
+	TRY: {"TRY", "Turkish Lira", 2, 949, []string{"TR"}, ""},
+	TTD: {"TTD", "Trinidad and Tobago Dollar", 2, 780, []string{"TT"}, ""},
+	TWD: {"TWD", "New Taiwan Dollar", 2, 901, []string{"TW"}, ""},
+	TZS: {"TZS", "Tanzanian Shilling", 2, 834, []string{"TZ"}, ""},
+	UAH: {"UAH", "Hryvnia", 2, 980, []string{"UA"}, ""},
+	UGX: {"UGX", "Uganda Shilling", 0, 800, []string{"UG"}, ""},
+	USD: {"USD", "US Dollar", 2, 840, []string{"AS", "BQ", "EC", "FM", "GU", "HT", "IO", "MH", "MP", "PA", "PR", "PW", "SV", "TC", "TL", "UM", "US", "VG", "VI"}, ""},
+	USN: {"USN", "US Dollar (Next day)", 2, 997, []string{"US"}, ""},
+	UYI: {"UYI", "Uruguay Peso en Unidades Indexadas (UI)", 0, 940, []string{"UY"}, ""},
+	UYU: {"UYU", "Peso Uruguayo", 2, 858, []string{"UY"}, ""},
+	UYW: {"UYW", "Unidad Previsional", 4, 927, []string{"UY"}, ""},
+	UZS: {"UZS", "Uzbekistan Sum", 2, 860, []string{"UZ"}, ""},
+	VES: {"VES", "Bolívar Soberano", 2, 928, []string{"VE"}, ""},
+	VND: {"VND", "Dong", 0, 704, []string{"VN"}, ""},
+	VUV: {"VUV", "Vatu", 0, 548, []string{"VU"}, ""},
+	WST: {"WST", "Tala", 2, 882, []string{"WS"}, ""},
+	XAF: {"XAF", "CFA Franc BEAC", 0, 950, []string{"CF", "CG", "CM", "GA", "GQ", "TD"}, ""},
+	XCD: {"XCD", "East Caribbean Dollar", 2, 951, []string{"AG", "AI", "DM", "GD", "KN", "LC", "MS", "VC"}, ""},
+	XDR: {"XDR", "SDR (Special Drawing Right)", 0, 960, nil, ""},
+	XOF: {"XOF", "CFA Franc BCEAO", 0, 952, []string{"BF", "BJ", "CI", "GW", "ML", "NE", "SN", "TG"}, ""},
+	XPF: {"XPF", "CFP Franc", 0, 953, []string{"NC", "PF", "WF"}, ""},
+	XSU: {"XSU", "Sucre", 0, 994, nil, ""},
+	XUA: {"XUA", "ADB Unit of Account", 0, 965, nil, ""},
+	YER: {"YER", "Yemeni Rial", 2, 886, []string{"YE"}, ""},
+	ZAR: {"ZAR", "Rand", 2, 710, []string{"LS", "NA", "ZA"}, ""},
+	ZMW: {"ZMW", "Zambian Kwacha", 2, 967, []string{"ZM"}, ""},
+	ZWL: {"ZWL", "Zimbabwe Dollar", 2, 932, []string{"ZW"}, ""},
+
+	// withdrawn currencies
+	ATS: {"ATS", "Schilling", 2, 40, []string{"AT"}, "2002-03"},
+	BEF: {"BEF", "Belgian Franc", 0, 56, []string{"BE"}, "2002-03"},
+	BYR: {"BYR", "Belarusian Ruble", 0, 974, []string{"BY"}, "2017-01"},
+	CYP: {"CYP", "Cyprus Pound", 2, 196, []string{"CY"}, "2008-01"},
+	DEM: {"DEM", "Deutsche Mark", 2, 276, []string{"DE"}, "2002-03"},
+	EEK: {"EEK", "Kroon", 2, 233, []string{"EE"}, "2011-01"},
+	ESP: {"ESP", "Spanish Peseta", 0, 724, []string{"AD", "ES"}, "2002-03"},
+	FIM: {"FIM", "Markka", 2, 246, []string{"AX", "FI"}, "2002-03"},
+	FRF: {"FRF", "French Franc", 2, 250, []string{"FR", "GF", "GP", "MC", "MQ", "PM", "RE", "TF", "YT"}, "2002-03"},
+	GRD: {"GRD", "Drachma", 0, 300, []string{"GR"}, "2002-03"},
+	HRK: {"HRK", "Kuna", 2, 191, []string{"HR"}, "2023-01"},
+	IEP: {"IEP", "Irish Pound", 2, 372, []string{"IE"}, "2002-03"},
+	ITL: {"ITL", "Italian Lira", 0, 380, []string{"IT", "SM", "VA"}, "2002-03"},
+	LTL: {"LTL", "Lithuanian Litas", 2, 440, []string{"LT"}, "2014-12"},
+	LUF: {"LUF", "Luxembourg Franc", 0, 442, []string{"LU"}, "2002-03"},
+	LVL: {"LVL", "Latvian Lats", 2, 428, []string{"LV"}, "2014-01"},
+	MRO: {"MRO", "Ouguiya", 2, 478, []string{"MR"}, "2017-12"},
+	MTL: {"MTL", "Maltese Lira", 2, 470, []string{"MT"}, "2008-01"},
+	NLG: {"NLG", "Netherlands Guilder", 2, 528, []string{"NL"}, "2002-03"},
+	PTE: {"PTE", "Portuguese Escudo", 0, 620, []string{"PT"}, "2002-03"},
+	SIT: {"SIT", "Tolar", 2, 705, []string{"SI"}, "2007-01"},
+	SKK: {"SKK", "Slovak Koruna", 2, 703, []string{"SK"}, "2009-01"},
+	STD: {"STD", "Dobra", 2, 678, []string{"ST"}, "2017-12"},
+	TRL: {"TRL", "Turkish Lira", 0, 792, []string{"TR"}, "2005-12"},
+	ZMK: {"ZMK", "Zambian Kwacha", 2, 894, []string{"ZM"}, "2012-12"},
+}
@@ -0,0 +1,249 @@
+package currency
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLocale is a locale used when no locale is set
+const DefaultLocale = "en-US"
+
+//go:embed data/symbols.json data/locales.json
+var localeData embed.FS
+
+// Locale is a set of rules to format currency amounts in some language and region
+type Locale struct {
+	// Name is a BCP 47 locale name, e.g. `en-US`
+	Name string `json:"name"`
+	// Decimal is a decimal separator
+	Decimal string `json:"decimal"`
+	// Group is a digit group separator
+	Group string `json:"group"`
+	// Grouping are sizes of digit groups from the right, the last size repeats.
+	// E.g. [3] for 1,234,567 or [3, 2] for 12,34,567
+	Grouping []int `json:"grouping"`
+	// Pattern is a pattern of a positive amount, `¤` stands for the currency symbol and `#` for the number.
+	// E.g. `¤#` for $1.00 or `# ¤` for 1,00 €
+	Pattern string `json:"pattern"`
+	// Symbols are currency symbols used in the locale instead of default ones, e.g. `$` for CAD in en-CA
+	Symbols map[Currency]string `json:"symbols,omitempty"`
+}
+
+var (
+	symbols map[Currency]string
+	locales map[string]*Locale
+)
+
+func init() {
+	var err error
+	if symbols, locales, err = loadLocaleData(localeData); err != nil {
+		panic(err)
+	}
+}
+
+func loadLocaleData(fsys embed.FS) (map[Currency]string, map[string]*Locale, error) {
+	data, err := fsys.ReadFile("data/symbols.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	var syms map[Currency]string
+	if err := json.Unmarshal(data, &syms); err != nil {
+		return nil, nil, fmt.Errorf("currency symbols: %w", err)
+	}
+
+	if data, err = fsys.ReadFile("data/locales.json"); err != nil {
+		return nil, nil, err
+	}
+	var list []*Locale
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, nil, fmt.Errorf("currency locales: %w", err)
+	}
+	locs := make(map[string]*Locale, len(list))
+	for _, l := range list {
+		if l.Decimal == "" || l.Decimal == l.Group || len(l.Grouping) == 0 ||
+			strings.Count(l.Pattern, "¤") != 1 || strings.Count(l.Pattern, "#") != 1 {
+			return nil, nil, fmt.Errorf("invalid currency locale %q", l.Name)
+		}
+		for _, g := range l.Grouping {
+			if g <= 0 {
+				return nil, nil, fmt.Errorf("invalid grouping of currency locale %q", l.Name)
+			}
+		}
+		locs[normalizeLocaleName(l.Name)] = l
+	}
+	return syms, locs, nil
+}
+
+// LookupLocale returns a locale by its name, e.g. `de-DE` or `de_DE`
+func LookupLocale(name string) (*Locale, error) {
+	if l, ok := locales[normalizeLocaleName(name)]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("unknown locale %q", name)
+}
+
+// Locales returns sorted names of all known locales
+func Locales() []string {
+	names := make([]string, 0, len(locales))
+	for _, l := range locales {
+		names = append(names, l.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func normalizeLocaleName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+func localeOrDefault(l *Locale) *Locale {
+	if l == nil {
+		return locales[normalizeLocaleName(DefaultLocale)]
+	}
+	return l
+}
+
+// Symbol returns a currency symbol in the locale.
+//
+// Currencies without a symbol are shown by their code. The default locale is used if the locale is nil
+func (c Currency) Symbol(l *Locale) string {
+	if s, ok := localeOrDefault(l).Symbols[c]; ok {
+		return s
+	}
+	if s, ok := symbols[c]; ok {
+		return s
+	}
+	return string(c)
+}
+
+// Format returns an amount formatted with the currency symbol by the locale rules.
+//
+// E.g. USD 123456 -> "$1,234.56" in en-US, EUR 123456 -> "1.234,56 €" in de-DE.
+// Negative amounts are prefixed with the minus sign. The default locale is used if the locale is nil
+func (c Currency) Format(raw Amount, l *Locale) string {
+	l = localeOrDefault(l)
+
+	num := c.FormatDecimal(raw)
+	sign := ""
+	if strings.HasPrefix(num, "-") {
+		sign, num = "-", num[1:]
+	}
+	intPart, fracPart := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		intPart, fracPart = num[:i], num[i+1:]
+	}
+	num = l.group(intPart)
+	if fracPart != "" {
+		num += l.Decimal + fracPart
+	}
+
+	sym := c.Symbol(l)
+	pattern := l.Pattern
+	// separate letter symbols from adjacent digits with a no-break space, e.g. "CHF 1.00" instead of "CHF1.00"
+	if strings.Contains(pattern, "¤#") {
+		if r, _ := utf8.DecodeLastRuneInString(sym); unicode.IsLetter(r) {
+			pattern = strings.Replace(pattern, "¤#", "¤\u00a0#", 1)
+		}
+	} else if strings.Contains(pattern, "#¤") {
+		if r, _ := utf8.DecodeRuneInString(sym); unicode.IsLetter(r) {
+			pattern = strings.Replace(pattern, "#¤", "#\u00a0¤", 1)
+		}
+	}
+	return sign + strings.NewReplacer("¤", sym, "#", num).Replace(pattern)
+}
+
+// group inserts group separators into a string of digits
+func (l *Locale) group(digits string) string {
+	var groups []string
+	for i := 0; len(digits) > 0; i++ {
+		size := l.Grouping[len(l.Grouping)-1]
+		if i < len(l.Grouping) {
+			size = l.Grouping[i]
+		}
+		if size >= len(digits) {
+			groups = append(groups, digits)
+			break
+		}
+		groups = append(groups, digits[len(digits)-size:])
+		digits = digits[:len(digits)-size]
+	}
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+	return strings.Join(groups, l.Group)
+}
+
+// Parse converts an amount formatted by the locale rules into an amount in the lowest unit of the currency.
+//
+// The currency symbol and its code are optional, group separators are ignored.
+// The amount can't have more decimal places than the currency. The default locale is used if the locale is nil
+func (c Currency) Parse(s string, l *Locale) (Amount, error) {
+	l = localeOrDefault(l)
+	errInvalid := fmt.Errorf("invalid %s amount %q in %s", string(c), s, l.Name)
+
+	num, neg := trimMinus(strings.TrimSpace(s))
+	for _, sym := range []string{c.Symbol(l), c.Symbol(nil), string(c)} {
+		if strings.HasPrefix(num, sym) {
+			num = strings.TrimSpace(num[len(sym):])
+			break
+		}
+		if strings.HasSuffix(num, sym) {
+			num = strings.TrimSpace(num[:len(num)-len(sym)])
+			break
+		}
+	}
+
+	if !neg {
+		// the sign may go after the symbol, e.g. "€ -1,00"
+		num, neg = trimMinus(num)
+	}
+
+	intPart, fracPart := num, ""
+	if i := strings.Index(num, l.Decimal); i >= 0 {
+		intPart, fracPart = num[:i], num[i+len(l.Decimal):]
+	}
+	var b strings.Builder
+	for _, r := range intPart {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune(l.Group, r):
+		case unicode.IsSpace(r) && strings.TrimSpace(l.Group) == "":
+			// any space is accepted as a space group separator
+		default:
+			return Amount{}, errInvalid
+		}
+	}
+	if b.Len() == 0 {
+		return Amount{}, errInvalid
+	}
+	if fracPart != "" {
+		b.WriteString("." + fracPart)
+	} else if strings.Contains(num, l.Decimal) {
+		return Amount{}, errInvalid
+	}
+
+	v, err := c.ParseDecimal(b.String())
+	if err != nil {
+		return Amount{}, errInvalid
+	}
+	if neg {
+		v = v.Neg()
+	}
+	return v, nil
+}
+
+// trimMinus removes a leading minus or hyphen-minus sign
+func trimMinus(s string) (string, bool) {
+	for _, minus := range []string{"-", "−"} {
+		if strings.HasPrefix(s, minus) {
+			return strings.TrimSpace(s[len(minus):]), true
+		}
+	}
+	return s, false
+}
@@ -0,0 +1,176 @@
+package currency
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func mustLocale(t *testing.T, name string) *Locale {
+	t.Helper()
+	l, err := LookupLocale(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func sortedCurrencies() []Currency {
+	list := make([]Currency, 0, len(currencyProperties))
+	for c := range currencyProperties {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+func TestLookupLocale(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"en-US", "en-US", false},
+		{"de_DE", "de-DE", false},
+		{"FR-fr", "fr-FR", false},
+		{"xx-XX", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupLocale(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err == nil && got.Name != tt.want {
+				t.Errorf("wrong locale %v, want %v", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrencyFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		c      Currency
+		raw    int64
+		locale string
+		want   string
+	}{
+		{"en-US dollar", USD, 123456, "en-US", "$1,234.56"},
+		{"de-DE euro", EUR, 123456, "de-DE", "1.234,56 €"},
+		{"yen without decimals", JPY, 1235, "en-US", "¥1,235"},
+		{"negative", USD, -123456, "en-US", "-$1,234.56"},
+		{"small", USD, 5, "en-US", "$0.05"},
+		{"zero", EUR, 0, "fr-FR", "0,00 €"},
+		{"three decimals", KWD, 1234567, "en-US", "KWD 1,234.567"},
+		{"locale symbol", CAD, 100, "en-CA", "$1.00"},
+		{"foreign dollar", USD, 100, "en-CA", "US$1.00"},
+		{"indian grouping", INR, 1234567890, "en-IN", "₹1,23,45,678.90"},
+		{"space group", RUB, 123456789, "ru-RU", "1 234 567,89 ₽"},
+		{"symbol before", EUR, 123456, "nl-NL", "€ 1.234,56"},
+		{"apostrophe group", CHF, 123456, "de-CH", "CHF 1’234.56"},
+		{"default locale", GBP, 100000, "", "£1,000.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l *Locale
+			if tt.locale != "" {
+				l = mustLocale(t, tt.locale)
+			}
+			if got := tt.c.Format(NewAmount(tt.raw), l); got != tt.want {
+				t.Errorf("wrong result %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrencyParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Currency
+		s       string
+		locale  string
+		want    int64
+		wantErr bool
+	}{
+		{"en-US dollar", USD, "$1,234.56", "en-US", 123456, false},
+		{"de-DE euro", EUR, "1.234,56 €", "de-DE", 123456, false},
+		{"yen", JPY, "¥1,235", "en-US", 1235, false},
+		{"no symbol", USD, "1234.5", "en-US", 123450, false},
+		{"code", USD, "USD 12", "en-US", 1200, false},
+		{"negative", USD, "-$1,234.56", "en-US", -123456, false},
+		{"minus after symbol", EUR, "€ -1.234,56", "nl-NL", -123456, false},
+		{"minus sign", EUR, "−1,00 €", "de-DE", -100, false},
+		{"any space group", RUB, "1 234 567,89 ₽", "ru-RU", 123456789, false},
+		{"default symbol", RUB, "RUB 1,00", "ru-RU", 100, false},
+		{"too many decimals", USD, "$1.234", "en-US", 0, true},
+		{"two decimal separators", EUR, "1,234,56 €", "de-DE", 0, true},
+		{"trailing separator", USD, "$1.", "en-US", 0, true},
+		{"other symbol", USD, "€1.00", "en-US", 0, true},
+		{"empty", USD, "$", "en-US", 0, true},
+		{"letters", USD, "$1a", "en-US", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.c.Parse(tt.s, mustLocale(t, tt.locale))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != NewAmount(tt.want) {
+				t.Errorf("wrong result %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCurrencyFormatGolden checks formatting of every currency in every locale.
+// Run with -update to rewrite golden files after changing locale data
+func TestCurrencyFormatGolden(t *testing.T) {
+	raws := amounts(0, -5, 123456789)
+	for _, name := range Locales() {
+		t.Run(name, func(t *testing.T) {
+			l := mustLocale(t, name)
+
+			var b strings.Builder
+			for _, c := range sortedCurrencies() {
+				b.WriteString(string(c))
+				for _, raw := range raws {
+					s := c.Format(raw, l)
+					fmt.Fprintf(&b, "\t%s", s)
+
+					got, err := c.Parse(s, l)
+					if err != nil {
+						t.Errorf("%s: %v", c, err)
+					} else if got != raw {
+						t.Errorf("wrong %s round trip result %v, want %v", c, got, raw)
+					}
+				}
+				b.WriteString("\n")
+			}
+
+			path := filepath.Join("testdata", "format", name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != string(want) {
+				t.Errorf("formatted amounts differ from %s, run tests with -update if the change is intended", path)
+			}
+		})
+	}
+}
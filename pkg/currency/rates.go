@@ -0,0 +1,107 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// ErrNoRate means that there is no exchange rate between currencies
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rates is an exchange rate table.
+//
+// Each rate is a price of one unit of the currency in the base currency, so a rate between any two currencies of the table is derived through the base currency
+type Rates struct {
+	base  Currency
+	rates map[Currency]float64
+}
+
+// NewRates creates an exchange rate table with prices of currencies in the base currency.
+//
+// E.g. USD base and EUR 1.1 means that 1 EUR costs 1.1 USD
+func NewRates(base Currency, rates map[Currency]float64) (*Rates, error) {
+	if _, ok := lookup(base); !ok {
+		return nil, fmt.Errorf("unknown currency (%s)", string(base))
+	}
+	r := &Rates{
+		base:  base,
+		rates: map[Currency]float64{base: 1},
+	}
+	for c, rate := range rates {
+		if _, ok := lookup(c); !ok {
+			return nil, fmt.Errorf("unknown currency (%s)", string(c))
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("invalid %s exchange rate %v", string(c), rate)
+		}
+		if c == base && rate != 1 {
+			return nil, fmt.Errorf("base currency %s rate should be 1, got %v", string(c), rate)
+		}
+		r.rates[c] = rate
+	}
+	return r, nil
+}
+
+// Base returns the base currency of the table
+func (r *Rates) Base() Currency {
+	return r.base
+}
+
+// List returns the table as rates of each currency to the base currency set at the time, sorted by currency.
+//
+// E.g. USD base and EUR 1.1 is EUR/USD 1.1
+func (r *Rates) List(at time.Time) []Rate {
+	list := make([]Rate, 0, len(r.rates))
+	for c, rate := range r.rates {
+		if c == r.base {
+			continue
+		}
+		list = append(list, Rate{Base: c, Quote: r.base, Rate: rate, Time: at})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Base < list[j].Base })
+	return list
+}
+
+// Rate returns a price of one unit of from currency in to currency.
+//
+// If one of the currencies is not in the table, the method will return `ErrNoRate` error
+func (r *Rates) Rate(from, to Currency) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if r == nil {
+		return 0, fmt.Errorf("%w from %s to %s", ErrNoRate, string(from), string(to))
+	}
+	fromRate, ok := r.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w from %s to %s", ErrNoRate, string(from), string(to))
+	}
+	toRate, ok := r.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w from %s to %s", ErrNoRate, string(from), string(to))
+	}
+	return fromRate / toRate, nil
+}
+
+// Convert converts an amount in the lowest unit of from currency into the lowest unit of to currency.
+//
+// The result is rounded to the lowest unit with the rounding mode. E.g. 1.1 USD per EUR: 1000 EUR cents -> 1100 USD cents.
+// It returns ErrOverflow if the result is out of the safe range
+func (r *Rates) Convert(amount Amount, from, to Currency, mode RoundingMode) (Amount, error) {
+	if _, err := r.Rate(from, to); err != nil {
+		return Amount{}, err
+	}
+	if from == to {
+		return amount, nil
+	}
+	// rates are applied as exact decimals, so 1000 * 1.1 is 1100, not 1100.0000000000002
+	x := new(big.Rat).SetInt(amount.Big())
+	x.Mul(x, Rat(r.rates[from]))
+	x.Quo(x, Rat(r.rates[to]))
+	x.Mul(x, pow10Rat(to.Decimals()-from.Decimals()))
+	return AmountFromBig(mode.RoundRatBig(x))
+}
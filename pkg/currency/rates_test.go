@@ -0,0 +1,87 @@
+package currency
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRatesConvert(t *testing.T) {
+	r, err := NewRates(USD, map[Currency]float64{
+		EUR: 1.1,
+		JPY: 0.0091,
+		BHD: 2.65,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		amount  Amount
+		from    Currency
+		to      Currency
+		mode    RoundingMode
+		want    Amount
+		wantErr error
+	}{
+		{"same currency", NewAmount(12345), USD, USD, RoundHalfEven, NewAmount(12345), nil},
+		{"to base", NewAmount(1000), EUR, USD, RoundHalfEven, NewAmount(1100), nil},
+		{"to base exact ceiling", NewAmount(1000), EUR, USD, RoundCeiling, NewAmount(1100), nil},
+		{"from base", NewAmount(1100), USD, EUR, RoundHalfEven, NewAmount(1000), nil},
+		{"through base", NewAmount(1000), EUR, JPY, RoundHalfEven, NewAmount(1209), nil},
+		{"through base down", NewAmount(1000), EUR, JPY, RoundDown, NewAmount(1208), nil},
+		{"more decimals", NewAmount(100), USD, BHD, RoundHalfEven, NewAmount(377), nil},
+		{"negative floor", NewAmount(-1000), EUR, JPY, RoundFloor, NewAmount(-1209), nil},
+		{"no rate", NewAmount(100), USD, GBP, RoundHalfEven, Amount{}, ErrNoRate},
+		{"overflow", MaxSafeAmount, USD, JPY, RoundHalfEven, Amount{}, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Convert(tt.amount, tt.from, tt.to, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("wrong result %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    Currency
+		rates   map[Currency]float64
+		wantErr bool
+	}{
+		{"valid", USD, map[Currency]float64{EUR: 1.1}, false},
+		{"unknown base", "AAA", nil, true},
+		{"unknown currency", USD, map[Currency]float64{"AAA": 1}, true},
+		{"zero rate", USD, map[Currency]float64{EUR: 0}, true},
+		{"wrong base rate", USD, map[Currency]float64{USD: 2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRates(tt.base, tt.rates); (err != nil) != tt.wantErr {
+				t.Errorf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRatesList(t *testing.T) {
+	at := time.Date(2019, 6, 23, 0, 0, 0, 0, time.UTC)
+	r, err := NewRates(USD, map[Currency]float64{EUR: 1.1, GBP: 1.27, USD: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rate{
+		{Base: EUR, Quote: USD, Rate: 1.1, Time: at},
+		{Base: GBP, Quote: USD, Rate: 1.27, Time: at},
+	}
+	if got := r.List(at); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong rates %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package currency
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// RoundingMode is a way to round a fractional amount to the lowest unit of the currency
+type RoundingMode int
+
+// Rounding modes.
+//
+// The zero value is `RoundHalfEven`
+const (
+	// RoundHalfEven rounds to the nearest unit, halves to the even one (banker's rounding)
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest unit, halves away from zero
+	RoundHalfUp
+	// RoundDown rounds toward zero
+	RoundDown
+	// RoundUp rounds away from zero
+	RoundUp
+	// RoundCeiling rounds toward positive infinity
+	RoundCeiling
+	// RoundFloor rounds toward negative infinity
+	RoundFloor
+)
+
+var roundingModeNames = map[RoundingMode]string{
+	RoundHalfEven: "half-even",
+	RoundHalfUp:   "half-up",
+	RoundDown:     "down",
+	RoundUp:       "up",
+	RoundCeiling:  "ceiling",
+	RoundFloor:    "floor",
+}
+
+// String returns a name of the rounding mode, e.g. `half-even`
+func (m RoundingMode) String() string {
+	if name, ok := roundingModeNames[m]; ok {
+		return name
+	}
+	return "RoundingMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// ParseRoundingMode converts a rounding mode name into the rounding mode
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	for m, name := range roundingModeNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown rounding mode %q", s)
+}
+
+// Round rounds the number to an integer.
+//
+// The number is taken as its shortest decimal representation, so 0.29 is rounded as 0.29, not as 0.28999999999999998.
+// Infinities and NaN are rounded to zero
+func (m RoundingMode) Round(x float64) int {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return 0
+	}
+	return m.RoundRat(Rat(x))
+}
+
+// RoundRat rounds the rational number to an integer
+func (m RoundingMode) RoundRat(x *big.Rat) int {
+	return int(m.RoundRatBig(x).Int64())
+}
+
+// RoundRatBig rounds the rational number to a big integer
+func (m RoundingMode) RoundRatBig(x *big.Rat) *big.Int {
+	q, r := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	sign := int64(x.Sign())
+	// compare the remainder with a half of the denominator
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(x.Denom())
+
+	var away bool
+	switch m {
+	case RoundHalfEven:
+		away = cmp > 0 || cmp == 0 && q.Bit(0) == 1
+	case RoundHalfUp:
+		away = cmp >= 0
+	case RoundDown:
+		away = false
+	case RoundUp:
+		away = true
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+// Rat returns the exact value of the shortest decimal representation of the number.
+//
+// E.g. 0.29 -> 29/100
+func Rat(x float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// pow10Rat returns 10^n as a rational number, n can be negative
+func pow10Rat(n int) *big.Rat {
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(n))), nil)
+	if n < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
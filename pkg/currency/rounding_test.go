@@ -0,0 +1,82 @@
+package currency
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRoundingModeRound(t *testing.T) {
+	tests := []struct {
+		name string
+		x    float64
+		want map[RoundingMode]int
+	}{
+		{"integer", 2, map[RoundingMode]int{
+			RoundHalfEven: 2, RoundHalfUp: 2, RoundDown: 2, RoundUp: 2, RoundCeiling: 2, RoundFloor: 2,
+		}},
+		{"below half", 2.4, map[RoundingMode]int{
+			RoundHalfEven: 2, RoundHalfUp: 2, RoundDown: 2, RoundUp: 3, RoundCeiling: 3, RoundFloor: 2,
+		}},
+		{"half to even", 2.5, map[RoundingMode]int{
+			RoundHalfEven: 2, RoundHalfUp: 3, RoundDown: 2, RoundUp: 3, RoundCeiling: 3, RoundFloor: 2,
+		}},
+		{"half to odd", 3.5, map[RoundingMode]int{
+			RoundHalfEven: 4, RoundHalfUp: 4, RoundDown: 3, RoundUp: 4, RoundCeiling: 4, RoundFloor: 3,
+		}},
+		{"negative half", -2.5, map[RoundingMode]int{
+			RoundHalfEven: -2, RoundHalfUp: -3, RoundDown: -2, RoundUp: -3, RoundCeiling: -2, RoundFloor: -3,
+		}},
+		{"negative above half", -2.6, map[RoundingMode]int{
+			RoundHalfEven: -3, RoundHalfUp: -3, RoundDown: -2, RoundUp: -3, RoundCeiling: -2, RoundFloor: -3,
+		}},
+		{"float error", 28.999999999999996, map[RoundingMode]int{
+			RoundHalfEven: 29, RoundHalfUp: 29, RoundDown: 28, RoundUp: 29, RoundCeiling: 29, RoundFloor: 28,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for mode, want := range tt.want {
+				if got := mode.Round(tt.x); got != want {
+					t.Errorf("wrong %s result %v, want %v", mode, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundingModeRoundRat(t *testing.T) {
+	// 0.29 * 100 is exactly 29 as a rational number
+	x := new(big.Rat).Mul(Rat(0.29), big.NewRat(100, 1))
+	for mode := range roundingModeNames {
+		if got := mode.RoundRat(x); got != 29 {
+			t.Errorf("wrong %s result %v, want %v", mode, got, 29)
+		}
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    RoundingMode
+		wantErr bool
+	}{
+		{"half-even", RoundHalfEven, false},
+		{"half-up", RoundHalfUp, false},
+		{"down", RoundDown, false},
+		{"up", RoundUp, false},
+		{"ceiling", RoundCeiling, false},
+		{"floor", RoundFloor, false},
+		{"nearest", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseRoundingMode(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("wrong result %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
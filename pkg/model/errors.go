@@ -4,3 +4,6 @@ import "errors"
 
 // ErrRowExists means that the row with the key provided already exists
 var ErrRowExists = errors.New("row exists")
+
+// ErrConflict means that the row was changed by a concurrent process
+var ErrConflict = errors.New("concurrent update conflict")
@@ -0,0 +1,28 @@
+package model
+
+// AccountImport is a raw account row of a bulk import
+type AccountImport struct {
+	// Line is a line number of the row in the source file
+	Line     int
+	ID       string
+	Currency string
+	// Balance is a decimal string
+	Balance string
+}
+
+// ImportError is a reason why the import row was rejected
+type ImportError struct {
+	Line  int
+	ID    string
+	Error string
+}
+
+// ImportResult is a result of a bulk import.
+//
+// Either all rows are imported or none of them, in the latter case Errors describes invalid rows
+type ImportResult struct {
+	// Imported is a number of imported rows. On a dry run it is a number of rows that would be imported
+	Imported int
+	DryRun   bool
+	Errors   []ImportError
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+// Compounding periods of interest-bearing accounts.
+//
+// Accrued interest is paid out on the last day of each period, so the next period earns interest on it
+const (
+	CompoundingDaily     = "daily"
+	CompoundingMonthly   = "monthly"
+	CompoundingQuarterly = "quarterly"
+	CompoundingAnnually  = "annually"
+)
+
+// EntryInterest is a journal entry of an interest payout
+const EntryInterest = "interest"
+
+// AccountType is an interest-bearing account type
+type AccountType struct {
+	Name string
+	// AnnualRate is a yearly interest rate, e.g. 0.02 for 2%
+	AnnualRate  float64
+	Compounding string
+}
+
+// Validate checks the rate and the compounding period of the account type
+func (t AccountType) Validate() error {
+	if t.AnnualRate < 0 || math.IsInf(t.AnnualRate, 0) || math.IsNaN(t.AnnualRate) {
+		return fmt.Errorf("account type %s has invalid annual rate %v", t.Name, t.AnnualRate)
+	}
+	switch t.Compounding {
+	case CompoundingDaily, CompoundingMonthly, CompoundingQuarterly, CompoundingAnnually:
+		return nil
+	}
+	return fmt.Errorf("account type %s has unknown compounding period %q", t.Name, t.Compounding)
+}
+
+// DailyInterest returns interest on the end-of-day balance in the lowest currency unit.
+//
+// The annual rate is divided by the number of days in the year, the result is rounded half to even.
+// Non-positive balances earn no interest
+func (t AccountType) DailyInterest(balance currency.Amount, day time.Time) (currency.Amount, error) {
+	if balance.Sign() <= 0 {
+		return currency.Amount{}, nil
+	}
+	days := time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	x := new(big.Rat).SetFrac(balance.Big(), big.NewInt(int64(days)))
+	x.Mul(x, currency.Rat(t.AnnualRate))
+	return currency.AmountFromBig(currency.RoundHalfEven.RoundRatBig(x))
+}
+
+// PayoutDue checks if the day is the last day of the compounding period
+func (t AccountType) PayoutDue(day time.Time) bool {
+	next := day.AddDate(0, 0, 1)
+	switch t.Compounding {
+	case CompoundingDaily:
+		return true
+	case CompoundingMonthly:
+		return next.Month() != day.Month()
+	case CompoundingQuarterly:
+		return next.Month() != day.Month() && day.Month()%3 == 0
+	case CompoundingAnnually:
+		return next.Year() != day.Year()
+	}
+	return false
+}
+
+// InterestAccrual is interest accrued on an account for one day
+type InterestAccrual struct {
+	AccountID string
+	Date      time.Time
+	// Balance is the end-of-day ledger balance the interest is calculated on
+	Balance  currency.Amount
+	Rate     float64
+	Amount   currency.Amount
+	Currency currency.Currency
+	// PaymentID is a payout payment, zero until the interest is paid
+	PaymentID int
+}
+
+// InterestRun is a result of the daily interest job
+type InterestRun struct {
+	Date time.Time
+	// Accrued is a number of new accruals, accounts accrued by an earlier run of the same day are skipped
+	Accrued int
+	Payouts []Payment
+}
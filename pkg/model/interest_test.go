@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+func TestAccountTypeDailyInterest(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		balance int64
+		day     time.Time
+		want    int64
+	}{
+		{"simple", 0.0365, 100000, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), 10},
+		{"leap year", 0.0366, 100000, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), 10},
+		{"half to even down", 0.0365, 25000, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), 2},
+		{"half to even up", 0.0365, 35000, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), 4},
+		{"negative balance", 0.0365, -100000, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := AccountType{Name: "savings", AnnualRate: tt.rate, Compounding: CompoundingDaily}
+			got, err := at.DailyInterest(currency.NewAmount(tt.balance), tt.day)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != currency.NewAmount(tt.want) {
+				t.Errorf("wrong interest %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountTypePayoutDue(t *testing.T) {
+	tests := []struct {
+		name        string
+		compounding string
+		day         time.Time
+		want        bool
+	}{
+		{"daily", CompoundingDaily, time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC), true},
+		{"monthly", CompoundingMonthly, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), true},
+		{"monthly not due", CompoundingMonthly, time.Date(2023, 2, 27, 0, 0, 0, 0, time.UTC), false},
+		{"quarterly", CompoundingQuarterly, time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC), true},
+		{"quarterly not due", CompoundingQuarterly, time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC), false},
+		{"annually", CompoundingAnnually, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{"annually not due", CompoundingAnnually, time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := AccountType{Name: "savings", Compounding: tt.compounding}
+			if got := at.PayoutDue(tt.day); got != tt.want {
+				t.Errorf("wrong payout due %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+// Journal entry kinds
+const (
+	EntryPayment    = "payment"
+	EntryConversion = "conversion"
+	// EntryOpening is an opening balance of an account
+	EntryOpening = "opening"
+)
+
+// System ledger account kinds.
+//
+// System accounts are not stored as accounts, they exist only in the ledger, one per currency, see `SystemAccountID`
+const (
+	SystemFees = "fees"
+	// SystemFX balances currency legs of conversions
+	SystemFX = "fx"
+	// SystemSuspense holds opening balances and other amounts of unknown origin
+	SystemSuspense = "suspense"
+)
+
+// systemAccountPrefix starts IDs of system ledger accounts
+const systemAccountPrefix = "@"
+
+// SystemAccountID returns an ID of the system ledger account of the kind in the currency, e.g. `@fx/EUR`
+func SystemAccountID(kind string, c currency.Currency) string {
+	return systemAccountPrefix + kind + "/" + string(c)
+}
+
+// IsSystemAccount checks if the ledger account is a system one
+func IsSystemAccount(id string) bool {
+	return strings.HasPrefix(id, systemAccountPrefix)
+}
+
+// JournalEntry is a ledger transaction.
+//
+// Postings of the entry sum to zero in each currency
+type JournalEntry struct {
+	ID       int
+	DateTime time.Time
+	Kind     string
+	// PaymentID is set for entries of payments and conversions
+	PaymentID int
+	Postings  []Posting
+}
+
+// Posting is a change of one ledger account balance in the currency.
+//
+// A positive amount is a credit that increases the account balance, a negative one is a debit
+type Posting struct {
+	AccountID string
+	Amount    currency.Amount
+	Currency  currency.Currency
+}
+
+// Balanced checks if postings of the entry sum to zero in each currency
+func (e JournalEntry) Balanced() bool {
+	sums := make(map[currency.Currency]*big.Int)
+	for _, p := range e.Postings {
+		if sums[p.Currency] == nil {
+			sums[p.Currency] = new(big.Int)
+		}
+		sums[p.Currency].Add(sums[p.Currency], p.Amount.Big())
+	}
+	for _, sum := range sums {
+		if sum.Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PaymentEntry returns a journal entry of the payment.
+//
+// A conversion has two extra postings on the FX system accounts, so each currency is balanced separately
+func PaymentEntry(p Payment) JournalEntry {
+	e := JournalEntry{
+		DateTime:  p.DateTime,
+		Kind:      EntryPayment,
+		PaymentID: p.ID,
+	}
+	if p.ToCurrency == "" {
+		e.Postings = []Posting{
+			{AccountID: p.AccFromID, Amount: p.Amount.Neg(), Currency: p.Currency},
+			{AccountID: p.AccToID, Amount: p.Amount, Currency: p.Currency},
+		}
+		return e
+	}
+	e.Kind = EntryConversion
+	e.Postings = []Posting{
+		{AccountID: p.AccFromID, Amount: p.Amount.Neg(), Currency: p.Currency},
+		{AccountID: SystemAccountID(SystemFX, p.Currency), Amount: p.Amount, Currency: p.Currency},
+		{AccountID: SystemAccountID(SystemFX, p.ToCurrency), Amount: p.ToAmount.Neg(), Currency: p.ToCurrency},
+		{AccountID: p.AccToID, Amount: p.ToAmount, Currency: p.ToCurrency},
+	}
+	return e
+}
+
+// OpeningEntry returns a journal entry of the account opening balance funded from the suspense account.
+//
+// The entry has no postings if the balance is zero
+func OpeningEntry(a Account, t time.Time) JournalEntry {
+	e := JournalEntry{
+		DateTime: t,
+		Kind:     EntryOpening,
+	}
+	if a.Balance.Sign() != 0 {
+		e.Postings = []Posting{
+			{AccountID: SystemAccountID(SystemSuspense, a.Currency), Amount: a.Balance.Neg(), Currency: a.Currency},
+			{AccountID: a.ID, Amount: a.Balance, Currency: a.Currency},
+		}
+	}
+	return e
+}
+
+// TrialBalanceAccount is a sum of postings of one ledger account in one currency
+type TrialBalanceAccount struct {
+	AccountID string
+	Currency  currency.Currency
+	// Debit and Credit are absolute sums of negative and positive postings
+	Debit   currency.Amount
+	Credit  currency.Amount
+	Balance currency.Amount
+	System  bool
+}
+
+// TrialBalanceTotal is a sum of postings of all ledger accounts in one currency
+type TrialBalanceTotal struct {
+	Currency currency.Currency
+	Debit    currency.Amount
+	Credit   currency.Amount
+}
+
+// TrialBalance is a report of ledger account sums.
+//
+// The ledger is balanced if total debits equal total credits in each currency
+type TrialBalance struct {
+	Accounts []TrialBalanceAccount
+	Totals   []TrialBalanceTotal
+	Balanced bool
+}
+
+// NewTrialBalance calculates the trial balance of account sums.
+//
+// Totals are ordered the same way as currencies first appear in accounts.
+// A total out of the safe amount range is reported as the ledger being unbalanced
+func NewTrialBalance(accounts []TrialBalanceAccount) TrialBalance {
+	tb := TrialBalance{
+		Accounts: accounts,
+		Totals:   make([]TrialBalanceTotal, 0),
+		Balanced: true,
+	}
+	idx := make(map[currency.Currency]int)
+	for i := range tb.Accounts {
+		a := &tb.Accounts[i]
+		a.System = IsSystemAccount(a.AccountID)
+
+		// both sums are non-negative, so the difference is always in the safe range
+		a.Balance, _ = a.Credit.Sub(a.Debit)
+
+		j, ok := idx[a.Currency]
+		if !ok {
+			j = len(tb.Totals)
+			idx[a.Currency] = j
+			tb.Totals = append(tb.Totals, TrialBalanceTotal{Currency: a.Currency})
+		}
+		debit, errDebit := tb.Totals[j].Debit.Add(a.Debit)
+		credit, errCredit := tb.Totals[j].Credit.Add(a.Credit)
+		if errDebit != nil || errCredit != nil {
+			tb.Balanced = false
+			continue
+		}
+		tb.Totals[j].Debit, tb.Totals[j].Credit = debit, credit
+	}
+	for _, t := range tb.Totals {
+		if t.Debit != t.Credit {
+			tb.Balanced = false
+		}
+	}
+	return tb
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+func TestPaymentEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		payment  Payment
+		wantKind string
+		wantLen  int
+	}{
+		{
+			name:     "payment",
+			payment:  Payment{ID: 1, AccFromID: "alice", AccToID: "bob", Amount: currency.NewAmount(1000), Currency: currency.USD},
+			wantKind: EntryPayment,
+			wantLen:  2,
+		},
+		{
+			name: "conversion",
+			payment: Payment{ID: 2, AccFromID: "alice/EUR", AccToID: "alice/USD", Amount: currency.NewAmount(1000), Currency: currency.EUR,
+				ToAmount: currency.NewAmount(1100), ToCurrency: currency.USD},
+			wantKind: EntryConversion,
+			wantLen:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := PaymentEntry(tt.payment)
+			if e.Kind != tt.wantKind || e.PaymentID != tt.payment.ID {
+				t.Errorf("wrong entry %v for payment %v, want %v for %v", e.Kind, e.PaymentID, tt.wantKind, tt.payment.ID)
+			}
+			if len(e.Postings) != tt.wantLen {
+				t.Errorf("wrong postings %v, want %v", len(e.Postings), tt.wantLen)
+			}
+			if !e.Balanced() {
+				t.Errorf("entry is not balanced %v", e.Postings)
+			}
+		})
+	}
+}
+
+func TestOpeningEntry(t *testing.T) {
+	e := OpeningEntry(Account{ID: "alice", Balance: currency.NewAmount(500), Currency: currency.EUR}, time.Now())
+	if len(e.Postings) != 2 || !e.Balanced() {
+		t.Errorf("wrong opening entry %v", e.Postings)
+	}
+	if e.Postings[0].AccountID != "@suspense/EUR" {
+		t.Errorf("wrong counter account %v, want %v", e.Postings[0].AccountID, "@suspense/EUR")
+	}
+
+	if e := OpeningEntry(Account{ID: "bob", Currency: currency.EUR}, time.Now()); len(e.Postings) != 0 {
+		t.Errorf("wrong zero balance entry %v", e.Postings)
+	}
+}
+
+func TestNewTrialBalance(t *testing.T) {
+	tb := NewTrialBalance([]TrialBalanceAccount{
+		{AccountID: "@fx/EUR", Currency: currency.EUR, Credit: currency.NewAmount(1000)},
+		{AccountID: "alice/EUR", Currency: currency.EUR, Debit: currency.NewAmount(1000)},
+		{AccountID: "@fx/USD", Currency: currency.USD, Debit: currency.NewAmount(1100)},
+		{AccountID: "alice/USD", Currency: currency.USD, Credit: currency.NewAmount(1000)},
+	})
+	if tb.Balanced {
+		t.Errorf("unbalanced USD is reported as balanced")
+	}
+	if len(tb.Totals) != 2 || tb.Totals[0].Currency != currency.EUR || tb.Totals[1].Debit != currency.NewAmount(1100) {
+		t.Errorf("wrong totals %v", tb.Totals)
+	}
+	if !tb.Accounts[0].System || tb.Accounts[1].System || tb.Accounts[1].Balance != currency.NewAmount(-1000) {
+		t.Errorf("wrong accounts %v", tb.Accounts)
+	}
+}
+
+func TestNewTrialBalanceOverflow(t *testing.T) {
+	tb := NewTrialBalance([]TrialBalanceAccount{
+		{AccountID: "alice/EUR", Currency: currency.EUR, Credit: currency.MaxSafeAmount},
+		{AccountID: "bob/EUR", Currency: currency.EUR, Credit: currency.MaxSafeAmount},
+		{AccountID: "@suspense/EUR", Currency: currency.EUR, Debit: currency.MaxSafeAmount},
+	})
+	if tb.Balanced {
+		t.Errorf("total out of the safe range is reported as balanced")
+	}
+	if tb.Totals[0].Credit != currency.MaxSafeAmount {
+		t.Errorf("wrong credit total %v, want %v", tb.Totals[0].Credit, currency.MaxSafeAmount)
+	}
+}
@@ -0,0 +1,144 @@
+// Package model contains common application models and types
+package model
+
+import (
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+// Account is a financial account entity representation
+type Account struct {
+	ID         string
+	LastUpdate *time.Time
+	Balance    currency.Amount
+	Currency   currency.Currency
+	AccountInfo
+	// Converted is the balance in another currency, it is only set on request
+	Converted *ConvertedBalance
+}
+
+// ConvertedBalance is an account balance converted into another currency with a historical exchange rate
+type ConvertedBalance struct {
+	Balance  currency.Amount
+	Currency currency.Currency
+	Rate     currency.Rate
+}
+
+// AccountInfo is descriptive account data that doesn't affect payments
+type AccountInfo struct {
+	// OwnerID is an external reference to the account owner, e.g. a customer ID
+	OwnerID     string
+	DisplayName string
+	Labels      []string
+	Metadata    map[string]string
+	// Type is a name of the interest-bearing account type, empty for a plain account
+	Type string
+}
+
+// AccountPatch is a partial account info update, nil fields are not changed
+type AccountPatch struct {
+	OwnerID     *string
+	DisplayName *string
+	// Labels replace all account labels
+	Labels *[]string
+	// Metadata replaces the whole metadata map
+	Metadata *map[string]string
+	Type     *string
+}
+
+// AccountFilter limits a list of accounts, empty fields match all accounts
+type AccountFilter struct {
+	OwnerID string
+	// Labels are labels every account should have
+	Labels []string
+	// ConvertTo is a currency to convert balances into, it doesn't limit the list
+	ConvertTo string
+}
+
+// Payment is a financial transaction between accounts
+type Payment struct {
+	ID        int
+	AccFromID string
+	AccToID   string
+	DateTime  time.Time
+	Amount    currency.Amount
+	Currency  currency.Currency
+	// ToAmount and ToCurrency are set only for a conversion between pockets of a wallet, then the receiver gets ToAmount in ToCurrency
+	ToAmount   currency.Amount
+	ToCurrency currency.Currency
+	// GroupID links payments of one split payment, zero for a single payment
+	GroupID int
+	// IdempotencyKey is a key of the payment creation call, a repeated call with the same key returns this payment
+	IdempotencyKey string
+	PaymentInfo
+}
+
+// PaymentInfo is descriptive payment data
+type PaymentInfo struct {
+	// Reference is an external reference, e.g. an invoice or an order number. It is unique among payments of the payer
+	Reference   string
+	Description string
+	Metadata    map[string]string
+}
+
+// PaymentFilter limits a list of payments, empty fields match all payments
+type PaymentFilter struct {
+	Reference string
+	// Search is a text to search in payment references and descriptions, case-insensitive
+	Search string
+	// Metadata are key-value pairs every payment should have
+	Metadata map[string]string
+	// GroupID limits the list to payments of the split payment
+	GroupID int
+}
+
+// SplitReceiver is a receiver of a split payment share.
+//
+// The share is either a fixed amount or a percentage of the amount left after all fixed shares
+type SplitReceiver struct {
+	AccountID string
+	// Amount is a fixed share in currency units, e.g. 12.5 for 12.50 USD
+	Amount  float64
+	Percent float64
+}
+
+// PaymentGroup is a split payment from one payer to many receivers.
+//
+// Each receiver gets a separate payment linked to the group by its ID
+type PaymentGroup struct {
+	ID        int
+	AccFromID string
+	DateTime  time.Time
+	// Amount is a total of all payments of the group
+	Amount   currency.Amount
+	Currency currency.Currency
+	Payments []Payment
+	// Description and metadata are copied to each payment of the group.
+	// The reference is kept only by the group, it is unique among split payments of the payer
+	PaymentInfo
+}
+
+// Wallet is a set of accounts of one owner in different currencies.
+//
+// Each account of the wallet is a currency pocket with the ID `wallet/currency`, see `PocketID`
+type Wallet struct {
+	ID      string
+	OwnerID string
+	Pockets []Account
+	// Total is a sum of pocket balances converted into TotalCurrency
+	Total         currency.Amount
+	TotalCurrency currency.Currency
+}
+
+// PocketID returns an account ID of the wallet pocket in the currency
+func PocketID(walletID string, c currency.Currency) string {
+	return walletID + "/" + string(c)
+}
+
+// CustomCurrency is a non-ISO currency, e.g. loyalty points or a crypto asset
+type CustomCurrency struct {
+	Code     string
+	Name     string
+	Decimals int
+}
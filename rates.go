@@ -0,0 +1,226 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+// Exchange rate file formats
+const (
+	ratesFormatCSV  = "csv"
+	ratesFormatJSON = "json"
+)
+
+// ratesColumns are required columns of a CSV rates file, the `time` column is optional
+var ratesColumns = []string{"base", "quote", "rate"}
+
+// DecodeRates reads exchange rates from a CSV file, or a JSON rate object or array of rates.
+//
+// A CSV file must have a header line with `base`, `quote`, `rate` and an optional `time` columns in any order.
+// The time is RFC 3339 or a date, a rate without time is set now. The rates are not validated, only a malformed file results in an error
+func DecodeRates(r io.Reader, format string) ([]Rate, error) {
+	switch format {
+	case ratesFormatCSV:
+		return decodeRatesCSV(r)
+	case ratesFormatJSON:
+		return decodeRatesJSON(r)
+	default:
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "unknown rates format %q, expected csv or json", format)
+	}
+}
+
+func decodeRatesCSV(r io.Reader) ([]Rate, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "empty rates file")
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid CSV file")
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, c := range ratesColumns {
+		if _, ok := columns[c]; !ok {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "missing %q column in CSV header", c)
+		}
+	}
+	timeCol, hasTime := columns["time"]
+
+	var rates []Rate
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rates, nil
+		} else if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid CSV file")
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[columns["rate"]]), 64)
+		if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid rate in line %d", line)
+		}
+		row := Rate{
+			Base:  currency.Currency(strings.TrimSpace(rec[columns["base"]])),
+			Quote: currency.Currency(strings.TrimSpace(rec[columns["quote"]])),
+			Rate:  rate,
+		}
+		if hasTime {
+			if row.Time, err = parseRateTime(rec[timeCol]); err != nil {
+				return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid time in line %d", line)
+			}
+		}
+		rates = append(rates, row)
+	}
+}
+
+func decodeRatesJSON(r io.Reader) ([]Rate, error) {
+	br := bufio.NewReader(r)
+	b, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "empty rates file")
+	} else if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(br)
+	var rates []Rate
+	if b == '[' {
+		err = dec.Decode(&rates)
+	} else {
+		var rate Rate
+		err = dec.Decode(&rate)
+		rates = []Rate{rate}
+	}
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid JSON rates")
+	}
+	return rates, nil
+}
+
+// peekNonSpace returns the first non-space byte of the reader without consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// parseRateTime parses an RFC 3339 time or a date, it returns nil for an empty string
+func parseRateTime(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", s); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// ratesFormat returns the rates format from the query or the content type, JSON by default
+func ratesFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		return ratesFormatCSV
+	}
+	return ratesFormatJSON
+}
+
+func decodePostRatesRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	rates, err := DecodeRates(http.MaxBytesReader(nil, r.Body, importMaxBytes), ratesFormat(r))
+	if err != nil {
+		return nil, err
+	}
+	return PostRatesRequest{Rates: rates}, nil
+}
+
+// encodePostRatesRequest sends the rates as a JSON array
+func encodePostRatesRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(PostRatesRequest)
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(r.Rates); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.ContentLength = int64(buf.Len())
+	req.Body = ioutil.NopCloser(&buf)
+	return nil
+}
+
+func decodePostRatesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res PostRatesResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func decodeGetRateRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	q := r.URL.Query()
+	req := GetRateRequest{
+		Base:  q.Get("base"),
+		Quote: q.Get("quote"),
+	}
+	if req.Base == "" || req.Quote == "" {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "base and quote currencies are required")
+	}
+	if at := q.Get("at"); at != "" {
+		if req.At, err = parseRateTime(at); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid rate time %q", at)
+		}
+	}
+	return req, nil
+}
+
+func encodeGetRateRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(GetRateRequest)
+	q := req.URL.Query()
+	q.Set("base", r.Base)
+	q.Set("quote", r.Quote)
+	if r.At != nil {
+		q.Set("at", r.At.Format(time.RFC3339))
+	}
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func decodeRateResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res Rate
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+)
+
+func TestPostRatesEndpoint(t *testing.T) {
+	jan30 := time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+	jan31 := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantCode    int
+		want        []currency.Rate
+	}{
+		{
+			name:     "json rate",
+			path:     "/api/rates",
+			body:     `{"base":"EUR","quote":"USD","rate":1.1,"time":"2024-01-30T00:00:00Z"}`,
+			wantCode: http.StatusOK,
+			want:     []currency.Rate{{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: jan30}},
+		},
+		{
+			name:     "json array",
+			path:     "/api/rates",
+			body:     ` [{"base":"EUR","quote":"USD","rate":1.1,"time":"2024-01-30T00:00:00Z"},{"base":"GBP","quote":"USD","rate":1.27,"time":"2024-01-31T12:00:00Z"}]`,
+			wantCode: http.StatusOK,
+			want: []currency.Rate{
+				{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: jan30},
+				{Base: currency.GBP, Quote: currency.USD, Rate: 1.27, Time: jan31},
+			},
+		},
+		{
+			name:        "csv by content type",
+			path:        "/api/rates",
+			contentType: "text/csv",
+			body:        "\ufeffQuote,Base,Rate,Time\nUSD, EUR ,1.1,2024-01-30\nUSD,GBP,1.27,2024-01-31T12:00:00Z\n",
+			wantCode:    http.StatusOK,
+			want: []currency.Rate{
+				{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: jan30},
+				{Base: currency.GBP, Quote: currency.USD, Rate: 1.27, Time: jan31},
+			},
+		},
+		{
+			name:     "csv by format",
+			path:     "/api/rates?format=csv",
+			body:     "base,quote,rate,time\nEUR,USD,1.1,2024-01-30\n",
+			wantCode: http.StatusOK,
+			want:     []currency.Rate{{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: jan30}},
+		},
+		{
+			name:        "missing column",
+			path:        "/api/rates",
+			contentType: "text/csv",
+			body:        "base,rate\nEUR,1.1\n",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "invalid csv rate",
+			path:        "/api/rates",
+			contentType: "text/csv",
+			body:        "base,quote,rate\nEUR,USD,x\n",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "invalid csv time",
+			path:        "/api/rates",
+			contentType: "text/csv",
+			body:        "base,quote,rate,time\nEUR,USD,1.1,yesterday\n",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:     "malformed json",
+			path:     "/api/rates",
+			body:     `{"base":"EUR",`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "empty body",
+			path:     "/api/rates",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid rate",
+			path:     "/api/rates",
+			body:     `{"base":"EUR","quote":"USD","rate":0}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown format",
+			path:     "/api/rates?format=xml",
+			body:     `<rate/>`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{}
+			h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+			r := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("wrong status code %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if !reflect.DeepEqual(db.Rates, tt.want) {
+				t.Errorf("wrong saved rates %v, want %v", db.Rates, tt.want)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got PostRatesResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Saved != len(tt.want) {
+				t.Errorf("wrong saved count %v, want %v", got.Saved, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGetRateEndpoint(t *testing.T) {
+	db := &TestDatabase{Rates: []currency.Rate{
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)},
+	}}
+	h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     string
+	}{
+		{"rate", "base=EUR&quote=USD&at=2024-01-31", http.StatusOK, `{"base":"EUR","quote":"USD","rate":1.1,"time":"2024-01-30T00:00:00Z"}`},
+		{"rfc 3339 time", "base=EUR&quote=USD&at=2024-01-30T00:00:00Z", http.StatusOK, `{"base":"EUR","quote":"USD","rate":1.1,"time":"2024-01-30T00:00:00Z"}`},
+		{"too early", "base=EUR&quote=USD&at=2024-01-29", http.StatusNotFound, ""},
+		{"missing quote", "base=EUR", http.StatusBadRequest, ""},
+		{"invalid time", "base=EUR&quote=USD&at=yesterday", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/api/rates?"+tt.query, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("wrong status code %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if got := strings.TrimSpace(w.Body.String()); tt.want != "" && got != tt.want {
+				t.Errorf("wrong response %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,15 +3,35 @@ package wallet
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"time"
 
-	"github.com/ilyakaznacheev/tiny-wallet/internal/model"
 	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
 	"golang.org/x/xerrors"
 )
 
+// Payment rejection reasons.
+//
+// They are wrapped into the ErrHTTPStatus returned by the service, so the caller can find out why the payment was declined with xerrors.Is
+var (
+	// ErrAccountNotFound means that the payer or the receiver account doesn't exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrCurrencyMismatch means that the payer and the receiver accounts have different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrInsufficientFunds means that the payer hasn't enough money on the balance
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrDuplicateReference means that the payer already has a payment with the same reference
+	ErrDuplicateReference = errors.New("duplicate payment reference")
+	// ErrIdempotencyKeyReused means that the idempotency key was already used for another payment of the payer
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+	// ErrNoExchangeRate means that there is no exchange rate between the pocket currencies
+	ErrNoExchangeRate = currency.ErrNoRate
+)
+
 // HTTPError is an error with an HTTP status code
 type HTTPError interface {
 	error
@@ -56,36 +76,119 @@ func (e ErrHTTPStatus) Unwrap() error {
 
 // Service is a set of CRUD operations that the backend can process
 type Service interface {
-	GetAllPayments(ctx context.Context) ([]model.Payment, error)
-	GetAllAccounts(ctx context.Context) ([]model.Account, error)
-	PostPayment(ctx context.Context, from, to string, amount float64) (*model.Payment, error)
-	PostAccount(ctx context.Context, id string, balance float64, curr string) (*model.Account, error)
+	GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error)
+	GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error)
+	PostPayment(ctx context.Context, from, to string, amount float64, info model.PaymentInfo) (*model.Payment, error)
+	PostAccount(ctx context.Context, id string, balance float64, curr string, info model.AccountInfo) (*model.Account, error)
+	PatchAccount(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error)
+	ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error
+	ExportAccounts(ctx context.Context, fn func(model.Account) error) error
+	ImportAccounts(ctx context.Context, rows []model.AccountImport, dryRun bool) (*model.ImportResult, error)
+	PostWallet(ctx context.Context, id, ownerID string, currencies []string) (*model.Wallet, error)
+	GetWallet(ctx context.Context, id, totalCurrency string) (*model.Wallet, error)
+	ConvertFunds(ctx context.Context, walletID, from, to string, amount float64) (*model.Payment, error)
+	PostSplitPayment(ctx context.Context, from string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error)
+	GetTrialBalance(ctx context.Context) (*model.TrialBalance, error)
+	GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error)
+	PostRates(ctx context.Context, rates []currency.Rate) (int, error)
+	GetRate(ctx context.Context, base, quote string, at time.Time) (*currency.Rate, error)
 }
 
 // Database is a common interface for a database layer
 type Database interface {
-	GetAllAccounts() ([]model.Account, error)
-	GetAllPayments() ([]model.Payment, error)
-	GetAccount(accountID string) (*model.Account, error)
-	CreatePayment(p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (*model.Payment, error)
-	CreateAccount(a model.Account) (*model.Account, error)
+	GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error)
+	GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error)
+	GetAccount(ctx context.Context, accountID string) (*model.Account, error)
+	GetPaymentByIdempotencyKey(ctx context.Context, accountFromID, key string) (*model.Payment, error)
+	CreatePayment(ctx context.Context, p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (*model.Payment, error)
+	CreatePaymentGroup(ctx context.Context, g model.PaymentGroup, lastChanged map[string]*time.Time) (*model.PaymentGroup, error)
+	CreateAccount(ctx context.Context, a model.Account) (*model.Account, error)
+	ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error
+	ExportAccounts(ctx context.Context, fn func(model.Account) error) error
+	CreateAccounts(ctx context.Context, accounts []model.Account, dryRun bool) (existing []int, err error)
+	UpdateAccountInfo(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error)
+	CreateWallet(ctx context.Context, w model.Wallet) (*model.Wallet, error)
+	GetWallet(ctx context.Context, id string) (*model.Wallet, error)
+	GetTrialBalance(ctx context.Context) ([]model.TrialBalanceAccount, error)
+	GetEndOfDayBalances(ctx context.Context, types []string, end time.Time) ([]model.Account, error)
+	CreateInterestAccruals(ctx context.Context, accruals []model.InterestAccrual) (int, error)
+	PayInterest(ctx context.Context, accountID, expenseAccountID string, upTo time.Time) (*model.Payment, error)
+	GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error)
+	CreateRates(ctx context.Context, rates []currency.Rate) (int, error)
+	GetRate(ctx context.Context, base, quote currency.Currency, at time.Time) (*currency.Rate, error)
+}
+
+// ServiceOption sets an optional parameter of the wallet service
+type ServiceOption func(*WalletService)
+
+// WithBaseCurrency sets a currency of wallet totals by default.
+//
+// Stored exchange rates are triangulated through it if there is no rate between two currencies
+func WithBaseCurrency(c currency.Currency) ServiceOption {
+	return func(s *WalletService) {
+		s.base = c
+	}
+}
+
+// WithAccountTypes sets interest-bearing account types that can be assigned to accounts
+func WithAccountTypes(types []model.AccountType) ServiceOption {
+	return func(s *WalletService) {
+		s.types = make(map[string]model.AccountType, len(types))
+		for _, t := range types {
+			s.types[t.Name] = t
+		}
+	}
+}
+
+// WithRounding sets a rounding mode of payment, account and conversion amounts that have more decimals than the currency allows.
+//
+// The default mode is half to even
+func WithRounding(mode currency.RoundingMode) ServiceOption {
+	return func(s *WalletService) {
+		s.rounding = mode
+	}
 }
 
 // WalletService is a business logic implementation of a Tiny Wallet.
 //
 // It is responsible to process HTTP requests and manipulate the data of accounts and payments between them.
 type WalletService struct {
+	db        Database
+	base      currency.Currency
+	converter *currency.Converter
+	types     map[string]model.AccountType
+	rounding  currency.RoundingMode
+}
+
+// NewWalletService creates a new wallet service with a connection to the database.
+//
+// All conversions use exchange rates stored in the database
+func NewWalletService(db Database, opts ...ServiceOption) Service {
+	s := &WalletService{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.converter = currency.NewConverter(rateSource{db}, s.base)
+	return s
+}
+
+// rateSource is a history of exchange rates stored in the database
+type rateSource struct {
 	db Database
 }
 
-// NewWalletService creates a new wallet service with a connection to the database
-func NewWalletService(db Database) Service {
-	return &WalletService{db}
+// LatestRate returns the last stored base/quote rate set at or before the time
+func (r rateSource) LatestRate(ctx context.Context, base, quote currency.Currency, at time.Time) (*currency.Rate, error) {
+	rate, err := r.db.GetRate(ctx, base, quote, at)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w from %s to %s", currency.ErrNoRate, string(base), string(quote))
+	}
+	return rate, err
 }
 
-// GetAllPayments returns a list of all payments in the system
-func (s *WalletService) GetAllPayments(ctx context.Context) ([]model.Payment, error) {
-	payments, err := s.db.GetAllPayments()
+// GetAllPayments returns a list of payments in the system matching the filter
+func (s *WalletService) GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error) {
+	payments, err := s.db.GetAllPayments(ctx, filter)
 	if err == sql.ErrNoRows {
 		return nil, NewErrHTTPStatusf(http.StatusNotFound, nil, "no payment found")
 	} else if err != nil {
@@ -94,14 +197,45 @@ func (s *WalletService) GetAllPayments(ctx context.Context) ([]model.Payment, er
 	return payments, nil
 }
 
-// GetAllAccounts returns a list of all accounts in the system
-func (s *WalletService) GetAllAccounts(ctx context.Context) ([]model.Account, error) {
-	accounts, err := s.db.GetAllAccounts()
+// GetAllAccounts returns a list of accounts in the system matching the filter.
+//
+// If the filter has a currency to convert into, each balance is also converted with the latest stored exchange rate
+func (s *WalletService) GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error) {
+	var convertTo *currency.Currency
+	if filter.ConvertTo != "" {
+		c, err := currency.AtoAnyCurrency(filter.ConvertTo)
+		if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't convert balances to currency %s", filter.ConvertTo)
+		}
+		convertTo = c
+	}
+
+	accounts, err := s.db.GetAllAccounts(ctx, filter)
 	if err == sql.ErrNoRows {
 		return nil, NewErrHTTPStatusf(http.StatusNotFound, nil, "no account found")
 	} else if err != nil {
 		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
 	}
+	if convertTo == nil {
+		return accounts, nil
+	}
+
+	now := time.Now().UTC()
+	for i := range accounts {
+		a := &accounts[i]
+		rate, err := s.converter.Rate(ctx, a.Currency, *convertTo, now)
+		if err == nil {
+			// converted balances are only a report, so they are rounded to the nearest unit regardless of the service rounding mode
+			a.Converted = &model.ConvertedBalance{Currency: *convertTo, Rate: *rate}
+			a.Converted.Balance, err = s.converter.Convert(ctx, a.Balance, a.Currency, *convertTo, now, currency.RoundHalfEven)
+		}
+		switch {
+		case xerrors.Is(err, currency.ErrNoRate), xerrors.Is(err, currency.ErrOverflow):
+			return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "can't convert account %s balance to %s", a.ID, string(*convertTo))
+		case err != nil:
+			return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+		}
+	}
 	return accounts, nil
 }
 
@@ -114,66 +248,295 @@ func (s *WalletService) GetAllAccounts(ctx context.Context) ([]model.Account, er
 // The method is based on compare-and-swap(https://en.wikipedia.org/wiki/Compare-and-swap) pattern.
 //
 // Thus, the method reads the current state of both payer and receiver accounts. That allows it doesn't hold the database transaction open while the app processes the business logic, which can take a long time. After that, if there is all business checks are good, the application creates a serialized database transaction, that tries to update account state and save the payment. If the account state was changed meanwhile (i.e. another payment had affected any of these accounts), the transaction will fail. The serialized transaction will not allow concurrent process to create a payments during this update without database lock. That gives a good performance and thread-safety.
-func (s *WalletService) PostPayment(ctx context.Context, fromID, toID string, amount float64) (*model.Payment, error) {
-	accFrom, err := s.db.GetAccount(fromID)
+//
+// The payment reference is optional, but if it is set, it should be unique among payments of the payer. Otherwise the payment is declined with 409 Status Code, so the same payment can't be processed twice.
+//
+// If the context has an idempotency key, see `ContextWithIdempotencyKey`, a repeated call with the same key returns the original payment instead of creating a new one.
+// A call that reuses the key for another receiver or amount is declined with 422 Status Code
+func (s *WalletService) PostPayment(ctx context.Context, fromID, toID string, amount float64, info model.PaymentInfo) (*model.Payment, error) {
+	if err := validatePaymentInfo(info); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process payment with invalid payment info")
+	}
+	key := IdempotencyKeyFromContext(ctx)
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process payment with idempotency key longer than %d characters", maxIdempotencyKeyLength)
+	}
+	if fromID == toID {
+		return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, nil, "can't process payment from account %s to itself", fromID)
+	}
+	if !(amount > 0) {
+		return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, nil, "can't process payment with non-positive amount %v", amount)
+	}
+
+	accFrom, err := s.db.GetAccount(ctx, fromID)
 	if err == sql.ErrNoRows {
-		return nil, NewErrHTTPStatusf(http.StatusNotFound, nil, "account %s not found", fromID)
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account %s not found", fromID)
 	} else if err != nil {
 		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
 	}
 
-	accTo, err := s.db.GetAccount(toID)
+	accTo, err := s.db.GetAccount(ctx, toID)
 	if err == sql.ErrNoRows {
-		return nil, NewErrHTTPStatusf(http.StatusNotFound, nil, "account %s not found", toID)
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account %s not found", toID)
 	} else if err != nil {
 		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
 	}
 
 	// check if the payer and the receiver have the same balance currency
 	if accFrom.Currency != accTo.Currency {
-		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "accounts %s and %s have different balance currencies, payment can't be processed", accFrom.ID, accTo.ID)
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, ErrCurrencyMismatch, "accounts %s and %s have different balance currencies, payment can't be processed", accFrom.ID, accTo.ID)
+	}
+
+	intAmount, err := currency.ConvertToInternal(amount, accFrom.Currency, s.rounding)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process payment with amount %v", amount)
+	}
+	if intAmount.Sign() <= 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process payment with amount %v: amount is below the currency's minor unit", amount)
 	}
 
-	intAmount := currency.ConvertToInternal(amount, accFrom.Currency)
+	payment := model.Payment{
+		AccFromID:      fromID,
+		AccToID:        toID,
+		Amount:         intAmount,
+		IdempotencyKey: key,
+		PaymentInfo:    info,
+	}
+	// the original payment is returned even if the balances have changed since then
+	if res, err := s.replayPayment(ctx, payment); res != nil || err != nil {
+		return res, err
+	}
 
 	// check if the payer has enough money on the balance
-	if accFrom.Balance < intAmount {
-		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "account %s has not enough money", accFrom.ID)
+	if accFrom.Balance.Cmp(intAmount) < 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, ErrInsufficientFunds, "account %s has not enough money", accFrom.ID)
+	}
+	if _, err := accTo.Balance.Add(intAmount); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process payment, account %s balance would be out of range", accTo.ID)
 	}
 
-	payment := model.Payment{
-		AccFromID: fromID,
-		AccToID:   toID,
-		Amount:    intAmount,
+	return s.createPayment(ctx, payment, accFrom, accTo)
+}
+
+// replayPayment returns the payment created earlier with the same idempotency key, or nil if the payment has no key or it wasn't used yet
+func (s *WalletService) replayPayment(ctx context.Context, payment model.Payment) (*model.Payment, error) {
+	if payment.IdempotencyKey == "" {
+		return nil, nil
+	}
+	res, err := s.db.GetPaymentByIdempotencyKey(ctx, payment.AccFromID, payment.IdempotencyKey)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
 	}
+	if res.AccToID != payment.AccToID || res.Amount != payment.Amount {
+		return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, ErrIdempotencyKeyReused, "idempotency key %s was already used for another payment of account %s", payment.IdempotencyKey, payment.AccFromID)
+	}
+	return res, nil
+}
 
-	res, err := s.db.CreatePayment(payment, accFrom.LastUpdate, accTo.LastUpdate)
-	if err != nil {
+// createPayment saves the payment between accounts if they weren't changed since they were read
+func (s *WalletService) createPayment(ctx context.Context, payment model.Payment, accFrom, accTo *model.Account) (*model.Payment, error) {
+	payment.Currency = accFrom.Currency
+	res, err := s.db.CreatePayment(ctx, payment, accFrom.LastUpdate, accTo.LastUpdate)
+	if xerrors.Is(err, model.ErrRowExists) {
+		// a concurrent call with the same idempotency key has created the payment first
+		if res, err := s.replayPayment(ctx, payment); res != nil || err != nil {
+			return res, err
+		}
+		return nil, NewErrHTTPStatusf(http.StatusConflict, ErrDuplicateReference, "account %s already has a payment with reference %s", accFrom.ID, payment.Reference)
+	} else if xerrors.Is(err, model.ErrConflict) {
+		return nil, NewErrHTTPStatusf(http.StatusConflict, err, "account %s or %s was changed by a concurrent payment, please retry", accFrom.ID, accTo.ID)
+	} else if err != nil {
 		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "payment processing failed")
 	}
 	res.Currency = accFrom.Currency
 	return res, nil
 }
 
+// Split payment limits
+const (
+	maxSplitReceivers = 100
+	// percentScale is a precision of split payment percentages, 4 decimal places
+	percentScale = 10000
+)
+
+// PostSplitPayment processes a payment from one payer to many receivers.
+//
+// Each receiver gets either a fixed amount or a percentage of the amount left after all fixed shares, the percentages should add up to 100.
+// Without percentage receivers the fixed amounts should add up to the total.
+// The shares are allocated exactly in the lowest currency unit, so they always sum to the total, see `currency.Allocate`.
+//
+// All receivers should have the payer balance currency. The payments are created in one transaction and linked with the group ID, so either every receiver is paid or none
+func (s *WalletService) PostSplitPayment(ctx context.Context, fromID string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (*model.PaymentGroup, error) {
+	if err := validatePaymentInfo(info); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process payment with invalid payment info")
+	}
+	if len(receivers) == 0 || len(receivers) > maxSplitReceivers {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "split payment should have from 1 to %d receivers", maxSplitReceivers)
+	}
+	if !(amount > 0) {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process split payment of non-positive amount %f", amount)
+	}
+
+	accFrom, err := s.db.GetAccount(ctx, fromID)
+	if err == sql.ErrNoRows {
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account %s not found", fromID)
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+
+	lastChanged := map[string]*time.Time{accFrom.ID: accFrom.LastUpdate}
+	for _, r := range receivers {
+		if r.AccountID == accFrom.ID {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "account %s can't receive its own split payment", r.AccountID)
+		}
+		if _, ok := lastChanged[r.AccountID]; ok {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "account %s is a receiver of the split payment more than once", r.AccountID)
+		}
+		accTo, err := s.db.GetAccount(ctx, r.AccountID)
+		if err == sql.ErrNoRows {
+			return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account %s not found", r.AccountID)
+		} else if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+		}
+		if accTo.Currency != accFrom.Currency {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, ErrCurrencyMismatch, "accounts %s and %s have different balance currencies, payment can't be processed", accFrom.ID, accTo.ID)
+		}
+		lastChanged[accTo.ID] = accTo.LastUpdate
+	}
+
+	total, err := currency.ConvertToInternal(amount, accFrom.Currency, s.rounding)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process split payment with amount %v", amount)
+	}
+	if total.Sign() <= 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process split payment with amount %v: amount is below the currency's minor unit", amount)
+	}
+	shares, err := splitShares(total, accFrom.Currency, receivers, s.rounding)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't split payment of %s %s", accFrom.Currency.FormatDecimal(total), string(accFrom.Currency))
+	}
+
+	// check if the payer has enough money on the balance
+	if accFrom.Balance.Cmp(total) < 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, ErrInsufficientFunds, "account %s has not enough money", accFrom.ID)
+	}
+
+	g := model.PaymentGroup{
+		AccFromID:   accFrom.ID,
+		Amount:      total,
+		Currency:    accFrom.Currency,
+		Payments:    make([]model.Payment, 0, len(receivers)),
+		PaymentInfo: info,
+	}
+	for i, r := range receivers {
+		g.Payments = append(g.Payments, model.Payment{
+			AccToID: r.AccountID,
+			Amount:  shares[i],
+		})
+	}
+
+	res, err := s.db.CreatePaymentGroup(ctx, g, lastChanged)
+	if xerrors.Is(err, model.ErrRowExists) {
+		return nil, NewErrHTTPStatusf(http.StatusConflict, ErrDuplicateReference, "account %s already has a split payment with reference %s", accFrom.ID, info.Reference)
+	} else if xerrors.Is(err, model.ErrConflict) {
+		return nil, NewErrHTTPStatusf(http.StatusConflict, err, "account %s or one of the receivers was changed by a concurrent payment, please retry", accFrom.ID)
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "split payment processing failed")
+	}
+	return res, nil
+}
+
+// splitShares calculates amounts of split payment receivers in the lowest currency unit.
+//
+// Fixed amounts are rounded with the rounding mode, the rest of the total is allocated by percentages
+func splitShares(total currency.Amount, c currency.Currency, receivers []model.SplitReceiver, mode currency.RoundingMode) ([]currency.Amount, error) {
+	shares := make([]currency.Amount, len(receivers))
+	ratios := make([]int, len(receivers))
+	left, percents := total, 0
+	for i, r := range receivers {
+		switch {
+		case r.Amount > 0 && r.Percent == 0:
+			share, err := currency.ConvertToInternal(r.Amount, c, mode)
+			if err != nil {
+				return nil, fmt.Errorf("amount %v of receiver %s: %w", r.Amount, r.AccountID, err)
+			}
+			shares[i] = share
+			if left, err = left.Sub(share); err != nil {
+				return nil, errors.New("fixed amounts exceed the total")
+			}
+		case r.Percent > 0 && r.Percent <= 100 && r.Amount == 0:
+			ratio := new(big.Rat).Mul(currency.Rat(r.Percent), big.NewRat(percentScale, 1))
+			if !ratio.IsInt() {
+				return nil, fmt.Errorf("percentage %v of receiver %s has more than 4 decimal places", r.Percent, r.AccountID)
+			}
+			ratios[i] = int(ratio.Num().Int64())
+			percents += ratios[i]
+		default:
+			return nil, fmt.Errorf("receiver %s should have either a positive amount or a percentage from 0 to 100", r.AccountID)
+		}
+	}
+	if left.Sign() < 0 {
+		return nil, errors.New("fixed amounts exceed the total")
+	}
+
+	if percents == 0 {
+		if left.Sign() != 0 {
+			return nil, errors.New("fixed amounts don't add up to the total")
+		}
+	} else {
+		if percents != 100*percentScale {
+			return nil, fmt.Errorf("percentages add up to %v, want 100", float64(percents)/percentScale)
+		}
+		parts, err := currency.Allocate(left, ratios)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range parts {
+			// parts add up to the amount left, so the shares never exceed the total
+			shares[i], _ = shares[i].Add(p)
+		}
+	}
+
+	for i, share := range shares {
+		if share.Sign() <= 0 {
+			return nil, fmt.Errorf("share of receiver %s is less than the lowest currency unit", receivers[i].AccountID)
+		}
+	}
+	return shares, nil
+}
+
 // PostAccount creates a new financial account.
 //
 // If the account already exists, it will return 409 Status Code
-func (s *WalletService) PostAccount(ctx context.Context, id string, balance float64, curr string) (*model.Account, error) {
+func (s *WalletService) PostAccount(ctx context.Context, id string, balance float64, curr string, info model.AccountInfo) (*model.Account, error) {
 	currKey, err := currency.AtoCurrency(curr)
 	if err != nil {
 		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process account creation with currency %s", curr)
 	}
+	if err := validateAccountInfo(info.OwnerID, info.DisplayName, info.Labels, info.Metadata); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process account creation with invalid account info")
+	}
+	if err := s.validateAccountType(info.Type); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process account creation with invalid account type")
+	}
 
 	if balance < 0 {
 		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process account creation with negative balance %f", balance)
 	}
+	intBalance, err := currency.ConvertToInternal(balance, *currKey, s.rounding)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process account creation with balance %v", balance)
+	}
 	a := model.Account{
-		ID:       id,
-		Balance:  currency.ConvertToInternal(balance, *currKey),
-		Currency: *currKey,
+		ID:          id,
+		Balance:     intBalance,
+		Currency:    *currKey,
+		AccountInfo: info,
 	}
 
-	res, err := s.db.CreateAccount(a)
+	res, err := s.db.CreateAccount(ctx, a)
 	if xerrors.Is(err, model.ErrRowExists) {
 		return nil, NewErrHTTPStatusf(http.StatusConflict, nil, "account %s already exists", a.ID)
 	} else if err != nil {
@@ -181,3 +544,455 @@ func (s *WalletService) PostAccount(ctx context.Context, id string, balance floa
 	}
 	return res, nil
 }
+
+// PatchAccount changes the account owner, display name, labels or metadata.
+//
+// Labels and metadata are replaced as a whole
+func (s *WalletService) PatchAccount(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error) {
+	var (
+		owner, name string
+		labels      []string
+		metadata    map[string]string
+	)
+	if patch.OwnerID != nil {
+		owner = *patch.OwnerID
+	}
+	if patch.DisplayName != nil {
+		name = *patch.DisplayName
+	}
+	if patch.Labels != nil {
+		labels = *patch.Labels
+	}
+	if patch.Metadata != nil {
+		metadata = *patch.Metadata
+	}
+	if err := validateAccountInfo(owner, name, labels, metadata); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't update account %s with invalid account info", id)
+	}
+	if patch.Type != nil {
+		if err := s.validateAccountType(*patch.Type); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't update account %s with invalid account type", id)
+		}
+	}
+
+	res, err := s.db.UpdateAccountInfo(ctx, id, patch)
+	if err == sql.ErrNoRows {
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account %s not found", id)
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "account update failed")
+	}
+	return res, nil
+}
+
+// Account and payment info limits
+const (
+	maxOwnerIDLength     = 64
+	maxDisplayNameLength = 255
+	maxLabelLength       = 64
+	maxReferenceLength   = 64
+	maxDescriptionLength = 255
+)
+
+// validateAccountType checks that the account type is configured, an empty type is a plain account
+func (s *WalletService) validateAccountType(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := s.types[name]; !ok {
+		return fmt.Errorf("unknown account type %q", name)
+	}
+	return nil
+}
+
+// validateAccountInfo checks the account info fields against database limits
+func validateAccountInfo(owner, name string, labels []string, metadata map[string]string) error {
+	if len(owner) > maxOwnerIDLength {
+		return fmt.Errorf("owner id is longer than %d characters", maxOwnerIDLength)
+	}
+	if len(name) > maxDisplayNameLength {
+		return fmt.Errorf("display name is longer than %d characters", maxDisplayNameLength)
+	}
+	seen := make(map[string]bool, len(labels))
+	for _, l := range labels {
+		switch {
+		case l == "":
+			return errors.New("empty label")
+		case len(l) > maxLabelLength:
+			return fmt.Errorf("label %q is longer than %d characters", l, maxLabelLength)
+		case seen[l]:
+			return fmt.Errorf("duplicate label %q", l)
+		}
+		seen[l] = true
+	}
+	return validateMetadata(metadata)
+}
+
+// validatePaymentInfo checks the payment info fields against database limits
+func validatePaymentInfo(info model.PaymentInfo) error {
+	if len(info.Reference) > maxReferenceLength {
+		return fmt.Errorf("reference is longer than %d characters", maxReferenceLength)
+	}
+	if len(info.Description) > maxDescriptionLength {
+		return fmt.Errorf("description is longer than %d characters", maxDescriptionLength)
+	}
+	return validateMetadata(info.Metadata)
+}
+
+// validateMetadata checks metadata keys
+func validateMetadata(metadata map[string]string) error {
+	for k := range metadata {
+		if k == "" {
+			return errors.New("empty metadata key")
+		}
+	}
+	return nil
+}
+
+// ExportPayments passes payments to fn one by one in historical order.
+//
+// The payment time is limited by from (inclusive) and to (exclusive), each of them can be nil.
+// Payment times are stored in UTC, so the period is converted into UTC
+func (s *WalletService) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error {
+	if from != nil && to != nil && !from.Before(*to) {
+		return NewErrHTTPStatusf(http.StatusBadRequest, nil, "export period start %s should be before its end %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	if err := s.db.ExportPayments(ctx, utcTime(from), utcTime(to), fn); err != nil {
+		return NewErrHTTPStatusf(http.StatusInternalServerError, err, "payment export failed")
+	}
+	return nil
+}
+
+// utcTime returns the time in UTC or nil for a nil time
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// ExportAccounts passes all accounts to fn one by one ordered by ID
+func (s *WalletService) ExportAccounts(ctx context.Context, fn func(model.Account) error) error {
+	if err := s.db.ExportAccounts(ctx, fn); err != nil {
+		return NewErrHTTPStatusf(http.StatusInternalServerError, err, "account export failed")
+	}
+	return nil
+}
+
+// maxAccountIDLength is a maximum length of an account ID in the database
+const maxAccountIDLength = 30
+
+// ImportAccounts creates accounts in bulk.
+//
+// Each row is validated as in PostAccount. The accounts are created in one transaction only if all rows are valid and none of the accounts exist.
+// Otherwise the result contains errors of all invalid rows. On a dry run the rows are checked, but nothing is created
+func (s *WalletService) ImportAccounts(ctx context.Context, rows []model.AccountImport, dryRun bool) (*model.ImportResult, error) {
+	if len(rows) == 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "no accounts to import")
+	}
+
+	res := &model.ImportResult{DryRun: dryRun}
+	accounts := make([]model.Account, 0, len(rows))
+	firstLine := make(map[string]int, len(rows))
+
+	for _, r := range rows {
+		a, err := parseAccountImport(r)
+		if err == nil {
+			if line, ok := firstLine[r.ID]; ok {
+				err = fmt.Errorf("duplicate account id, first occurrence in line %d", line)
+			}
+		}
+		if err != nil {
+			res.Errors = append(res.Errors, model.ImportError{Line: r.Line, ID: r.ID, Error: err.Error()})
+			continue
+		}
+		firstLine[r.ID] = r.Line
+		accounts = append(accounts, *a)
+	}
+	if len(res.Errors) > 0 {
+		return res, nil
+	}
+
+	existing, err := s.db.CreateAccounts(ctx, accounts, dryRun)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "account import failed")
+	}
+	for _, i := range existing {
+		res.Errors = append(res.Errors, model.ImportError{Line: rows[i].Line, ID: rows[i].ID, Error: "account already exists"})
+	}
+	if len(res.Errors) == 0 {
+		res.Imported = len(accounts)
+	}
+	return res, nil
+}
+
+// parseAccountImport validates the import row and converts it into an account
+func parseAccountImport(r model.AccountImport) (*model.Account, error) {
+	if err := validateNewAccountID(r.ID); err != nil {
+		return nil, err
+	}
+	curr, err := currency.AtoCurrency(r.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if r.Balance == "" {
+		return nil, errors.New("empty balance")
+	}
+	balance, err := curr.ParseDecimal(r.Balance)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Sign() < 0 {
+		return nil, fmt.Errorf("negative balance %s", r.Balance)
+	}
+	return &model.Account{
+		ID:       r.ID,
+		Balance:  balance,
+		Currency: *curr,
+	}, nil
+}
+
+// maxWalletIDLength is a maximum length of a wallet ID, so the pocket account ID `wallet/currency` with an ISO currency fits into the account ID limit.
+// Pockets in custom currencies with longer codes need shorter wallet IDs
+const maxWalletIDLength = maxAccountIDLength - 4
+
+// PostWallet creates a new wallet with an empty pocket in each currency.
+//
+// An invalid wallet id or an empty currency list results in 422 Status Code with the invalid fields.
+// If the wallet or any of its pocket accounts already exists, it will return 409 Status Code
+func (s *WalletService) PostWallet(ctx context.Context, id, ownerID string, currencies []string) (*model.Wallet, error) {
+	if err := validateWallet(id, currencies); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "invalid wallet request")
+	}
+	if err := validateAccountInfo(ownerID, "", nil, nil); err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process wallet creation with invalid owner")
+	}
+
+	w := model.Wallet{
+		ID:      id,
+		OwnerID: ownerID,
+	}
+	seen := make(map[currency.Currency]bool, len(currencies))
+	for _, curr := range currencies {
+		currKey, err := currency.AtoCurrency(curr)
+		if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process wallet creation with currency %s", curr)
+		}
+		if seen[*currKey] {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process wallet creation with duplicate currency %s", curr)
+		}
+		seen[*currKey] = true
+		if pocketID := model.PocketID(id, *currKey); len(pocketID) > maxAccountIDLength {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process wallet creation with pocket id %s longer than %d characters", pocketID, maxAccountIDLength)
+		}
+		w.Pockets = append(w.Pockets, model.Account{
+			ID:       model.PocketID(id, *currKey),
+			Currency: *currKey,
+		})
+	}
+
+	res, err := s.db.CreateWallet(ctx, w)
+	if xerrors.Is(err, model.ErrRowExists) {
+		return nil, NewErrHTTPStatusf(http.StatusConflict, nil, "wallet %s or one of its pocket accounts already exists", id)
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "wallet creation failed")
+	}
+	return s.walletTotal(ctx, res, "")
+}
+
+// GetWallet returns the wallet with its pocket balances and the total balance.
+//
+// The total is converted into totalCurrency, or the base currency of the service if it is empty
+func (s *WalletService) GetWallet(ctx context.Context, id, totalCurrency string) (*model.Wallet, error) {
+	w, err := s.db.GetWallet(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, nil, "wallet %s not found", id)
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	return s.walletTotal(ctx, w, totalCurrency)
+}
+
+// walletTotal calculates the total balance of the wallet pockets in the currency with the current exchange rates
+func (s *WalletService) walletTotal(ctx context.Context, w *model.Wallet, curr string) (*model.Wallet, error) {
+	switch {
+	case curr != "":
+		currKey, err := currency.AtoCurrency(curr)
+		if err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't calculate wallet total in currency %s", curr)
+		}
+		w.TotalCurrency = *currKey
+	case s.base != "":
+		w.TotalCurrency = s.base
+	case len(w.Pockets) > 0:
+		w.TotalCurrency = w.Pockets[0].Currency
+	}
+
+	w.Total = currency.Amount{}
+	now := time.Now().UTC()
+	for _, p := range w.Pockets {
+		// empty pockets don't need an exchange rate
+		if p.Balance.Sign() == 0 {
+			continue
+		}
+		// the total is only a report, so it is rounded to the nearest unit regardless of the service rounding mode
+		amount, err := s.converter.Convert(ctx, p.Balance, p.Currency, w.TotalCurrency, now, currency.RoundHalfEven)
+		switch {
+		case xerrors.Is(err, currency.ErrNoRate), xerrors.Is(err, currency.ErrOverflow):
+			return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "can't calculate wallet %s total in %s", w.ID, string(w.TotalCurrency))
+		case err != nil:
+			return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+		}
+		if w.Total, err = w.Total.Add(amount); err != nil {
+			return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "can't calculate wallet %s total in %s", w.ID, string(w.TotalCurrency))
+		}
+	}
+	return w, nil
+}
+
+// ConvertFunds moves money between two pockets of the wallet.
+//
+// The amount is taken from the pocket in from currency, and the receiving pocket gets it converted with the current stored exchange rate.
+// Like a payment, the conversion is declined with 409 Status Code if one of the pockets was changed meanwhile.
+// Money can be converted from a pocket in a withdrawn currency, but not into it
+func (s *WalletService) ConvertFunds(ctx context.Context, walletID, from, to string, amount float64) (*model.Payment, error) {
+	fromKey, err := currency.AtoAnyCurrency(from)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process conversion from currency %s", from)
+	}
+	toKey, err := currency.AtoCurrency(to)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process conversion to currency %s", to)
+	}
+	if *fromKey == *toKey {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process conversion into the same currency %s", from)
+	}
+	if amount <= 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process conversion of non-positive amount %f", amount)
+	}
+
+	accFrom, err := s.getPocket(ctx, walletID, *fromKey)
+	if err != nil {
+		return nil, err
+	}
+	accTo, err := s.getPocket(ctx, walletID, *toKey)
+	if err != nil {
+		return nil, err
+	}
+
+	intAmount, err := currency.ConvertToInternal(amount, accFrom.Currency, s.rounding)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't process conversion with amount %v", amount)
+	}
+	if intAmount.Sign() <= 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process conversion with amount %v: amount is below the currency's minor unit", amount)
+	}
+	if accFrom.Balance.Cmp(intAmount) < 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, ErrInsufficientFunds, "account %s has not enough money", accFrom.ID)
+	}
+	// the receiving pocket never gets more than the exchange rate gives, the remainder stays on the FX account
+	toAmount, err := s.converter.Convert(ctx, intAmount, accFrom.Currency, accTo.Currency, time.Now().UTC(), currency.RoundDown)
+	switch {
+	case xerrors.Is(err, currency.ErrOverflow):
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't convert %f %s to %s", amount, from, to)
+	case xerrors.Is(err, currency.ErrNoRate):
+		return nil, NewErrHTTPStatusf(http.StatusUnprocessableEntity, err, "can't convert %s to %s", from, to)
+	case err != nil:
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	if toAmount.Sign() <= 0 {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "amount %f %s is too small to convert to %s", amount, from, to)
+	}
+
+	payment := model.Payment{
+		AccFromID:  accFrom.ID,
+		AccToID:    accTo.ID,
+		Amount:     intAmount,
+		ToAmount:   toAmount,
+		ToCurrency: accTo.Currency,
+	}
+	return s.createPayment(ctx, payment, accFrom, accTo)
+}
+
+// getPocket returns the wallet pocket account in the currency
+func (s *WalletService) getPocket(ctx context.Context, walletID string, c currency.Currency) (*model.Account, error) {
+	a, err := s.db.GetAccount(ctx, model.PocketID(walletID, c))
+	if err == sql.ErrNoRows {
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "wallet %s has no %s pocket", walletID, string(c))
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	return a, nil
+}
+
+// GetTrialBalance returns sums of postings of all ledger accounts and checks that the ledger is balanced
+func (s *WalletService) GetTrialBalance(ctx context.Context) (*model.TrialBalance, error) {
+	accounts, err := s.db.GetTrialBalance(ctx)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	tb := model.NewTrialBalance(accounts)
+	return &tb, nil
+}
+
+// GetInterestAccruals returns daily interest accrued on the account in historical order
+func (s *WalletService) GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error) {
+	if _, err := s.db.GetAccount(ctx, accountID); err == sql.ErrNoRows {
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account %s not found", accountID)
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	accruals, err := s.db.GetInterestAccruals(ctx, accountID)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	return accruals, nil
+}
+
+// PostRates saves historical exchange rates.
+//
+// Rates without a time are set now. A rate of the currency pair for the same time is replaced. Returns a number of saved rates
+func (s *WalletService) PostRates(ctx context.Context, rates []currency.Rate) (int, error) {
+	if len(rates) == 0 {
+		return 0, NewErrHTTPStatusf(http.StatusBadRequest, nil, "no exchange rates to save")
+	}
+	now := time.Now().UTC()
+	for i := range rates {
+		r := &rates[i]
+		if err := r.Validate(); err != nil {
+			return 0, NewErrHTTPStatusf(http.StatusBadRequest, err, "can't save exchange rate #%d: %v", i+1, err)
+		}
+		if r.Time.IsZero() {
+			r.Time = now
+		}
+	}
+	created, err := s.db.CreateRates(ctx, rates)
+	if err != nil {
+		return 0, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	return created, nil
+}
+
+// GetRate returns an exchange rate from base to quote currency at the time, or now if the time is zero.
+//
+// The rate is the latest stored one set at or before the time. It is derived from an inverse rate or through the base currency of the service if there is no direct one
+func (s *WalletService) GetRate(ctx context.Context, base, quote string, at time.Time) (*currency.Rate, error) {
+	baseKey, err := currency.AtoAnyCurrency(base)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid base currency %s", base)
+	}
+	quoteKey, err := currency.AtoAnyCurrency(quote)
+	if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid quote currency %s", quote)
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	rate, err := s.converter.Rate(ctx, *baseKey, *quoteKey, at)
+	if xerrors.Is(err, currency.ErrNoRate) {
+		return nil, NewErrHTTPStatusf(http.StatusNotFound, ErrNoExchangeRate, "no %s/%s exchange rate at %s", base, quote, at.Format(time.RFC3339))
+	} else if err != nil {
+		return nil, NewErrHTTPStatusf(http.StatusInternalServerError, err, "unexpected error")
+	}
+	return rate, nil
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/ilyakaznacheev/tiny-wallet/internal/model"
+	"github.com/go-kit/kit/log"
 	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
 )
 
 var testDatabaseErr = errors.New("test error")
@@ -24,29 +30,191 @@ type TestDatabase struct {
 	GetAccountData     map[string]testDatabaseData
 	CreatePaymentData  testDatabaseData
 	CreateAccountData  testDatabaseData
+	ExportPaymentsData testDatabaseData
+	ExportAccountsData testDatabaseData
+	CreateAccountsData testDatabaseData
+	UpdateAccountData  testDatabaseData
+	CreateWalletData   testDatabaseData
+	GetWalletData      testDatabaseData
+	TrialBalanceData   testDatabaseData
+	EndOfDayData       testDatabaseData
+	AccrualsData       testDatabaseData
+	PayInterestData    map[string]testDatabaseData
+	PaymentGroupErr    error
+	// KeyPayments are results of consecutive GetPaymentByIdempotencyKey calls, nil means that there is no payment with the key.
+	// After them, the created payment is found by its key
+	KeyPayments []*model.Payment
+	// Rates are stored exchange rates, CreateRates appends to them
+	Rates    []currency.Rate
+	RatesErr error
+	// accruals are accruals passed to CreateInterestAccruals
+	accruals []model.InterestAccrual
+	// payers are expense accounts passed to PayInterest by receiver
+	payers map[string]string
+	// created are accounts passed to CreateAccounts
+	created []model.Account
+	// payment is a payment passed to CreatePayment, payments is a number of CreatePayment calls
+	payment  model.Payment
+	payments int
+	// group is a split payment passed to CreatePaymentGroup
+	group model.PaymentGroup
+	// lastChanged are account change times passed to CreatePaymentGroup
+	lastChanged map[string]*time.Time
+	// filter is a filter passed to GetAllAccounts
+	filter model.AccountFilter
+	// exportFrom and exportTo are a period passed to ExportPayments
+	exportFrom, exportTo *time.Time
 }
 
-func (db *TestDatabase) GetAllAccounts() ([]model.Account, error) {
+func (db *TestDatabase) GetAllAccounts(ctx context.Context, filter model.AccountFilter) ([]model.Account, error) {
+	db.filter = filter
 	return db.GetAllAccountsData.dat.([]model.Account), db.GetAllAccountsData.err
 }
 
-func (db *TestDatabase) GetAllPayments() ([]model.Payment, error) {
+func (db *TestDatabase) GetAllPayments(ctx context.Context, filter model.PaymentFilter) ([]model.Payment, error) {
 	return db.GetAllPaymentsData.dat.([]model.Payment), db.GetAllPaymentsData.err
 }
 
-func (db *TestDatabase) GetAccount(accountID string) (*model.Account, error) {
+func (db *TestDatabase) GetAccount(ctx context.Context, accountID string) (*model.Account, error) {
 	testData := db.GetAccountData[accountID]
 	return testData.dat.(*model.Account), testData.err
 }
 
-func (db *TestDatabase) CreatePayment(p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (*model.Payment, error) {
+func (db *TestDatabase) GetPaymentByIdempotencyKey(ctx context.Context, accountFromID, key string) (*model.Payment, error) {
+	if len(db.KeyPayments) == 0 {
+		if db.payments > 0 && db.payment.AccFromID == accountFromID && db.payment.IdempotencyKey == key {
+			p := db.payment
+			return &p, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+	p := db.KeyPayments[0]
+	db.KeyPayments = db.KeyPayments[1:]
+	if p == nil {
+		return nil, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (db *TestDatabase) CreatePayment(ctx context.Context, p model.Payment, lastChangedFrom, lastChangedTo *time.Time) (*model.Payment, error) {
+	db.payment = p
+	db.payments++
 	return db.CreatePaymentData.dat.(*model.Payment), db.CreatePaymentData.err
 }
 
-func (db *TestDatabase) CreateAccount(a model.Account) (*model.Account, error) {
+func (db *TestDatabase) CreatePaymentGroup(ctx context.Context, g model.PaymentGroup, lastChanged map[string]*time.Time) (*model.PaymentGroup, error) {
+	db.group, db.lastChanged = g, lastChanged
+	if db.PaymentGroupErr != nil {
+		return nil, db.PaymentGroupErr
+	}
+	g.ID = 1
+	return &g, nil
+}
+
+func (db *TestDatabase) CreateAccount(ctx context.Context, a model.Account) (*model.Account, error) {
 	return db.CreateAccountData.dat.(*model.Account), db.CreateAccountData.err
 }
 
+func (db *TestDatabase) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) error {
+	db.exportFrom, db.exportTo = from, to
+	if db.ExportPaymentsData.dat != nil {
+		for _, p := range db.ExportPaymentsData.dat.([]model.Payment) {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+	}
+	return db.ExportPaymentsData.err
+}
+
+func (db *TestDatabase) ExportAccounts(ctx context.Context, fn func(model.Account) error) error {
+	if db.ExportAccountsData.dat != nil {
+		for _, a := range db.ExportAccountsData.dat.([]model.Account) {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+	}
+	return db.ExportAccountsData.err
+}
+
+func (db *TestDatabase) CreateAccounts(ctx context.Context, accounts []model.Account, dryRun bool) ([]int, error) {
+	db.created = accounts
+	existing, _ := db.CreateAccountsData.dat.([]int)
+	return existing, db.CreateAccountsData.err
+}
+
+func (db *TestDatabase) UpdateAccountInfo(ctx context.Context, id string, patch model.AccountPatch) (*model.Account, error) {
+	a, _ := db.UpdateAccountData.dat.(*model.Account)
+	return a, db.UpdateAccountData.err
+}
+
+func (db *TestDatabase) CreateWallet(ctx context.Context, w model.Wallet) (*model.Wallet, error) {
+	if db.CreateWalletData.err != nil {
+		return nil, db.CreateWalletData.err
+	}
+	return &w, nil
+}
+
+func (db *TestDatabase) GetWallet(ctx context.Context, id string) (*model.Wallet, error) {
+	w, _ := db.GetWalletData.dat.(*model.Wallet)
+	return w, db.GetWalletData.err
+}
+
+func (db *TestDatabase) GetTrialBalance(ctx context.Context) ([]model.TrialBalanceAccount, error) {
+	accounts, _ := db.TrialBalanceData.dat.([]model.TrialBalanceAccount)
+	return accounts, db.TrialBalanceData.err
+}
+
+func (db *TestDatabase) GetEndOfDayBalances(ctx context.Context, types []string, end time.Time) ([]model.Account, error) {
+	accounts, _ := db.EndOfDayData.dat.([]model.Account)
+	return accounts, db.EndOfDayData.err
+}
+
+func (db *TestDatabase) CreateInterestAccruals(ctx context.Context, accruals []model.InterestAccrual) (int, error) {
+	db.accruals = append(db.accruals, accruals...)
+	return len(accruals), nil
+}
+
+func (db *TestDatabase) PayInterest(ctx context.Context, accountID, expenseAccountID string, upTo time.Time) (*model.Payment, error) {
+	if db.payers == nil {
+		db.payers = make(map[string]string)
+	}
+	db.payers[accountID] = expenseAccountID
+	testData := db.PayInterestData[accountID]
+	p, _ := testData.dat.(*model.Payment)
+	return p, testData.err
+}
+
+func (db *TestDatabase) GetInterestAccruals(ctx context.Context, accountID string) ([]model.InterestAccrual, error) {
+	accruals, _ := db.AccrualsData.dat.([]model.InterestAccrual)
+	return accruals, db.AccrualsData.err
+}
+
+func (db *TestDatabase) CreateRates(ctx context.Context, rates []currency.Rate) (int, error) {
+	if db.RatesErr != nil {
+		return 0, db.RatesErr
+	}
+	db.Rates = append(db.Rates, rates...)
+	return len(rates), nil
+}
+
+func (db *TestDatabase) GetRate(ctx context.Context, base, quote currency.Currency, at time.Time) (*currency.Rate, error) {
+	if db.RatesErr != nil {
+		return nil, db.RatesErr
+	}
+	var res *currency.Rate
+	for i, r := range db.Rates {
+		if r.Base == base && r.Quote == quote && !r.Time.After(at) && (res == nil || r.Time.After(res.Time)) {
+			res = &db.Rates[i]
+		}
+	}
+	if res == nil {
+		return nil, sql.ErrNoRows
+	}
+	return res, nil
+}
+
 func TestServiceGetAllPayments(t *testing.T) {
 	now := time.Now()
 	tests := []struct {
@@ -65,7 +233,7 @@ func TestServiceGetAllPayments(t *testing.T) {
 							AccFromID: "1",
 							AccToID:   "2",
 							DateTime:  now,
-							Amount:    12345,
+							Amount:    currency.NewAmount(12345),
 							Currency:  currency.USD,
 						},
 						model.Payment{
@@ -73,7 +241,7 @@ func TestServiceGetAllPayments(t *testing.T) {
 							AccFromID: "2",
 							AccToID:   "3",
 							DateTime:  now,
-							Amount:    456,
+							Amount:    currency.NewAmount(456),
 							Currency:  currency.USD,
 						},
 					},
@@ -86,7 +254,7 @@ func TestServiceGetAllPayments(t *testing.T) {
 					AccFromID: "1",
 					AccToID:   "2",
 					DateTime:  now,
-					Amount:    12345,
+					Amount:    currency.NewAmount(12345),
 					Currency:  currency.USD,
 				},
 				model.Payment{
@@ -94,7 +262,7 @@ func TestServiceGetAllPayments(t *testing.T) {
 					AccFromID: "2",
 					AccToID:   "3",
 					DateTime:  now,
-					Amount:    456,
+					Amount:    currency.NewAmount(456),
 					Currency:  currency.USD,
 				},
 			},
@@ -130,7 +298,7 @@ func TestServiceGetAllPayments(t *testing.T) {
 			s := &WalletService{
 				db: tt.db,
 			}
-			got, err := s.GetAllPayments(context.Background())
+			got, err := s.GetAllPayments(context.Background(), model.PaymentFilter{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("wrong error state %v, wantErr %v", err, tt.wantErr)
 				return
@@ -158,13 +326,13 @@ func TestServiceGetAllAccounts(t *testing.T) {
 						model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    12345,
+							Balance:    currency.NewAmount(12345),
 							Currency:   currency.USD,
 						},
 						model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    67890,
+							Balance:    currency.NewAmount(67890),
 							Currency:   currency.USD,
 						},
 					},
@@ -175,13 +343,13 @@ func TestServiceGetAllAccounts(t *testing.T) {
 				model.Account{
 					ID:         "1",
 					LastUpdate: &now,
-					Balance:    12345,
+					Balance:    currency.NewAmount(12345),
 					Currency:   currency.USD,
 				},
 				model.Account{
 					ID:         "2",
 					LastUpdate: &now,
-					Balance:    67890,
+					Balance:    currency.NewAmount(67890),
 					Currency:   currency.USD,
 				},
 			},
@@ -217,7 +385,7 @@ func TestServiceGetAllAccounts(t *testing.T) {
 			s := &WalletService{
 				db: tt.db,
 			}
-			got, err := s.GetAllAccounts(context.Background())
+			got, err := s.GetAllAccounts(context.Background(), model.AccountFilter{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("wrong error state %v, wantErr %v", err, tt.wantErr)
 				return
@@ -235,6 +403,7 @@ func TestServicePostPayment(t *testing.T) {
 		fromID string
 		toID   string
 		amount float64
+		info   model.PaymentInfo
 	}
 	tests := []struct {
 		name    string
@@ -256,7 +425,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    12345,
+							Balance:    currency.NewAmount(12345),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -265,7 +434,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    67890,
+							Balance:    currency.NewAmount(67890),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -277,7 +446,7 @@ func TestServicePostPayment(t *testing.T) {
 						AccFromID: "1",
 						AccToID:   "2",
 						DateTime:  now,
-						Amount:    12345,
+						Amount:    currency.NewAmount(12345),
 						Currency:  currency.USD,
 					},
 					err: nil,
@@ -288,7 +457,7 @@ func TestServicePostPayment(t *testing.T) {
 				AccFromID: "1",
 				AccToID:   "2",
 				DateTime:  now,
-				Amount:    12345,
+				Amount:    currency.NewAmount(12345),
 				Currency:  currency.USD,
 			},
 			wantErr: false,
@@ -311,7 +480,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    67890,
+							Balance:    currency.NewAmount(67890),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -323,7 +492,7 @@ func TestServicePostPayment(t *testing.T) {
 						AccFromID: "1",
 						AccToID:   "2",
 						DateTime:  now,
-						Amount:    12345,
+						Amount:    currency.NewAmount(12345),
 						Currency:  currency.USD,
 					},
 					err: nil,
@@ -346,7 +515,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    12345,
+							Balance:    currency.NewAmount(12345),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -362,7 +531,7 @@ func TestServicePostPayment(t *testing.T) {
 						AccFromID: "1",
 						AccToID:   "2",
 						DateTime:  now,
-						Amount:    12345,
+						Amount:    currency.NewAmount(12345),
 						Currency:  currency.USD,
 					},
 					err: nil,
@@ -389,7 +558,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    67890,
+							Balance:    currency.NewAmount(67890),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -401,7 +570,7 @@ func TestServicePostPayment(t *testing.T) {
 						AccFromID: "1",
 						AccToID:   "2",
 						DateTime:  now,
-						Amount:    12345,
+						Amount:    currency.NewAmount(12345),
 						Currency:  currency.USD,
 					},
 					err: nil,
@@ -424,7 +593,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    12345,
+							Balance:    currency.NewAmount(12345),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -440,7 +609,7 @@ func TestServicePostPayment(t *testing.T) {
 						AccFromID: "1",
 						AccToID:   "2",
 						DateTime:  now,
-						Amount:    12345,
+						Amount:    currency.NewAmount(12345),
 						Currency:  currency.USD,
 					},
 					err: nil,
@@ -463,7 +632,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    12345,
+							Balance:    currency.NewAmount(12345),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -472,7 +641,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    67890,
+							Balance:    currency.NewAmount(67890),
 							Currency:   currency.CAD,
 						},
 						err: nil,
@@ -500,7 +669,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    123,
+							Balance:    currency.NewAmount(123),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -509,7 +678,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    567,
+							Balance:    currency.NewAmount(567),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -537,7 +706,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "1",
 							LastUpdate: &now,
-							Balance:    12345,
+							Balance:    currency.NewAmount(12345),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -546,7 +715,7 @@ func TestServicePostPayment(t *testing.T) {
 						dat: &model.Account{
 							ID:         "2",
 							LastUpdate: &now,
-							Balance:    67890,
+							Balance:    currency.NewAmount(67890),
 							Currency:   currency.USD,
 						},
 						err: nil,
@@ -560,13 +729,101 @@ func TestServicePostPayment(t *testing.T) {
 			want:    &model.Payment{},
 			wantErr: true,
 		},
+
+		{
+			name: "conflict error",
+			args: args{
+				fromID: "1",
+				toID:   "2",
+				amount: 123,
+			},
+			db: &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{
+					"1": testDatabaseData{
+						dat: &model.Account{
+							ID:         "1",
+							LastUpdate: &now,
+							Balance:    currency.NewAmount(12345),
+							Currency:   currency.USD,
+						},
+						err: nil,
+					},
+					"2": testDatabaseData{
+						dat: &model.Account{
+							ID:         "2",
+							LastUpdate: &now,
+							Balance:    currency.NewAmount(67890),
+							Currency:   currency.USD,
+						},
+						err: nil,
+					},
+				},
+				CreatePaymentData: testDatabaseData{
+					dat: &model.Payment{},
+					err: model.ErrConflict,
+				},
+			},
+			want:    &model.Payment{},
+			wantErr: true,
+		},
+
+		{
+			name: "duplicate reference",
+			args: args{
+				fromID: "1",
+				toID:   "2",
+				amount: 123,
+				info:   model.PaymentInfo{Reference: "inv-1"},
+			},
+			db: &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{
+					"1": testDatabaseData{
+						dat: &model.Account{
+							ID:         "1",
+							LastUpdate: &now,
+							Balance:    currency.NewAmount(12345),
+							Currency:   currency.USD,
+						},
+						err: nil,
+					},
+					"2": testDatabaseData{
+						dat: &model.Account{
+							ID:         "2",
+							LastUpdate: &now,
+							Balance:    currency.NewAmount(67890),
+							Currency:   currency.USD,
+						},
+						err: nil,
+					},
+				},
+				CreatePaymentData: testDatabaseData{
+					dat: &model.Payment{},
+					err: model.ErrRowExists,
+				},
+			},
+			want:    &model.Payment{},
+			wantErr: true,
+		},
+
+		{
+			name: "too long reference",
+			args: args{
+				fromID: "1",
+				toID:   "2",
+				amount: 123,
+				info:   model.PaymentInfo{Reference: strings.Repeat("r", 65)},
+			},
+			db:      &TestDatabase{},
+			want:    &model.Payment{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &WalletService{
 				db: tt.db,
 			}
-			got, err := s.PostPayment(context.Background(), tt.args.fromID, tt.args.toID, tt.args.amount)
+			got, err := s.PostPayment(context.Background(), tt.args.fromID, tt.args.toID, tt.args.amount, tt.args.info)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("wrong error state %v, wantErr %v", err, tt.wantErr)
 				return
@@ -578,12 +835,313 @@ func TestServicePostPayment(t *testing.T) {
 	}
 }
 
+func TestServicePostPaymentRounding(t *testing.T) {
+	now := time.Now()
+	accounts := map[string]testDatabaseData{
+		"1": {dat: &model.Account{ID: "1", LastUpdate: &now, Balance: currency.NewAmount(12345), Currency: currency.USD}},
+		"2": {dat: &model.Account{ID: "2", LastUpdate: &now, Balance: currency.NewAmount(67890), Currency: currency.USD}},
+	}
+	tests := []struct {
+		name   string
+		mode   currency.RoundingMode
+		amount float64
+		want   int64
+	}{
+		{"exact", currency.RoundDown, 0.29, 29},
+		{"half even", currency.RoundHalfEven, 0.295, 30},
+		{"half even to even", currency.RoundHalfEven, 0.285, 28},
+		{"half up", currency.RoundHalfUp, 0.285, 29},
+		{"down", currency.RoundDown, 0.299, 29},
+		{"up", currency.RoundUp, 0.291, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData:    accounts,
+				CreatePaymentData: testDatabaseData{dat: &model.Payment{}},
+			}
+			s := NewWalletService(db, WithRounding(tt.mode))
+			if _, err := s.PostPayment(context.Background(), "1", "2", tt.amount, model.PaymentInfo{}); err != nil {
+				t.Fatal(err)
+			}
+			if db.payment.Amount != currency.NewAmount(tt.want) {
+				t.Errorf("wrong amount %v, want %v", db.payment.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestServicePostPaymentBelowMinorUnit(t *testing.T) {
+	now := time.Now()
+	accounts := map[string]testDatabaseData{
+		"1":    {dat: &model.Account{ID: "1", LastUpdate: &now, Balance: currency.NewAmount(12345), Currency: currency.USD}},
+		"2":    {dat: &model.Account{ID: "2", LastUpdate: &now, Balance: currency.NewAmount(67890), Currency: currency.USD}},
+		"yen1": {dat: &model.Account{ID: "yen1", LastUpdate: &now, Balance: currency.NewAmount(100), Currency: currency.JPY}},
+		"yen2": {dat: &model.Account{ID: "yen2", LastUpdate: &now, Balance: currency.NewAmount(100), Currency: currency.JPY}},
+	}
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		mode     currency.RoundingMode
+		amount   float64
+		wantCode int
+	}{
+		{"tenth of a cent", "1", "2", currency.RoundHalfEven, 0.001, http.StatusBadRequest},
+		{"rounded down", "1", "2", currency.RoundDown, 0.009, http.StatusBadRequest},
+		{"rounded up", "1", "2", currency.RoundUp, 0.001, http.StatusOK},
+		{"zero decimals", "yen1", "yen2", currency.RoundHalfEven, 0.4, http.StatusBadRequest},
+		{"lowest unit", "1", "2", currency.RoundHalfEven, 0.01, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData:    accounts,
+				CreatePaymentData: testDatabaseData{dat: &model.Payment{}},
+			}
+			s := NewWalletService(db, WithRounding(tt.mode))
+			_, err := s.PostPayment(context.Background(), tt.from, tt.to, tt.amount, model.PaymentInfo{})
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil && db.payments != 0 {
+				t.Errorf("wrong number of created payments %v, want 0", db.payments)
+			}
+		})
+	}
+}
+
+func TestServicePostPaymentOverflow(t *testing.T) {
+	now := time.Now()
+	full, err := currency.MaxSafeAmount.Sub(currency.NewAmount(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := map[string]testDatabaseData{
+		"rich":  {dat: &model.Account{ID: "rich", LastUpdate: &now, Balance: currency.MaxSafeAmount, Currency: currency.VND}},
+		"full":  {dat: &model.Account{ID: "full", LastUpdate: &now, Balance: full, Currency: currency.VND}},
+		"empty": {dat: &model.Account{ID: "empty", LastUpdate: &now, Balance: currency.NewAmount(0), Currency: currency.VND}},
+	}
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		amount   float64
+		wantCode int
+	}{
+		{"large amount", "rich", "empty", 1e37, http.StatusOK},
+		{"amount out of range", "rich", "empty", 1e38, http.StatusBadRequest},
+		{"amount above 128 bits", "rich", "empty", 1e39, http.StatusBadRequest},
+		{"infinite amount", "rich", "empty", math.Inf(1), http.StatusBadRequest},
+		{"receiver balance out of range", "rich", "full", 11, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData:    accounts,
+				CreatePaymentData: testDatabaseData{dat: &model.Payment{}},
+			}
+			s := NewWalletService(db)
+			_, err := s.PostPayment(context.Background(), tt.from, tt.to, tt.amount, model.PaymentInfo{})
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil && !xerrors.Is(err, currency.ErrOverflow) {
+				t.Errorf("wrong error %v, want %v", err, currency.ErrOverflow)
+			}
+		})
+	}
+}
+
+func TestServicePostPaymentIdempotencyKey(t *testing.T) {
+	now := time.Now()
+	original := &model.Payment{ID: 7, AccFromID: "alice", AccToID: "bob", Amount: currency.NewAmount(1050), Currency: currency.USD, IdempotencyKey: "key-1"}
+	tests := []struct {
+		name        string
+		key         string
+		to          string
+		amount      float64
+		balance     int64
+		keyPayments []*model.Payment
+		createErr   error
+		wantCode    int
+		wantID      int
+		wantCreated bool
+	}{
+		{"no key", "", "bob", 10.5, 5000, nil, nil, http.StatusOK, 1, true},
+		{"new key", "key-1", "bob", 10.5, 5000, nil, nil, http.StatusOK, 1, true},
+		// the payer has not enough money for another payment, but the original one is returned
+		{"repeated key", "key-1", "bob", 10.5, 0, []*model.Payment{original}, nil, http.StatusOK, 7, false},
+		{"key of another payment", "key-1", "carol", 10.5, 5000, []*model.Payment{original}, nil, http.StatusUnprocessableEntity, 0, false},
+		{"key of another amount", "key-1", "bob", 20, 5000, []*model.Payment{original}, nil, http.StatusUnprocessableEntity, 0, false},
+		{"concurrent call with the key", "key-1", "bob", 10.5, 5000, []*model.Payment{nil, original}, model.ErrRowExists, http.StatusOK, 7, true},
+		{"duplicate reference", "", "bob", 10.5, 5000, nil, model.ErrRowExists, http.StatusConflict, 0, true},
+		{"long key", strings.Repeat("k", 65), "bob", 10.5, 5000, nil, nil, http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{
+					"alice": {dat: &model.Account{ID: "alice", LastUpdate: &now, Balance: currency.NewAmount(tt.balance), Currency: currency.USD}},
+					"bob":   {dat: &model.Account{ID: "bob", LastUpdate: &now, Currency: currency.USD}},
+					"carol": {dat: &model.Account{ID: "carol", LastUpdate: &now, Currency: currency.USD}},
+				},
+				CreatePaymentData: testDatabaseData{dat: &model.Payment{ID: 1}, err: tt.createErr},
+				KeyPayments:       tt.keyPayments,
+			}
+			s := NewWalletService(db)
+
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = ContextWithIdempotencyKey(ctx, tt.key)
+			}
+			got, err := s.PostPayment(ctx, "alice", tt.to, tt.amount, model.PaymentInfo{})
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if tt.wantCode == http.StatusUnprocessableEntity && !xerrors.Is(err, ErrIdempotencyKeyReused) {
+				t.Errorf("wrong error %v, want %v", err, ErrIdempotencyKeyReused)
+			}
+			if created := db.payment.AccFromID != ""; created != tt.wantCreated {
+				t.Errorf("wrong payment creation %v, want %v", created, tt.wantCreated)
+			}
+			if tt.wantCreated && db.payment.IdempotencyKey != tt.key {
+				t.Errorf("wrong saved key %q, want %q", db.payment.IdempotencyKey, tt.key)
+			}
+			if err == nil && got.ID != tt.wantID {
+				t.Errorf("wrong payment id %v, want %v", got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestPostPaymentIdempotencyKeyHeader(t *testing.T) {
+	now := time.Now()
+	db := &TestDatabase{
+		GetAccountData: map[string]testDatabaseData{
+			"alice": {dat: &model.Account{ID: "alice", LastUpdate: &now, Balance: currency.NewAmount(5000), Currency: currency.USD}},
+			"bob":   {dat: &model.Account{ID: "bob", LastUpdate: &now, Currency: currency.USD}},
+		},
+		CreatePaymentData: testDatabaseData{dat: &model.Payment{ID: 1}},
+	}
+	h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("POST", "/api/payment", strings.NewReader(`{"account-from":"alice","account-to":"bob","amount":10.5}`))
+		r.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("wrong status code %v, want %v: %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+	if db.payments != 1 {
+		t.Errorf("wrong number of created payments %v, want %v", db.payments, 1)
+	}
+	if db.payment.IdempotencyKey != "key-1" {
+		t.Errorf("wrong saved key %q, want %q", db.payment.IdempotencyKey, "key-1")
+	}
+}
+
+func TestServicePostSplitPayment(t *testing.T) {
+	now := time.Now()
+	accounts := map[string]testDatabaseData{
+		"buyer":    {dat: &model.Account{ID: "buyer", LastUpdate: &now, Balance: currency.NewAmount(20000), Currency: currency.USD}},
+		"seller1":  {dat: &model.Account{ID: "seller1", Balance: currency.NewAmount(0), Currency: currency.USD}},
+		"seller2":  {dat: &model.Account{ID: "seller2", Balance: currency.NewAmount(0), Currency: currency.USD}},
+		"platform": {dat: &model.Account{ID: "platform", Balance: currency.NewAmount(0), Currency: currency.USD}},
+		"euro":     {dat: &model.Account{ID: "euro", Balance: currency.NewAmount(0), Currency: currency.EUR}},
+		"missing":  {dat: (*model.Account)(nil), err: sql.ErrNoRows},
+	}
+	fixed := func(id string, amount float64) model.SplitReceiver {
+		return model.SplitReceiver{AccountID: id, Amount: amount}
+	}
+	percent := func(id string, p float64) model.SplitReceiver {
+		return model.SplitReceiver{AccountID: id, Percent: p}
+	}
+	tests := []struct {
+		name      string
+		amount    float64
+		receivers []model.SplitReceiver
+		dbErr     error
+		want      []int64
+		wantCode  int
+		wantIs    error
+	}{
+		{"even percentages", 100, []model.SplitReceiver{
+			percent("seller1", 33.3333), percent("seller2", 33.3333), percent("platform", 33.3334),
+		}, nil, []int64{3333, 3333, 3334}, http.StatusOK, nil},
+		{"fixed and commission", 100, []model.SplitReceiver{
+			fixed("seller1", 60), fixed("seller2", 30.5), percent("platform", 100),
+		}, nil, []int64{6000, 3050, 950}, http.StatusOK, nil},
+		{"fixed only", 10, []model.SplitReceiver{
+			fixed("seller1", 2.5), fixed("seller2", 7.5),
+		}, nil, []int64{250, 750}, http.StatusOK, nil},
+		{"leftover cent", 0.1, []model.SplitReceiver{
+			percent("seller1", 50), percent("seller2", 25), percent("platform", 25),
+		}, nil, []int64{5, 3, 2}, http.StatusOK, nil},
+		{"fixed don't add up", 10, []model.SplitReceiver{fixed("seller1", 2.5)}, nil, nil, http.StatusBadRequest, nil},
+		{"fixed exceed total", 10, []model.SplitReceiver{fixed("seller1", 8), fixed("seller2", 8)}, nil, nil, http.StatusBadRequest, nil},
+		{"percentages not 100", 10, []model.SplitReceiver{percent("seller1", 50), percent("seller2", 40)}, nil, nil, http.StatusBadRequest, nil},
+		{"too precise percentage", 10, []model.SplitReceiver{percent("seller1", 33.33333), percent("seller2", 66.66667)}, nil, nil, http.StatusBadRequest, nil},
+		{"amount and percentage", 10, []model.SplitReceiver{{AccountID: "seller1", Amount: 10, Percent: 100}}, nil, nil, http.StatusBadRequest, nil},
+		{"zero share", 0.01, []model.SplitReceiver{percent("seller1", 50), percent("seller2", 50)}, nil, nil, http.StatusBadRequest, nil},
+		{"total below minor unit", 0.001, []model.SplitReceiver{percent("seller1", 100)}, nil, nil, http.StatusBadRequest, nil},
+		{"no receivers", 10, nil, nil, nil, http.StatusBadRequest, nil},
+		{"payer receives", 10, []model.SplitReceiver{fixed("buyer", 10)}, nil, nil, http.StatusBadRequest, nil},
+		{"duplicate receiver", 10, []model.SplitReceiver{fixed("seller1", 5), fixed("seller1", 5)}, nil, nil, http.StatusBadRequest, nil},
+		{"currency mismatch", 10, []model.SplitReceiver{fixed("euro", 10)}, nil, nil, http.StatusBadRequest, ErrCurrencyMismatch},
+		{"receiver not found", 10, []model.SplitReceiver{fixed("missing", 10)}, nil, nil, http.StatusNotFound, ErrAccountNotFound},
+		{"insufficient funds", 300, []model.SplitReceiver{percent("seller1", 100)}, nil, nil, http.StatusBadRequest, ErrInsufficientFunds},
+		{"conflict", 10, []model.SplitReceiver{fixed("seller1", 10)}, model.ErrConflict, nil, http.StatusConflict, model.ErrConflict},
+		{"duplicate reference", 10, []model.SplitReceiver{fixed("seller1", 10)}, model.ErrRowExists, nil, http.StatusConflict, ErrDuplicateReference},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData:  accounts,
+				PaymentGroupErr: tt.dbErr,
+			}
+			s := NewWalletService(db)
+			got, err := s.PostSplitPayment(context.Background(), "buyer", tt.amount, tt.receivers, model.PaymentInfo{Reference: "order-1"})
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if tt.wantIs != nil && !xerrors.Is(err, tt.wantIs) {
+				t.Errorf("wrong error %v, want %v", err, tt.wantIs)
+			}
+			if err != nil {
+				return
+			}
+			shares := make([]int64, 0, len(got.Payments))
+			var sum currency.Amount
+			for _, p := range got.Payments {
+				share, _ := p.Amount.Int64()
+				shares = append(shares, share)
+				if sum, err = sum.Add(p.Amount); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(shares, tt.want) {
+				t.Errorf("wrong shares %v, want %v", shares, tt.want)
+			}
+			if sum != got.Amount {
+				t.Errorf("wrong total %v, want %v", got.Amount, sum)
+			}
+			if len(db.lastChanged) != len(tt.receivers)+1 {
+				t.Errorf("wrong number of locked accounts %v, want %v", len(db.lastChanged), len(tt.receivers)+1)
+			}
+		})
+	}
+}
+
 func Test_WalletService_PostAccount(t *testing.T) {
 	now := time.Now()
 	type args struct {
 		id      string
 		balance float64
 		curr    string
+		info    model.AccountInfo
 	}
 	tests := []struct {
 		name    string
@@ -604,7 +1162,7 @@ func Test_WalletService_PostAccount(t *testing.T) {
 					dat: &model.Account{
 						ID:         "1",
 						LastUpdate: &now,
-						Balance:    12345,
+						Balance:    currency.NewAmount(12345),
 						Currency:   currency.USD,
 					},
 					err: nil,
@@ -613,7 +1171,7 @@ func Test_WalletService_PostAccount(t *testing.T) {
 			want: &model.Account{
 				ID:         "1",
 				LastUpdate: &now,
-				Balance:    12345,
+				Balance:    currency.NewAmount(12345),
 				Currency:   currency.USD,
 			},
 			wantErr: false,
@@ -631,6 +1189,30 @@ func Test_WalletService_PostAccount(t *testing.T) {
 			wantErr: true,
 		},
 
+		{
+			name: "error withdrawn currency",
+			args: args{
+				id:      "1",
+				balance: 123.45,
+				curr:    "DEM",
+			},
+			db:      &TestDatabase{},
+			want:    &model.Account{},
+			wantErr: true,
+		},
+
+		{
+			name: "error balance out of range",
+			args: args{
+				id:      "1",
+				balance: 1e39,
+				curr:    "VND",
+			},
+			db:      &TestDatabase{},
+			want:    &model.Account{},
+			wantErr: true,
+		},
+
 		{
 			name: "error amount",
 			args: args{
@@ -643,6 +1225,32 @@ func Test_WalletService_PostAccount(t *testing.T) {
 			wantErr: true,
 		},
 
+		{
+			name: "error labels",
+			args: args{
+				id:      "1",
+				balance: 123.45,
+				curr:    "USD",
+				info:    model.AccountInfo{Labels: []string{"vip", "vip"}},
+			},
+			db:      &TestDatabase{},
+			want:    &model.Account{},
+			wantErr: true,
+		},
+
+		{
+			name: "error unknown type",
+			args: args{
+				id:      "1",
+				balance: 123.45,
+				curr:    "USD",
+				info:    model.AccountInfo{Type: "checking"},
+			},
+			db:      &TestDatabase{},
+			want:    &model.Account{},
+			wantErr: true,
+		},
+
 		{
 			name: "error creation",
 			args: args{
@@ -682,7 +1290,7 @@ func Test_WalletService_PostAccount(t *testing.T) {
 			s := &WalletService{
 				db: tt.db,
 			}
-			got, err := s.PostAccount(context.Background(), tt.args.id, tt.args.balance, tt.args.curr)
+			got, err := s.PostAccount(context.Background(), tt.args.id, tt.args.balance, tt.args.curr, tt.args.info)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("WalletService.PostAccount() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -693,3 +1301,570 @@ func Test_WalletService_PostAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestServicePatchAccount(t *testing.T) {
+	owner := "customer-1"
+	longName := strings.Repeat("a", 256)
+	emptyLabels := []string{""}
+	account := &model.Account{ID: "1", Balance: currency.NewAmount(100), Currency: currency.USD, AccountInfo: model.AccountInfo{OwnerID: owner}}
+
+	tests := []struct {
+		name     string
+		patch    model.AccountPatch
+		db       *TestDatabase
+		want     *model.Account
+		wantCode int
+	}{
+		{
+			name:  "simple",
+			patch: model.AccountPatch{OwnerID: &owner},
+			db:    &TestDatabase{UpdateAccountData: testDatabaseData{dat: account}},
+			want:  account,
+		},
+		{
+			name:     "long name",
+			patch:    model.AccountPatch{DisplayName: &longName},
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "empty label",
+			patch:    model.AccountPatch{Labels: &emptyLabels},
+			db:       &TestDatabase{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "not found",
+			patch:    model.AccountPatch{OwnerID: &owner},
+			db:       &TestDatabase{UpdateAccountData: testDatabaseData{err: sql.ErrNoRows}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "database error",
+			patch:    model.AccountPatch{OwnerID: &owner},
+			db:       &TestDatabase{UpdateAccountData: testDatabaseData{err: testDatabaseErr}},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(tt.db)
+			got, err := s.PatchAccount(context.Background(), "1", tt.patch)
+			if tt.wantCode != 0 {
+				var httpErr HTTPError
+				if !xerrors.As(err, &httpErr) || httpErr.Code() != tt.wantCode {
+					t.Fatalf("wrong error %v, want code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong account %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceImportAccounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        []model.AccountImport
+		db          *TestDatabase
+		want        *model.ImportResult
+		wantCreated []model.Account
+		wantErr     bool
+	}{
+		{
+			name: "valid",
+			rows: []model.AccountImport{
+				{Line: 2, ID: "alice", Currency: "USD", Balance: "12.30"},
+				{Line: 3, ID: "bob", Currency: "BHD", Balance: "0"},
+				{Line: 4, ID: "carol", Currency: "USD", Balance: "999999999999999999999999999999999999.99"},
+			},
+			db:   &TestDatabase{},
+			want: &model.ImportResult{Imported: 3},
+			wantCreated: []model.Account{
+				{ID: "alice", Balance: currency.NewAmount(1230), Currency: currency.USD},
+				{ID: "bob", Balance: currency.NewAmount(0), Currency: currency.BHD},
+				{ID: "carol", Balance: currency.MaxSafeAmount, Currency: currency.USD},
+			},
+		},
+		{
+			name: "invalid rows",
+			rows: []model.AccountImport{
+				{Line: 2, ID: "alice", Currency: "USD", Balance: "12.30"},
+				{Line: 3, ID: "", Currency: "USD", Balance: "1"},
+				{Line: 4, ID: "bob", Currency: "XXX", Balance: "1"},
+				{Line: 5, ID: "carol", Currency: "USD", Balance: "-1"},
+				{Line: 6, ID: "dave", Currency: "USD", Balance: "1.005"},
+				{Line: 7, ID: "alice", Currency: "USD", Balance: "1"},
+				{Line: 8, ID: "eve", Currency: "USD", Balance: ""},
+				{Line: 9, ID: "abcdefghijklmnopqrstuvwxyz012345", Currency: "USD", Balance: "1"},
+				{Line: 10, ID: "bob/USD", Currency: "USD", Balance: "1"},
+				{Line: 11, ID: "frank", Currency: "USD", Balance: "1000000000000000000000000000000000000"},
+			},
+			db: &TestDatabase{},
+			want: &model.ImportResult{Errors: []model.ImportError{
+				{Line: 3, ID: "", Error: "empty account id"},
+				{Line: 4, ID: "bob", Error: "unknown currency (XXX)"},
+				{Line: 5, ID: "carol", Error: "negative balance -1"},
+				{Line: 6, ID: "dave", Error: `amount "1.005" has more than 2 decimal places of USD`},
+				{Line: 7, ID: "alice", Error: "duplicate account id, first occurrence in line 2"},
+				{Line: 8, ID: "eve", Error: "empty balance"},
+				{Line: 9, ID: "abcdefghijklmnopqrstuvwxyz012345", Error: "account id is longer than 30 characters"},
+				{Line: 10, ID: "bob/USD", Error: "account id has invalid character '/', it separates the wallet id and the currency in pocket ids"},
+				{Line: 11, ID: "frank", Error: `USD amount "1000000000000000000000000000000000000": amount is out of the safe range`},
+			}},
+		},
+		{
+			name: "existing accounts",
+			rows: []model.AccountImport{
+				{Line: 1, ID: "alice", Currency: "USD", Balance: "1"},
+				{Line: 2, ID: "bob", Currency: "USD", Balance: "1"},
+			},
+			db: &TestDatabase{CreateAccountsData: testDatabaseData{dat: []int{1}}},
+			want: &model.ImportResult{DryRun: true, Errors: []model.ImportError{
+				{Line: 2, ID: "bob", Error: "account already exists"},
+			}},
+			wantCreated: []model.Account{
+				{ID: "alice", Balance: currency.NewAmount(100), Currency: currency.USD},
+				{ID: "bob", Balance: currency.NewAmount(100), Currency: currency.USD},
+			},
+		},
+		{
+			name:    "empty",
+			db:      &TestDatabase{},
+			wantErr: true,
+		},
+		{
+			name:    "database error",
+			rows:    []model.AccountImport{{Line: 1, ID: "alice", Currency: "USD", Balance: "1"}},
+			db:      &TestDatabase{CreateAccountsData: testDatabaseData{err: testDatabaseErr}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(tt.db)
+			dryRun := tt.want != nil && tt.want.DryRun
+			got, err := s.ImportAccounts(context.Background(), tt.rows, dryRun)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wrong error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong result %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.db.created, tt.wantCreated) {
+				t.Errorf("wrong created accounts %v, want %v", tt.db.created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestServicePostWallet(t *testing.T) {
+	if _, err := currency.Register("POINTS", "Loyalty Points", 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		id         string
+		currencies []string
+		db         *TestDatabase
+		wantCode   int
+	}{
+		{"simple", "alice", []string{"USD", "EUR"}, &TestDatabase{}, http.StatusOK},
+		{"empty id", "", []string{"USD"}, &TestDatabase{}, http.StatusUnprocessableEntity},
+		{"long id", strings.Repeat("a", 27), []string{"USD"}, &TestDatabase{}, http.StatusUnprocessableEntity},
+		{"slash in id", "alice/bob", []string{"USD"}, &TestDatabase{}, http.StatusUnprocessableEntity},
+		{"invalid character in id", "alice smith", []string{"USD"}, &TestDatabase{}, http.StatusUnprocessableEntity},
+		{"no currencies", "alice", nil, &TestDatabase{}, http.StatusUnprocessableEntity},
+		{"unknown currency", "alice", []string{"XXX"}, &TestDatabase{}, http.StatusBadRequest},
+		{"withdrawn currency", "alice", []string{"HRK"}, &TestDatabase{}, http.StatusBadRequest},
+		{"custom currency", "alice", []string{"USD", "POINTS"}, &TestDatabase{}, http.StatusOK},
+		{"long pocket id", strings.Repeat("a", 24), []string{"POINTS"}, &TestDatabase{}, http.StatusBadRequest},
+		{"duplicate currency", "alice", []string{"USD", "USD"}, &TestDatabase{}, http.StatusBadRequest},
+		{"exists", "alice", []string{"USD"}, &TestDatabase{CreateWalletData: testDatabaseData{err: model.ErrRowExists}}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(tt.db)
+			got, err := s.PostWallet(context.Background(), tt.id, "customer-1", tt.currencies)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			want := make([]string, 0, len(tt.currencies))
+			for _, c := range tt.currencies {
+				want = append(want, tt.id+"/"+c)
+			}
+			if len(got.Pockets) != len(want) {
+				t.Fatalf("wrong pockets %v, want %v", got.Pockets, want)
+			}
+			for i, p := range got.Pockets {
+				if p.ID != want[i] {
+					t.Errorf("wrong pocket id %v, want %v", p.ID, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServiceGetWallet(t *testing.T) {
+	rateTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rates := []currency.Rate{
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.2, Time: rateTime},
+		// the latest rate is used
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: rateTime.AddDate(0, 1, 0)},
+		// the rate isn't set yet
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.3, Time: time.Now().AddDate(1, 0, 0)},
+	}
+	newWallet := func() *model.Wallet {
+		return &model.Wallet{
+			ID: "alice",
+			Pockets: []model.Account{
+				{ID: "alice/EUR", Balance: currency.NewAmount(1000), Currency: currency.EUR},
+				{ID: "alice/USD", Balance: currency.NewAmount(500), Currency: currency.USD},
+			},
+		}
+	}
+	tests := []struct {
+		name      string
+		total     string
+		db        *TestDatabase
+		wantTotal int64
+		wantCurr  currency.Currency
+		wantCode  int
+	}{
+		{"base currency", "", &TestDatabase{GetWalletData: testDatabaseData{dat: newWallet()}, Rates: rates}, 1600, currency.USD, http.StatusOK},
+		{"in EUR", "EUR", &TestDatabase{GetWalletData: testDatabaseData{dat: newWallet()}, Rates: rates}, 1455, currency.EUR, http.StatusOK},
+		{"no rate", "GBP", &TestDatabase{GetWalletData: testDatabaseData{dat: newWallet()}, Rates: rates}, 0, "", http.StatusUnprocessableEntity},
+		{"rates error", "", &TestDatabase{GetWalletData: testDatabaseData{dat: newWallet()}, RatesErr: errors.New("test error")}, 0, "", http.StatusInternalServerError},
+		{"not found", "", &TestDatabase{GetWalletData: testDatabaseData{err: sql.ErrNoRows}}, 0, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(tt.db, WithBaseCurrency(currency.USD))
+			got, err := s.GetWallet(context.Background(), "alice", tt.total)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if got.Total != currency.NewAmount(tt.wantTotal) || got.TotalCurrency != tt.wantCurr {
+				t.Errorf("wrong total %v %v, want %v %v", got.Total, got.TotalCurrency, tt.wantTotal, tt.wantCurr)
+			}
+		})
+	}
+}
+
+func TestServiceConvertFunds(t *testing.T) {
+	now := time.Now()
+	rates := []currency.Rate{
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: now.AddDate(0, -1, 0)},
+		{Base: currency.HRK, Quote: currency.USD, Rate: 0.13, Time: now.AddDate(-5, 0, 0)},
+		// conversions use the current rate
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.2, Time: now.AddDate(0, 1, 0)},
+	}
+	pockets := map[string]testDatabaseData{
+		"alice/HRK": {dat: &model.Account{ID: "alice/HRK", LastUpdate: &now, Balance: currency.NewAmount(1000), Currency: currency.HRK}},
+		"alice/USD": {dat: &model.Account{ID: "alice/USD", LastUpdate: &now, Balance: currency.NewAmount(5000), Currency: currency.USD}},
+		"alice/EUR": {dat: &model.Account{ID: "alice/EUR", LastUpdate: &now, Balance: currency.NewAmount(1000), Currency: currency.EUR}},
+		"alice/GBP": {dat: &model.Account{ID: "alice/GBP", LastUpdate: &now, Balance: currency.NewAmount(1000), Currency: currency.GBP}},
+		"alice/JPY": {dat: (*model.Account)(nil), err: sql.ErrNoRows},
+	}
+	tests := []struct {
+		name       string
+		from       string
+		to         string
+		amount     float64
+		wantAmount int64
+		wantCode   int
+		wantIs     error
+	}{
+		{"simple", "EUR", "USD", 10, 1100, http.StatusOK, nil},
+		{"rounded down", "EUR", "USD", 0.05, 5, http.StatusOK, nil},
+		{"same currency", "USD", "USD", 10, 0, http.StatusBadRequest, nil},
+		{"negative amount", "EUR", "USD", -1, 0, http.StatusBadRequest, nil},
+		{"below minor unit", "EUR", "USD", 0.001, 0, http.StatusBadRequest, nil},
+		{"insufficient funds", "EUR", "USD", 11, 0, http.StatusBadRequest, ErrInsufficientFunds},
+		{"no pocket", "USD", "JPY", 1, 0, http.StatusNotFound, ErrAccountNotFound},
+		{"no rate", "USD", "GBP", 1, 0, http.StatusUnprocessableEntity, ErrNoExchangeRate},
+		{"from withdrawn currency", "HRK", "USD", 10, 130, http.StatusOK, nil},
+		{"into withdrawn currency", "USD", "HRK", 10, 0, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData:    pockets,
+				CreatePaymentData: testDatabaseData{dat: &model.Payment{}},
+				Rates:             rates,
+			}
+			s := NewWalletService(db, WithBaseCurrency(currency.USD))
+			_, err := s.ConvertFunds(context.Background(), "alice", tt.from, tt.to, tt.amount)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if tt.wantIs != nil && !xerrors.Is(err, tt.wantIs) {
+				t.Errorf("wrong error %v, want %v", err, tt.wantIs)
+			}
+			if err != nil {
+				return
+			}
+			if db.payment.ToAmount != currency.NewAmount(tt.wantAmount) || db.payment.AccToID != "alice/"+tt.to {
+				t.Errorf("wrong conversion %v to %v, want %v to alice/%v", db.payment.ToAmount, db.payment.AccToID, tt.wantAmount, tt.to)
+			}
+		})
+	}
+}
+
+func TestServiceGetTrialBalance(t *testing.T) {
+	tests := []struct {
+		name         string
+		db           *TestDatabase
+		wantBalanced bool
+		wantCode     int
+	}{
+		{
+			name: "balanced",
+			db: &TestDatabase{TrialBalanceData: testDatabaseData{dat: []model.TrialBalanceAccount{
+				{AccountID: "@suspense/USD", Currency: currency.USD, Debit: currency.NewAmount(1000)},
+				{AccountID: "alice", Currency: currency.USD, Debit: currency.NewAmount(300), Credit: currency.NewAmount(1000)},
+				{AccountID: "bob", Currency: currency.USD, Credit: currency.NewAmount(300)},
+			}}},
+			wantBalanced: true,
+			wantCode:     http.StatusOK,
+		},
+		{
+			name: "unbalanced",
+			db: &TestDatabase{TrialBalanceData: testDatabaseData{dat: []model.TrialBalanceAccount{
+				{AccountID: "alice", Currency: currency.USD, Credit: currency.NewAmount(1000)},
+			}}},
+			wantBalanced: false,
+			wantCode:     http.StatusOK,
+		},
+		{
+			name:     "database error",
+			db:       &TestDatabase{TrialBalanceData: testDatabaseData{err: errors.New("connection lost")}},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(tt.db)
+			got, err := s.GetTrialBalance(context.Background())
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if got.Balanced != tt.wantBalanced {
+				t.Errorf("wrong balanced %v, want %v", got.Balanced, tt.wantBalanced)
+			}
+		})
+	}
+}
+
+func TestServiceGetInterestAccruals(t *testing.T) {
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	accruals := []model.InterestAccrual{
+		{AccountID: "alice", Date: day, Balance: currency.NewAmount(100000), Rate: 0.02, Amount: currency.NewAmount(5), Currency: currency.USD},
+	}
+	tests := []struct {
+		name     string
+		db       *TestDatabase
+		want     []model.InterestAccrual
+		wantCode int
+	}{
+		{
+			name: "simple",
+			db: &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{"alice": {dat: &model.Account{ID: "alice"}}},
+				AccrualsData:   testDatabaseData{dat: accruals},
+			},
+			want:     accruals,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not found",
+			db: &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{"alice": {dat: (*model.Account)(nil), err: sql.ErrNoRows}},
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(tt.db)
+			got, err := s.GetInterestAccruals(context.Background(), "alice")
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong accruals %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// errorCode returns the status code of the service error, or 200 if there is no error
+func errorCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var httpErr HTTPError
+	if xerrors.As(err, &httpErr) {
+		return httpErr.Code()
+	}
+	return 0
+}
+
+func TestServiceGetAllAccountsConverted(t *testing.T) {
+	rateTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	stored := []currency.Rate{
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: rateTime},
+		{Base: currency.USD, Quote: currency.JPY, Rate: 110, Time: rateTime.AddDate(0, 1, 0)},
+	}
+	accounts := func() []model.Account {
+		return []model.Account{
+			{ID: "alice", Balance: currency.NewAmount(1000), Currency: currency.EUR},
+			{ID: "bob", Balance: currency.NewAmount(550), Currency: currency.USD},
+		}
+	}
+	tests := []struct {
+		name      string
+		convertTo string
+		rates     []currency.Rate
+		want      []int64
+		wantTime  time.Time
+		wantCode  int
+	}{
+		{"not converted", "", nil, nil, time.Time{}, http.StatusOK},
+		{"direct and same currency", "USD", stored, []int64{1100, 550}, rateTime, http.StatusOK},
+		{"inverse", "EUR", stored, []int64{1000, 500}, rateTime, http.StatusOK},
+		{"through pivot", "JPY", stored, []int64{1210, 605}, rateTime, http.StatusOK},
+		{"no rate", "GBP", stored, nil, time.Time{}, http.StatusUnprocessableEntity},
+		{"unknown currency", "XYZ", stored, nil, time.Time{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{GetAllAccountsData: testDatabaseData{dat: accounts()}, Rates: tt.rates}
+			s := NewWalletService(db, WithBaseCurrency(currency.USD))
+			got, err := s.GetAllAccounts(context.Background(), model.AccountFilter{ConvertTo: tt.convertTo})
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			for i, a := range got {
+				if tt.want == nil {
+					if a.Converted != nil {
+						t.Errorf("unexpected converted balance %+v", a.Converted)
+					}
+					continue
+				}
+				if a.Converted == nil {
+					t.Fatalf("no converted balance of %s", a.ID)
+				}
+				if a.Converted.Balance != currency.NewAmount(tt.want[i]) || string(a.Converted.Currency) != tt.convertTo {
+					t.Errorf("wrong %s converted balance %v %v, want %v %v", a.ID, a.Converted.Balance, a.Converted.Currency, tt.want[i], tt.convertTo)
+				}
+				// alice's EUR rate is the oldest one in every conversion
+				if i == 0 && a.Currency != a.Converted.Currency && !a.Converted.Rate.Time.Equal(tt.wantTime) {
+					t.Errorf("wrong %s rate time %v, want %v", a.ID, a.Converted.Rate.Time, tt.wantTime)
+				}
+			}
+		})
+	}
+}
+
+func TestServicePostRates(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		rates    []currency.Rate
+		dbErr    error
+		want     int
+		wantCode int
+	}{
+		{"single", []currency.Rate{{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: at}}, nil, 1, http.StatusOK},
+		{"bulk", []currency.Rate{
+			{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: at},
+			{Base: currency.GBP, Quote: currency.USD, Rate: 1.27},
+		}, nil, 2, http.StatusOK},
+		{"empty", nil, nil, 0, http.StatusBadRequest},
+		{"unknown currency", []currency.Rate{{Base: "XYZ", Quote: currency.USD, Rate: 1}}, nil, 0, http.StatusBadRequest},
+		{"same currency", []currency.Rate{{Base: currency.USD, Quote: currency.USD, Rate: 1}}, nil, 0, http.StatusBadRequest},
+		{"negative rate", []currency.Rate{{Base: currency.EUR, Quote: currency.USD, Rate: -1}}, nil, 0, http.StatusBadRequest},
+		{"NaN rate", []currency.Rate{{Base: currency.EUR, Quote: currency.USD, Rate: math.NaN()}}, nil, 0, http.StatusBadRequest},
+		{"database error", []currency.Rate{{Base: currency.EUR, Quote: currency.USD, Rate: 1.1}}, testDatabaseErr, 0, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{RatesErr: tt.dbErr}
+			got, err := NewWalletService(db).PostRates(context.Background(), tt.rates)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if got != tt.want {
+				t.Errorf("wrong saved rates %v, want %v", got, tt.want)
+			}
+			for _, r := range db.Rates {
+				if r.Time.IsZero() {
+					t.Errorf("rate %s/%s time isn't set", r.Base, r.Quote)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceGetRate(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &TestDatabase{Rates: []currency.Rate{
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.1, Time: at},
+		{Base: currency.EUR, Quote: currency.USD, Rate: 1.2, Time: at.AddDate(0, 0, 10)},
+	}}
+	tests := []struct {
+		name     string
+		base     string
+		quote    string
+		at       time.Time
+		want     float64
+		wantCode int
+	}{
+		{"latest before", "EUR", "USD", at.AddDate(0, 0, 5), 1.1, http.StatusOK},
+		{"now", "EUR", "USD", time.Time{}, 1.2, http.StatusOK},
+		{"inverse", "USD", "EUR", at, 1 / 1.1, http.StatusOK},
+		{"too early", "EUR", "USD", at.Add(-time.Second), 0, http.StatusNotFound},
+		{"invalid currency", "EUR", "XYZ", at, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewWalletService(db).GetRate(context.Background(), tt.base, tt.quote, tt.at)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("wrong status code %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err == nil && got.Rate != tt.want {
+				t.Errorf("wrong rate %v, want %v", got.Rate, tt.want)
+			}
+			if tt.wantCode == http.StatusNotFound && !xerrors.Is(err, ErrNoExchangeRate) {
+				t.Errorf("wrong error %v, want %v", err, ErrNoExchangeRate)
+			}
+		})
+	}
+}
+
+// toInternal converts a test amount into the lowest currency units
+func toInternal(m float64, c currency.Currency) currency.Amount {
+	res, err := currency.ConvertToInternal(m, c, currency.RoundHalfEven)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+)
+
+// ErrDraining means that the service is shutting down and doesn't accept new requests
+var ErrDraining = errors.New("service is shutting down")
+
+// Drainer tracks the shutdown state of the service.
+//
+// Once draining is started, the HTTP handler refuses new requests with 503 status and the readiness probe fails,
+// so load balancers stop sending traffic to the instance while in-flight requests are completed
+type Drainer struct {
+	draining int32
+}
+
+// NewDrainer creates a new drainer in the serving state
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Drain switches the service into the draining state
+func (d *Drainer) Drain() {
+	atomic.StoreInt32(&d.draining, 1)
+}
+
+// Draining checks if the service is in the draining state
+func (d *Drainer) Draining() bool {
+	return atomic.LoadInt32(&d.draining) == 1
+}
+
+// Check is a readiness check that fails while draining
+func (d *Drainer) Check(context.Context) error {
+	if d.Draining() {
+		return ErrDraining
+	}
+	return nil
+}
+
+// makeDrainingMiddleware creates a router middleware that refuses new requests while draining.
+//
+// Health probes are still served to report the service state
+func makeDrainingMiddleware(d *Drainer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if d.Draining() && !isProbeRequest(r) {
+				w.Header().Set("Connection", "close")
+				encodeError(r.Context(), NewErrHTTPStatusf(http.StatusServiceUnavailable, ErrDraining, "service unavailable"), w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+)
+
+func TestDrainer(t *testing.T) {
+	db := &TestDatabase{
+		GetAllAccountsData: testDatabaseData{
+			dat: []model.Account{},
+		},
+	}
+	d := NewDrainer()
+	h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger(), WithDrainer(d), WithReadinessChecks(time.Second))
+
+	tests := []struct {
+		name     string
+		path     string
+		drain    bool
+		wantCode int
+	}{
+		{"serving api", "/api/accounts", false, http.StatusOK},
+		{"serving readiness", "/readyz", false, http.StatusOK},
+		{"serving liveness", "/healthz", false, http.StatusOK},
+		{"draining api", "/api/accounts", true, http.StatusServiceUnavailable},
+		{"draining readiness", "/readyz", true, http.StatusServiceUnavailable},
+		{"draining liveness", "/healthz", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.drain {
+				d.Drain()
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("wrong status code %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package wallet
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/ilyakaznacheev/tiny-wallet/internal/tracing"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingService is a Service middleware that creates a span for each service method call
+type tracingService struct {
+	Service
+	tracer *tracing.Tracer
+}
+
+// NewTracingService wraps the service with a tracing middleware
+func NewTracingService(s Service, tracer *tracing.Tracer) Service {
+	return &tracingService{s, tracer}
+}
+
+// GetAllPayments traces the GetAllPayments call
+func (s *tracingService) GetAllPayments(ctx context.Context, filter model.PaymentFilter) (res []model.Payment, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.GetAllPayments")
+	defer func() { tracing.End(span, err) }()
+	return s.Service.GetAllPayments(ctx, filter)
+}
+
+// GetAllAccounts traces the GetAllAccounts call
+func (s *tracingService) GetAllAccounts(ctx context.Context, filter model.AccountFilter) (res []model.Account, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.GetAllAccounts")
+	defer func() { tracing.End(span, err) }()
+	return s.Service.GetAllAccounts(ctx, filter)
+}
+
+// PostPayment traces the PostPayment call
+func (s *tracingService) PostPayment(ctx context.Context, fromID, toID string, amount float64, info model.PaymentInfo) (res *model.Payment, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.PostPayment", trace.WithAttributes(
+		attribute.String("account.from", fromID),
+		attribute.String("account.to", toID),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.PostPayment(ctx, fromID, toID, amount, info)
+}
+
+// PostSplitPayment traces the PostSplitPayment call
+func (s *tracingService) PostSplitPayment(ctx context.Context, fromID string, amount float64, receivers []model.SplitReceiver, info model.PaymentInfo) (res *model.PaymentGroup, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.PostSplitPayment", trace.WithAttributes(
+		attribute.String("account.from", fromID),
+		attribute.String("split.receivers", strconv.Itoa(len(receivers))),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.PostSplitPayment(ctx, fromID, amount, receivers, info)
+}
+
+// PostAccount traces the PostAccount call
+func (s *tracingService) PostAccount(ctx context.Context, id string, balance float64, curr string, info model.AccountInfo) (res *model.Account, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.PostAccount", trace.WithAttributes(
+		attribute.String("account.id", id),
+		attribute.String("account.currency", curr),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.PostAccount(ctx, id, balance, curr, info)
+}
+
+// PatchAccount traces the PatchAccount call
+func (s *tracingService) PatchAccount(ctx context.Context, id string, patch model.AccountPatch) (res *model.Account, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.PatchAccount", trace.WithAttributes(
+		attribute.String("account.id", id),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.PatchAccount(ctx, id, patch)
+}
+
+// ExportPayments traces the ExportPayments call
+func (s *tracingService) ExportPayments(ctx context.Context, from, to *time.Time, fn func(model.Payment) error) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.ExportPayments")
+	defer func() { tracing.End(span, err) }()
+	return s.Service.ExportPayments(ctx, from, to, fn)
+}
+
+// ExportAccounts traces the ExportAccounts call
+func (s *tracingService) ExportAccounts(ctx context.Context, fn func(model.Account) error) (err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.ExportAccounts")
+	defer func() { tracing.End(span, err) }()
+	return s.Service.ExportAccounts(ctx, fn)
+}
+
+// ImportAccounts traces the ImportAccounts call
+func (s *tracingService) ImportAccounts(ctx context.Context, rows []model.AccountImport, dryRun bool) (res *model.ImportResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.ImportAccounts", trace.WithAttributes(
+		attribute.String("import.rows", strconv.Itoa(len(rows))),
+		attribute.String("import.dry_run", strconv.FormatBool(dryRun)),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.ImportAccounts(ctx, rows, dryRun)
+}
+
+// PostWallet traces the PostWallet call
+func (s *tracingService) PostWallet(ctx context.Context, id, ownerID string, currencies []string) (res *model.Wallet, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.PostWallet", trace.WithAttributes(
+		attribute.String("wallet.id", id),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.PostWallet(ctx, id, ownerID, currencies)
+}
+
+// GetWallet traces the GetWallet call
+func (s *tracingService) GetWallet(ctx context.Context, id, totalCurrency string) (res *model.Wallet, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.GetWallet", trace.WithAttributes(
+		attribute.String("wallet.id", id),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.GetWallet(ctx, id, totalCurrency)
+}
+
+// ConvertFunds traces the ConvertFunds call
+func (s *tracingService) ConvertFunds(ctx context.Context, walletID, from, to string, amount float64) (res *model.Payment, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.ConvertFunds", trace.WithAttributes(
+		attribute.String("wallet.id", walletID),
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.ConvertFunds(ctx, walletID, from, to, amount)
+}
+
+// GetTrialBalance traces the GetTrialBalance call
+func (s *tracingService) GetTrialBalance(ctx context.Context) (res *model.TrialBalance, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.GetTrialBalance")
+	defer func() { tracing.End(span, err) }()
+	return s.Service.GetTrialBalance(ctx)
+}
+
+// GetInterestAccruals traces the GetInterestAccruals call
+func (s *tracingService) GetInterestAccruals(ctx context.Context, accountID string) (res []model.InterestAccrual, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.GetInterestAccruals", trace.WithAttributes(
+		attribute.String("account.id", accountID),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.GetInterestAccruals(ctx, accountID)
+}
+
+// PostRates traces the PostRates call
+func (s *tracingService) PostRates(ctx context.Context, rates []currency.Rate) (res int, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.PostRates", trace.WithAttributes(
+		attribute.String("rates.count", strconv.Itoa(len(rates))),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.PostRates(ctx, rates)
+}
+
+// GetRate traces the GetRate call
+func (s *tracingService) GetRate(ctx context.Context, base, quote string, at time.Time) (res *currency.Rate, err error) {
+	ctx, span := s.tracer.Start(ctx, "Service.GetRate", trace.WithAttributes(
+		attribute.String("rate.base", base),
+		attribute.String("rate.quote", quote),
+	))
+	defer func() { tracing.End(span, err) }()
+	return s.Service.GetRate(ctx, base, quote, at)
+}
+
+// makeTracingMiddleware creates a router middleware that starts a server span for each request.
+//
+// The span is named after the route and continues a trace from the W3C traceparent request header, if there is one
+func makeTracingMiddleware(tracer *tracing.Tracer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "",
+			otelhttp.WithTracerProvider(tracer.Provider()),
+			otelhttp.WithPropagators(tracing.Propagator),
+			otelhttp.WithSpanNameFormatter(routeSpanName),
+		)
+	}
+}
+
+// routeSpanName returns a span name of the request, the method and the path template of the route
+func routeSpanName(_ string, r *http.Request) string {
+	name := r.Method
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			name += " " + tpl
+		}
+	}
+	return name
+}
+
+// traceDecoder wraps a request decoder with a span
+func traceDecoder(tracer *tracing.Tracer, name string, dec httptransport.DecodeRequestFunc) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (request interface{}, err error) {
+		ctx, span := tracer.Start(ctx, name)
+		defer func() { tracing.End(span, err) }()
+		return dec(ctx, r)
+	}
+}
@@ -0,0 +1,80 @@
+package wallet
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/internal/tracing"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPHandlerTracing(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+	now := time.Now()
+	db := &TestDatabase{
+		GetAccountData: map[string]testDatabaseData{
+			"1": testDatabaseData{
+				dat: &model.Account{ID: "1", LastUpdate: &now, Balance: currency.NewAmount(12345), Currency: currency.USD},
+			},
+			"2": testDatabaseData{
+				dat: &model.Account{ID: "2", LastUpdate: &now, Balance: currency.NewAmount(67890), Currency: currency.USD},
+			},
+		},
+		CreatePaymentData: testDatabaseData{
+			dat: &model.Payment{ID: 1, AccFromID: "1", AccToID: "2", DateTime: now, Amount: currency.NewAmount(100)},
+		},
+	}
+
+	exp := tracetest.NewInMemoryExporter()
+	tracer := tracing.NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	h := MakeHTTPHandler(NewTracingService(NewWalletService(db), tracer), log.NewNopLogger(), WithTracer(tracer))
+
+	req := httptest.NewRequest("POST", "/api/payment", bytes.NewBufferString(`{"account-from":"1","account-to":"2","amount":1}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong status code %d: %s", w.Code, w.Body.String())
+	}
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, s := range exp.GetSpans() {
+		if s.SpanContext.TraceID().String() != traceID {
+			t.Errorf("span %s has wrong trace id %s, want %s", s.Name, s.SpanContext.TraceID(), traceID)
+		}
+		spans[s.Name] = s
+	}
+
+	server, ok := spans["POST /api/payment"]
+	if !ok {
+		t.Fatalf("no server span in %v", spans)
+	}
+	if server.Parent.SpanID().String() != parentID {
+		t.Errorf("wrong server span parent %s, want %s", server.Parent.SpanID(), parentID)
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("wrong server span kind %v", server.SpanKind)
+	}
+	for _, name := range []string{"decode PostPaymentRequest", "Service.PostPayment"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("no span %s in %v", name, spans)
+			continue
+		}
+		if s.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("span %s has wrong parent %s, want %s", name, s.Parent.SpanID(), server.SpanContext.SpanID())
+		}
+	}
+}
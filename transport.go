@@ -4,19 +4,87 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/ilyakaznacheev/tiny-wallet/internal/tracing"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
 	"golang.org/x/xerrors"
 )
 
+// defaultReadinessTimeout is a default time limit of a single readiness check
+const defaultReadinessTimeout = 2 * time.Second
+
+// HTTPOption sets an optional parameter of the HTTP handler
+type HTTPOption func(*httpOptions)
+
+// httpOptions is a set of optional HTTP handler parameters
+type httpOptions struct {
+	metrics         *Metrics
+	tracer          *tracing.Tracer
+	readinessChecks []ReadinessCheck
+	readinessWait   time.Duration
+	drainer         *Drainer
+}
+
+// WithMetrics enables HTTP request metrics collection
+func WithMetrics(m *Metrics) HTTPOption {
+	return func(o *httpOptions) {
+		o.metrics = m
+	}
+}
+
+// WithTracer enables request tracing
+func WithTracer(t *tracing.Tracer) HTTPOption {
+	return func(o *httpOptions) {
+		o.tracer = t
+	}
+}
+
+// WithReadinessChecks sets dependency checks of the readiness probe.
+//
+// Each check is interrupted after the timeout
+func WithReadinessChecks(timeout time.Duration, checks ...ReadinessCheck) HTTPOption {
+	return func(o *httpOptions) {
+		o.readinessWait = timeout
+		o.readinessChecks = append(o.readinessChecks, checks...)
+	}
+}
+
+// WithDrainer makes the handler refuse new requests and fail the readiness probe while the service is draining
+func WithDrainer(d *Drainer) HTTPOption {
+	return func(o *httpOptions) {
+		o.drainer = d
+	}
+}
+
 // MakeHTTPHandler mounts all of the service endpoints into an http.Handler
-func MakeHTTPHandler(s Service, logger log.Logger) http.Handler {
+func MakeHTTPHandler(s Service, logger log.Logger, opts ...HTTPOption) http.Handler {
+	o := httpOptions{
+		readinessWait: defaultReadinessTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	r := mux.NewRouter()
+	if o.drainer != nil {
+		r.Use(makeDrainingMiddleware(o.drainer))
+		o.readinessChecks = append(o.readinessChecks, ReadinessCheck{"shutdown", o.drainer.Check})
+	}
+	if o.tracer != nil {
+		r.Use(makeTracingMiddleware(o.tracer))
+	}
+	if o.metrics != nil {
+		r.Use(makeInstrumentingMiddleware(o.metrics))
+	}
 	e := MakeServerEndpoints(s)
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorLogger(logger),
@@ -25,32 +93,120 @@ func MakeHTTPHandler(s Service, logger log.Logger) http.Handler {
 
 	r.Methods("GET").Path("/api/payments").Handler(httptransport.NewServer(
 		e.GetAllPaymentsEndpoint,
-		decodeDummy,
+		decodeGetAllPaymentsRequest,
 		encodeResponse,
 		options...,
 	))
 
 	r.Methods("GET").Path("/api/accounts").Handler(httptransport.NewServer(
 		e.GetAllAccountsEndpoint,
-		decodeDummy,
+		decodeGetAllAccountsRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/api/payments/export").Handler(httptransport.NewServer(
+		e.ExportPaymentsEndpoint,
+		decodeExportPaymentsRequest,
+		encodeExportResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/api/accounts/export").Handler(httptransport.NewServer(
+		e.ExportAccountsEndpoint,
+		decodeExportAccountsRequest,
+		encodeExportResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/api/accounts/import").Handler(httptransport.NewServer(
+		e.ImportAccountsEndpoint,
+		traceDecoder(o.tracer, "decode ImportAccountsRequest", decodeImportAccountsRequest),
 		encodeResponse,
 		options...,
 	))
 
 	r.Methods("POST").Path("/api/payment").Handler(httptransport.NewServer(
 		e.PostPayment,
-		decodePostPaymentRequest,
+		traceDecoder(o.tracer, "decode PostPaymentRequest", decodePostPaymentRequest),
+		encodeResponse,
+		append(options, httptransport.ServerBefore(decodeIdempotencyKey))...,
+	))
+
+	r.Methods("POST").Path("/api/payments/split").Handler(httptransport.NewServer(
+		e.PostSplitPayment,
+		traceDecoder(o.tracer, "decode PostSplitPaymentRequest", decodePostSplitPaymentRequest),
 		encodeResponse,
 		options...,
 	))
 
 	r.Methods("POST").Path("/api/account").Handler(httptransport.NewServer(
 		e.PostAccount,
-		decodePostAccountRequest,
+		traceDecoder(o.tracer, "decode PostAccountRequest", decodePostAccountRequest),
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("PATCH").Path("/api/accounts/{id}").Handler(httptransport.NewServer(
+		e.PatchAccount,
+		traceDecoder(o.tracer, "decode PatchAccountRequest", decodePatchAccountRequest),
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/api/wallet").Handler(httptransport.NewServer(
+		e.PostWallet,
+		traceDecoder(o.tracer, "decode PostWalletRequest", decodePostWalletRequest),
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/api/wallets/{id}").Handler(httptransport.NewServer(
+		e.GetWallet,
+		decodeGetWalletRequest,
 		encodeResponse,
 		options...,
 	))
 
+	r.Methods("POST").Path("/api/wallets/{id}/convert").Handler(httptransport.NewServer(
+		e.ConvertFunds,
+		traceDecoder(o.tracer, "decode ConvertFundsRequest", decodeConvertFundsRequest),
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/api/accounts/{id}/interest").Handler(httptransport.NewServer(
+		e.GetInterestAccruals,
+		decodeGetInterestAccrualsRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/api/ledger/trial-balance").Handler(httptransport.NewServer(
+		e.GetTrialBalance,
+		decodeDummy,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/api/rates").Handler(httptransport.NewServer(
+		e.PostRates,
+		traceDecoder(o.tracer, "decode PostRatesRequest", decodePostRatesRequest),
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/api/rates").Handler(httptransport.NewServer(
+		e.GetRate,
+		decodeGetRateRequest,
+		encodeResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/healthz").Name(routeLiveness).Handler(makeLivenessHandler())
+
+	r.Methods("GET").Path("/readyz").Name(routeReadiness).Handler(makeReadinessHandler(o.readinessWait, o.readinessChecks))
+
 	r.Path("/api").Handler(httptransport.NewServer(
 		e.RedirectAPI,
 		decodeDummy,
@@ -74,7 +230,15 @@ func decodeDummy(_ context.Context, r *http.Request) (request interface{}, err e
 
 func decodePostPaymentRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
 	var req PostPaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodePostSplitPaymentRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req PostSplitPaymentRequest
+	if err := decodeJSONBody(r, &req); err != nil {
 		return nil, err
 	}
 	return req, nil
@@ -82,9 +246,81 @@ func decodePostPaymentRequest(_ context.Context, r *http.Request) (request inter
 
 func decodePostAccountRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
 	var req PostAccountRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeGetAllPaymentsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	q := r.URL.Query()
+	req := GetAllPaymentsRequest{
+		Reference: q.Get("reference"),
+		Search:    q.Get("q"),
+	}
+	for _, m := range q["meta"] {
+		kv := strings.SplitN(m, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, nil, "invalid metadata filter %q, expected key:value", m)
+		}
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]string)
+		}
+		req.Metadata[kv[0]] = kv[1]
+	}
+	if g := q.Get("group"); g != "" {
+		id, err := strconv.Atoi(g)
+		if err != nil || id <= 0 {
+			return nil, NewErrHTTPStatusf(http.StatusBadRequest, err, "invalid payment group %q", g)
+		}
+		req.GroupID = id
+	}
+	return req, nil
+}
+
+func decodeGetAllAccountsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	q := r.URL.Query()
+	return GetAllAccountsRequest{
+		OwnerID:   q.Get("owner"),
+		Labels:    q["label"],
+		ConvertTo: q.Get("convert_to"),
+	}, nil
+}
+
+func decodePatchAccountRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req PatchAccountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return nil, err
 	}
+	req.ID = mux.Vars(r)["id"]
+	return req, nil
+}
+
+func decodeGetInterestAccrualsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return GetInterestAccrualsRequest{AccountID: mux.Vars(r)["id"]}, nil
+}
+
+func decodePostWalletRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req PostWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeGetWalletRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return GetWalletRequest{
+		ID:            mux.Vars(r)["id"],
+		TotalCurrency: r.URL.Query().Get("total"),
+	}, nil
+}
+
+func decodeConvertFundsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req ConvertFundsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+	req.WalletID = mux.Vars(r)["id"]
 	return req, nil
 }
 
@@ -98,19 +334,226 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 		return nil
 	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if sc, ok := response.(httptransport.StatusCoder); ok {
+		w.WriteHeader(sc.StatusCode())
+	}
 	return json.NewEncoder(w).Encode(response)
 }
 
+func encodeDummyRequest(_ context.Context, req *http.Request, request interface{}) error {
+	return nil
+}
+
 func encodeRequest(_ context.Context, req *http.Request, request interface{}) error {
 	var buf bytes.Buffer
 	err := json.NewEncoder(&buf).Encode(request)
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.ContentLength = int64(buf.Len())
 	req.Body = ioutil.NopCloser(&buf)
 	return nil
 }
 
+func encodeDummy(_ context.Context, req *http.Request, request interface{}) error {
+	return nil
+}
+
+func encodeGetAllPaymentsRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(GetAllPaymentsRequest)
+	q := req.URL.Query()
+	if r.Reference != "" {
+		q.Set("reference", r.Reference)
+	}
+	if r.Search != "" {
+		q.Set("q", r.Search)
+	}
+	for k, v := range r.Metadata {
+		q.Add("meta", k+":"+v)
+	}
+	if r.GroupID != 0 {
+		q.Set("group", strconv.Itoa(r.GroupID))
+	}
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func encodeGetAllAccountsRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(GetAllAccountsRequest)
+	q := req.URL.Query()
+	if r.OwnerID != "" {
+		q.Set("owner", r.OwnerID)
+	}
+	for _, l := range r.Labels {
+		q.Add("label", l)
+	}
+	if r.ConvertTo != "" {
+		q.Set("convert_to", r.ConvertTo)
+	}
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// encodePatchAccountRequest adds the account ID to the path and encodes the body
+func encodePatchAccountRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	r := request.(PatchAccountRequest)
+	req.URL.Path += "/" + r.ID
+	return encodeRequest(ctx, req, request)
+}
+
+func encodeGetInterestAccrualsRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(GetInterestAccrualsRequest)
+	req.URL.Path += "/" + r.AccountID + "/interest"
+	return nil
+}
+
+func encodeGetWalletRequest(_ context.Context, req *http.Request, request interface{}) error {
+	r := request.(GetWalletRequest)
+	req.URL.Path += "/" + r.ID
+	if r.TotalCurrency != "" {
+		q := req.URL.Query()
+		q.Set("total", r.TotalCurrency)
+		req.URL.RawQuery = q.Encode()
+	}
+	return nil
+}
+
+func encodeConvertFundsRequest(ctx context.Context, req *http.Request, request interface{}) error {
+	r := request.(ConvertFundsRequest)
+	req.URL.Path += "/" + r.WalletID + "/convert"
+	return encodeRequest(ctx, req, request)
+}
+
+func decodeGetAllPaymentsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res GetAllPaymentsResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func decodeGetAllAccountsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res GetAllAccountsResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func decodePaymentResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res Payment
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func decodePaymentGroupResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res PaymentGroup
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func decodeAccountResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res Account
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func decodeWalletResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res Wallet
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func decodeTrialBalanceResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res TrialBalance
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func decodeGetInterestAccrualsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+	var res GetInterestAccrualsResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// remoteErrors are errors that can be restored from the error response details
+var remoteErrors = []error{
+	ErrAccountNotFound,
+	ErrCurrencyMismatch,
+	ErrInsufficientFunds,
+	ErrDuplicateReference,
+	ErrIdempotencyKeyReused,
+	ErrNoExchangeRate,
+	model.ErrConflict,
+}
+
+// decodeError converts an error response into the ErrHTTPStatus error.
+//
+// If the details contain a known error, it will be wrapped, so the caller can check it with xerrors.Is.
+// Invalid request fields are wrapped as ValidationError
+func decodeError(r *http.Response) error {
+	var errResp ErrorResponse
+	if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil || errResp.Error.Text == "" {
+		// the response didn't come from the service, e.g. from a proxy
+		return NewErrHTTPStatusf(r.StatusCode, nil, http.StatusText(r.StatusCode))
+	}
+
+	var wrapped error
+	if len(errResp.Error.Fields) > 0 {
+		return NewErrHTTPStatusf(r.StatusCode, ValidationError(errResp.Error.Fields), "%s", errResp.Error.Text)
+	}
+details:
+	for _, d := range errResp.Error.Details {
+		for _, e := range remoteErrors {
+			if d == e.Error() {
+				wrapped = e
+				break details
+			}
+		}
+	}
+	if wrapped == nil && len(errResp.Error.Details) > 0 {
+		wrapped = errors.New(errResp.Error.Details[0])
+	}
+	return NewErrHTTPStatusf(r.StatusCode, wrapped, "%s", errResp.Error.Text)
+}
+
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	if err == nil {
 		panic("encodeError with nil error")
@@ -143,12 +586,16 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 		errResp["details"] = errDescr
 	}
 
+	// list invalid fields
+	var fields ValidationError
+	xerrors.As(err, &fields)
+
 	// process response data
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(&ErrorResponse{
 		Code:  code,
-		Error: ErrorResponseMessage{err.Error(), errDescr},
+		Error: ErrorResponseMessage{err.Error(), errDescr, fields},
 	})
 }
 
@@ -172,7 +619,8 @@ type (
 	}
 	// ErrorResponseMessage is an error message and details
 	ErrorResponseMessage struct {
-		Text    string   `json:"text"`
-		Details []string `json:"details,omitempty"`
+		Text    string       `json:"text"`
+		Details []string     `json:"details,omitempty"`
+		Fields  []FieldError `json:"fields,omitempty"`
 	}
 )
@@ -0,0 +1,297 @@
+package wallet
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/ilyakaznacheev/tiny-wallet/pb"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// paymentEventsBuffer is a number of payment events kept for a single stream
+const paymentEventsBuffer = 64
+
+// GRPCOption sets an optional parameter of the gRPC server
+type GRPCOption func(*grpcOptions)
+
+// grpcOptions is a set of optional gRPC server parameters
+type grpcOptions struct {
+	events *PaymentEvents
+}
+
+// WithPaymentEvents enables payment event streaming from the broker
+func WithPaymentEvents(e *PaymentEvents) GRPCOption {
+	return func(o *grpcOptions) {
+		o.events = e
+	}
+}
+
+// grpcServer is a gRPC implementation of the wallet service
+type grpcServer struct {
+	pb.UnimplementedWalletServer
+	getAllPayments grpctransport.Handler
+	getAllAccounts grpctransport.Handler
+	postPayment    grpctransport.Handler
+	postAccount    grpctransport.Handler
+	events         *PaymentEvents
+}
+
+// MakeGRPCServer makes the service endpoints available as a gRPC server
+func MakeGRPCServer(e Endpoints, logger log.Logger, opts ...GRPCOption) pb.WalletServer {
+	var o grpcOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	options := []grpctransport.ServerOption{
+		grpctransport.ServerErrorLogger(logger),
+	}
+
+	return &grpcServer{
+		getAllPayments: grpctransport.NewServer(
+			e.GetAllPaymentsEndpoint,
+			decodeGRPCGetAllPaymentsRequest,
+			encodeGRPCGetAllPaymentsResponse,
+			options...,
+		),
+		getAllAccounts: grpctransport.NewServer(
+			e.GetAllAccountsEndpoint,
+			decodeGRPCGetAllAccountsRequest,
+			encodeGRPCGetAllAccountsResponse,
+			options...,
+		),
+		postPayment: grpctransport.NewServer(
+			e.PostPayment,
+			decodeGRPCPostPaymentRequest,
+			encodeGRPCPaymentResponse,
+			options...,
+		),
+		postAccount: grpctransport.NewServer(
+			e.PostAccount,
+			decodeGRPCPostAccountRequest,
+			encodeGRPCAccountResponse,
+			options...,
+		),
+		events: o.events,
+	}
+}
+
+// GetAllPayments returns all payments in the system
+func (s *grpcServer) GetAllPayments(ctx context.Context, req *pb.GetAllPaymentsRequest) (*pb.GetAllPaymentsResponse, error) {
+	_, resp, err := s.getAllPayments.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return resp.(*pb.GetAllPaymentsResponse), nil
+}
+
+// GetAllAccounts returns all accounts in the system
+func (s *grpcServer) GetAllAccounts(ctx context.Context, req *pb.GetAllAccountsRequest) (*pb.GetAllAccountsResponse, error) {
+	_, resp, err := s.getAllAccounts.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return resp.(*pb.GetAllAccountsResponse), nil
+}
+
+// PostPayment processes a new payment
+func (s *grpcServer) PostPayment(ctx context.Context, req *pb.PostPaymentRequest) (*pb.Payment, error) {
+	_, resp, err := s.postPayment.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return resp.(*pb.Payment), nil
+}
+
+// PostAccount creates a new account
+func (s *grpcServer) PostAccount(ctx context.Context, req *pb.PostAccountRequest) (*pb.Account, error) {
+	_, resp, err := s.postAccount.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return resp.(*pb.Account), nil
+}
+
+// StreamPayments sends new payments to the client until the call is cancelled or the broker is closed
+func (s *grpcServer) StreamPayments(req *pb.StreamPaymentsRequest, stream pb.Wallet_StreamPaymentsServer) error {
+	if s.events == nil {
+		return status.Error(codes.Unimplemented, "payment events are disabled")
+	}
+
+	payments, cancel := s.events.Subscribe(paymentEventsBuffer)
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+
+		case p, ok := <-payments:
+			if !ok {
+				return status.Error(codes.Unavailable, "service is shutting down")
+			}
+			if req.AccountId != "" && p.AccFromID != req.AccountId && p.AccToID != req.AccountId {
+				continue
+			}
+			err := stream.Send(&pb.PaymentEvent{
+				Payment: encodePBPayment(makePayment(p)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func decodeGRPCGetAllPaymentsRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetAllPaymentsRequest)
+	return GetAllPaymentsRequest{
+		Reference: req.Reference,
+		Search:    req.Search,
+		Metadata:  req.Metadata,
+	}, nil
+}
+
+func decodeGRPCGetAllAccountsRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.GetAllAccountsRequest)
+	return GetAllAccountsRequest{
+		OwnerID: req.OwnerId,
+		Labels:  req.Labels,
+	}, nil
+}
+
+func decodeGRPCPostPaymentRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.PostPaymentRequest)
+	return PostPaymentRequest{
+		AccountFromID: req.AccountFrom,
+		AccountToID:   req.AccountTo,
+		Amount:        req.Amount,
+		Reference:     req.Reference,
+		Description:   req.Description,
+		Metadata:      req.Metadata,
+	}, nil
+}
+
+func decodeGRPCPostAccountRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.PostAccountRequest)
+	return PostAccountRequest{
+		ID:          req.Id,
+		Balance:     req.Balance,
+		Currency:    req.Currency,
+		OwnerID:     req.OwnerId,
+		DisplayName: req.DisplayName,
+		Labels:      req.Labels,
+		Metadata:    req.Metadata,
+		Type:        req.Type,
+	}, nil
+}
+
+func encodeGRPCGetAllPaymentsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(GetAllPaymentsResponse)
+	res := &pb.GetAllPaymentsResponse{
+		Payments: make([]*pb.Payment, 0, len(resp.Payments)),
+	}
+	for _, p := range resp.Payments {
+		res.Payments = append(res.Payments, encodePBPayment(p))
+	}
+	return res, nil
+}
+
+func encodeGRPCGetAllAccountsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(GetAllAccountsResponse)
+	res := &pb.GetAllAccountsResponse{
+		Accounts: make([]*pb.Account, 0, len(resp.Accounts)),
+	}
+	for _, a := range resp.Accounts {
+		res.Accounts = append(res.Accounts, encodePBAccount(a))
+	}
+	return res, nil
+}
+
+func encodeGRPCPaymentResponse(_ context.Context, response interface{}) (interface{}, error) {
+	return encodePBPayment(*response.(*Payment)), nil
+}
+
+func encodeGRPCAccountResponse(_ context.Context, response interface{}) (interface{}, error) {
+	return encodePBAccount(*response.(*Account)), nil
+}
+
+func encodePBPayment(p Payment) *pb.Payment {
+	return &pb.Payment{
+		AccountFrom: p.AccFromID,
+		AccountTo:   p.AccToID,
+		Time:        timestamppb.New(p.DateTime),
+		Amount:      p.Amount,
+		Currency:    string(p.Currency),
+		Reference:   p.Reference,
+		Description: p.Description,
+		Metadata:    p.Metadata,
+		ToAmount:    p.ToAmount,
+		ToCurrency:  string(p.ToCurrency),
+		GroupId:     int64(p.GroupID),
+	}
+}
+
+func encodePBAccount(a Account) *pb.Account {
+	return &pb.Account{
+		Id:          a.ID,
+		Balance:     a.Balance,
+		Currency:    string(a.Currency),
+		OwnerId:     a.OwnerID,
+		DisplayName: a.DisplayName,
+		Labels:      a.Labels,
+		Metadata:    a.Metadata,
+		Type:        a.Type,
+	}
+}
+
+// encodeGRPCError converts a service error into a gRPC status error
+func encodeGRPCError(err error) error {
+	code := codes.Internal
+	var httpErr HTTPError
+	if xerrors.As(err, &httpErr) {
+		code = grpcCode(err, httpErr.Code())
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcCode returns a gRPC status code corresponding to the error and its HTTP status code
+func grpcCode(err error, httpCode int) codes.Code {
+	switch {
+	case xerrors.Is(err, model.ErrConflict):
+		return codes.Aborted
+	case xerrors.Is(err, ErrInsufficientFunds), xerrors.Is(err, ErrCurrencyMismatch):
+		return codes.FailedPrecondition
+	}
+
+	switch httpCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,158 @@
+package wallet
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/pb"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCClient starts a gRPC server of the service on an in-memory connection
+func newTestGRPCClient(t *testing.T, s Service, events *PaymentEvents) pb.WalletClient {
+	ln := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	pb.RegisterWalletServer(srv, MakeGRPCServer(MakeServerEndpoints(s), log.NewNopLogger(), WithPaymentEvents(events)))
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return ln.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewWalletClient(conn)
+}
+
+func TestGRPCServer(t *testing.T) {
+	now := time.Now()
+	db := &TestDatabase{
+		GetAllAccountsData: testDatabaseData{
+			dat: []model.Account{{ID: "1", Balance: currency.NewAmount(12345), Currency: currency.USD}},
+		},
+		GetAllPaymentsData: testDatabaseData{
+			dat: []model.Payment{},
+			err: sql.ErrNoRows,
+		},
+		GetAccountData: map[string]testDatabaseData{
+			"1": {dat: &model.Account{ID: "1", LastUpdate: &now, Balance: currency.NewAmount(12345), Currency: currency.USD}},
+			"2": {dat: &model.Account{ID: "2", LastUpdate: &now, Balance: currency.NewAmount(0), Currency: currency.USD}},
+		},
+		CreatePaymentData: testDatabaseData{
+			dat: &model.Payment{ID: 1, AccFromID: "1", AccToID: "2", DateTime: now, Amount: currency.NewAmount(1050)},
+		},
+	}
+	events := NewPaymentEvents()
+	c := newTestGRPCClient(t, NewEventsService(NewWalletService(db), events), events)
+	ctx := context.Background()
+
+	accounts, err := c.GetAllAccounts(ctx, &pb.GetAllAccountsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts.Accounts) != 1 || accounts.Accounts[0].Balance != 123.45 || accounts.Accounts[0].Currency != "USD" {
+		t.Errorf("wrong accounts %v", accounts.Accounts)
+	}
+
+	_, err = c.GetAllPayments(ctx, &pb.GetAllPaymentsRequest{})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("wrong status code %v, want %v", got, codes.NotFound)
+	}
+
+	_, err = c.PostPayment(ctx, &pb.PostPaymentRequest{AccountFrom: "2", AccountTo: "1", Amount: 1})
+	if got := status.Code(err); got != codes.FailedPrecondition {
+		t.Errorf("wrong status code %v, want %v", got, codes.FailedPrecondition)
+	}
+
+	// subscribe to payments of the account and wait until the server is subscribed
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.StreamPayments(streamCtx, &pb.StreamPaymentsRequest{AccountId: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for subscribers(events) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	p, err := c.PostPayment(ctx, &pb.PostPaymentRequest{AccountFrom: "1", AccountTo: "2", Amount: 10.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Amount != 10.5 || p.Currency != "USD" || !p.Time.AsTime().Equal(now) {
+		t.Errorf("wrong payment %v", p)
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment.AccountTo != "2" || event.Payment.Amount != 10.5 {
+		t.Errorf("wrong payment event %v", event.Payment)
+	}
+
+	// the stream ends when the service shuts down
+	events.Close(ctx)
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("wrong stream error %v, want %v", err, codes.Unavailable)
+	}
+}
+
+func subscribers(e *PaymentEvents) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.subs)
+}
+
+func TestGRPCCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"not found", NewErrHTTPStatusf(http.StatusNotFound, ErrAccountNotFound, "account 1 not found"), codes.NotFound},
+		{"insufficient funds", NewErrHTTPStatusf(http.StatusBadRequest, ErrInsufficientFunds, "account 1 has not enough money"), codes.FailedPrecondition},
+		{"bad request", NewErrHTTPStatusf(http.StatusBadRequest, nil, "can't process account creation"), codes.InvalidArgument},
+		{"concurrent payment", NewErrHTTPStatusf(http.StatusConflict, model.ErrConflict, "please retry"), codes.Aborted},
+		{"account exists", NewErrHTTPStatusf(http.StatusConflict, nil, "account 1 already exists"), codes.AlreadyExists},
+		{"internal", NewErrHTTPStatusf(http.StatusInternalServerError, nil, "unexpected error"), codes.Internal},
+		{"unknown error", sql.ErrConnDone, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(encodeGRPCError(tt.err)); got != tt.want {
+				t.Errorf("wrong code %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodePBPayment(t *testing.T) {
+	p := encodePBPayment(Payment{
+		AccFromID:  "alice/USD",
+		AccToID:    "alice/EUR",
+		Amount:     10,
+		Currency:   currency.USD,
+		ToAmount:   9.2,
+		ToCurrency: currency.EUR,
+		GroupID:    7,
+	})
+	if p.ToAmount != 9.2 || p.ToCurrency != "EUR" || p.GroupId != 7 {
+		t.Errorf("wrong payment %v", p)
+	}
+}
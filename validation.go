@@ -0,0 +1,214 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// accountIDChars are characters allowed in account IDs besides latin letters and digits.
+//
+// The slash separates a wallet ID and a currency in pocket IDs
+const accountIDChars = "-_./"
+
+// FieldError is an error of a single request field
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// ValidationError is a list of invalid request fields.
+//
+// It is wrapped into the ErrHTTPStatus returned by the service, so the caller can get the fields with xerrors.As
+type ValidationError []FieldError
+
+// Error returns all field errors in one line
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, f := range e {
+		msgs = append(msgs, f.Field+": "+f.Error)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add adds an error of the field
+func (e *ValidationError) add(field, format string, a ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Error: fmt.Sprintf(format, a...)})
+}
+
+// err returns the list as an error, or nil if all fields are valid
+func (e ValidationError) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validateAccountID checks that the ID fits into the database and has only allowed characters
+func validateAccountID(id string) error {
+	if id == "" {
+		return errors.New("empty account id")
+	}
+	if len(id) > maxAccountIDLength {
+		return fmt.Errorf("account id is longer than %d characters", maxAccountIDLength)
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(accountIDChars, r)) {
+			return fmt.Errorf("account id has invalid character %q, allowed are latin letters, digits and %q", r, accountIDChars)
+		}
+	}
+	return nil
+}
+
+// validateNewAccountID checks the ID of a new account.
+//
+// The slash is reserved for pocket IDs, so a plain account can't take the ID of a wallet pocket
+func validateNewAccountID(id string) error {
+	if err := validateAccountID(id); err != nil {
+		return err
+	}
+	if strings.Contains(id, "/") {
+		return errors.New("account id has invalid character '/', it separates the wallet id and the currency in pocket ids")
+	}
+	return nil
+}
+
+// isFinite checks that the number is neither NaN nor infinity
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// validate checks the payment request fields
+func (r PostPaymentRequest) validate() error {
+	var errs ValidationError
+	if err := validateAccountID(r.AccountFromID); err != nil {
+		errs.add("account-from", "%v", err)
+	}
+	if err := validateAccountID(r.AccountToID); err != nil {
+		errs.add("account-to", "%v", err)
+	} else if r.AccountToID == r.AccountFromID {
+		errs.add("account-to", "payer and receiver are the same account")
+	}
+	switch {
+	case !isFinite(r.Amount):
+		errs.add("amount", "amount %v is not a finite number", r.Amount)
+	case r.Amount <= 0:
+		errs.add("amount", "amount %v should be positive", r.Amount)
+	}
+	return errs.err()
+}
+
+// validate checks the split payment request fields
+func (r PostSplitPaymentRequest) validate() error {
+	var errs ValidationError
+	if err := validateAccountID(r.AccountFromID); err != nil {
+		errs.add("account-from", "%v", err)
+	}
+	switch {
+	case !isFinite(r.Amount):
+		errs.add("amount", "amount %v is not a finite number", r.Amount)
+	case r.Amount <= 0:
+		errs.add("amount", "amount %v should be positive", r.Amount)
+	}
+	switch {
+	case len(r.Receivers) == 0:
+		errs.add("receivers", "empty receiver list")
+	case len(r.Receivers) > maxSplitReceivers:
+		errs.add("receivers", "more than %d receivers", maxSplitReceivers)
+	}
+
+	seen := make(map[string]bool, len(r.Receivers))
+	for i, rec := range r.Receivers {
+		field := fmt.Sprintf("receivers[%d]", i)
+		switch err := validateAccountID(rec.AccountID); {
+		case err != nil:
+			errs.add(field+".account", "%v", err)
+		case rec.AccountID == r.AccountFromID:
+			errs.add(field+".account", "payer can't receive its own split payment")
+		case seen[rec.AccountID]:
+			errs.add(field+".account", "account %s is a receiver more than once", rec.AccountID)
+		}
+		seen[rec.AccountID] = true
+
+		switch {
+		case !isFinite(rec.Amount):
+			errs.add(field+".amount", "amount %v is not a finite number", rec.Amount)
+		case rec.Amount < 0:
+			errs.add(field+".amount", "amount %v is negative", rec.Amount)
+		case !isFinite(rec.Percent) || rec.Percent < 0 || rec.Percent > 100:
+			errs.add(field+".percent", "percentage %v should be from 0 to 100", rec.Percent)
+		case (rec.Amount > 0) == (rec.Percent > 0):
+			errs.add(field, "receiver should have either a positive amount or a percentage")
+		}
+	}
+	return errs.err()
+}
+
+// validate checks the account request fields
+func (r PostAccountRequest) validate() error {
+	var errs ValidationError
+	if err := validateNewAccountID(r.ID); err != nil {
+		errs.add("id", "%v", err)
+	}
+	if r.Currency == "" {
+		errs.add("currency", "empty currency")
+	}
+	switch {
+	case !isFinite(r.Balance):
+		errs.add("balance", "balance %v is not a finite number", r.Balance)
+	case r.Balance < 0:
+		errs.add("balance", "balance %v is negative", r.Balance)
+	}
+	return errs.err()
+}
+
+// validateWallet checks the wallet ID and currencies of a new wallet
+func validateWallet(id string, currencies []string) error {
+	var errs ValidationError
+	switch err := validateAccountID(id); {
+	case err != nil:
+		errs.add("id", "%v", err)
+	case strings.Contains(id, "/"):
+		errs.add("id", "wallet id has invalid character '/', it separates the wallet id and the currency in pocket ids")
+	case len(id) > maxWalletIDLength:
+		errs.add("id", "wallet id is longer than %d characters", maxWalletIDLength)
+	}
+	if len(currencies) == 0 {
+		errs.add("currencies", "empty currency list")
+	}
+	return errs.err()
+}
+
+// decodeJSONBody strictly decodes a JSON request body, unknown fields are rejected.
+//
+// A malformed body results in 400 Status Code with the invalid field, if it is known
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return NewErrHTTPStatusf(http.StatusBadRequest, jsonFieldError(err), "invalid request body")
+	}
+	if dec.More() {
+		return NewErrHTTPStatusf(http.StatusBadRequest, nil, "invalid request body: unexpected data after the JSON object")
+	}
+	return nil
+}
+
+// jsonFieldError converts a JSON decoding error into a field error if the field is known
+func jsonFieldError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if xerrors.As(err, &typeErr) && typeErr.Field != "" {
+		return ValidationError{{Field: typeErr.Field, Error: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}}
+	}
+	// the decoder doesn't have a typed error for unknown fields
+	const unknownPrefix = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknownPrefix) {
+		return ValidationError{{Field: strings.Trim(strings.TrimPrefix(msg, unknownPrefix), `"`), Error: "unknown field"}}
+	}
+	return err
+}
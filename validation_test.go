@@ -0,0 +1,350 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/currency"
+	"github.com/ilyakaznacheev/tiny-wallet/pkg/model"
+	"golang.org/x/xerrors"
+)
+
+func TestValidateAccountID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"alice", false},
+		{"alice-savings_2.old", false},
+		{"alice/EUR", false},
+		{strings.Repeat("a", 30), false},
+		{"", true},
+		{strings.Repeat("a", 31), true},
+		{"alice smith", true},
+		{"@fx/EUR", true},
+		{"älice", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if err := validateAccountID(tt.id); (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestPostPaymentValidation(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantFields []string
+	}{
+		{
+			name:     "valid",
+			body:     `{"account-from":"alice","account-to":"bob","amount":1.5,"reference":"order-1"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"account-from":"alice","account-to":"bob","amount":1.5,"ammount":2}`,
+			wantCode:   http.StatusBadRequest,
+			wantFields: []string{"ammount"},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"account-from":"alice","account-to":"bob","amount":"1.5"}`,
+			wantCode:   http.StatusBadRequest,
+			wantFields: []string{"amount"},
+		},
+		{
+			name:     "malformed json",
+			body:     `{"account-from":"alice",`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "trailing data",
+			body:     `{"account-from":"alice","account-to":"bob","amount":1.5} {}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:       "empty",
+			body:       `{}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"account-from", "account-to", "amount"},
+		},
+		{
+			name:       "invalid ids",
+			body:       `{"account-from":"alice smith","account-to":"` + strings.Repeat("b", 31) + `","amount":1}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"account-from", "account-to"},
+		},
+		{
+			name:       "self-payment",
+			body:       `{"account-from":"alice","account-to":"alice","amount":1}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"account-to"},
+		},
+		{
+			name:       "negative amount",
+			body:       `{"account-from":"alice","account-to":"bob","amount":-1}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"amount"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{
+					"alice": {dat: &model.Account{ID: "alice", LastUpdate: &now, Balance: currency.NewAmount(1000), Currency: currency.USD}},
+					"bob":   {dat: &model.Account{ID: "bob", LastUpdate: &now, Balance: currency.NewAmount(0), Currency: currency.USD}},
+				},
+				CreatePaymentData: testDatabaseData{dat: &model.Payment{ID: 1}},
+			}
+			h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/api/payment", strings.NewReader(tt.body)))
+
+			checkValidationResponse(t, w, tt.wantCode, tt.wantFields)
+		})
+	}
+}
+
+func TestPostSplitPaymentValidation(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantFields []string
+	}{
+		{
+			name:     "valid",
+			body:     `{"account-from":"alice","amount":10,"receivers":[{"account":"bob","amount":2.5},{"account":"carol","percent":100}]}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"account-from":"alice","amount":10,"receivers":[{"account":"bob","share":10}]}`,
+			wantCode:   http.StatusBadRequest,
+			wantFields: []string{"share"},
+		},
+		{
+			name:     "trailing data",
+			body:     `{"account-from":"alice","amount":10,"receivers":[{"account":"bob","amount":10}]} {}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:       "empty",
+			body:       `{}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"account-from", "amount", "receivers"},
+		},
+		{
+			name: "invalid receivers",
+			body: `{"account-from":"alice","amount":10,"receivers":[` +
+				`{"account":"bob smith","amount":1},` +
+				`{"account":"alice","amount":1},` +
+				`{"account":"bob","amount":-1},` +
+				`{"account":"carol","percent":101},` +
+				`{"account":"dave","amount":1,"percent":50},` +
+				`{"account":"bob","amount":1}]}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantFields: []string{
+				"receivers[0].account",
+				"receivers[1].account",
+				"receivers[2].amount",
+				"receivers[3].percent",
+				"receivers[4]",
+				"receivers[5].account",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				GetAccountData: map[string]testDatabaseData{
+					"alice": {dat: &model.Account{ID: "alice", LastUpdate: &now, Balance: currency.NewAmount(1000), Currency: currency.USD}},
+					"bob":   {dat: &model.Account{ID: "bob", LastUpdate: &now, Balance: currency.NewAmount(0), Currency: currency.USD}},
+					"carol": {dat: &model.Account{ID: "carol", LastUpdate: &now, Balance: currency.NewAmount(0), Currency: currency.USD}},
+				},
+			}
+			h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/api/payments/split", strings.NewReader(tt.body)))
+
+			checkValidationResponse(t, w, tt.wantCode, tt.wantFields)
+		})
+	}
+}
+
+func TestPostAccountValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantFields []string
+	}{
+		{
+			name:     "valid",
+			body:     `{"id":"alice","currency":"USD"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"id":"alice","currency":"USD","owner":"customer-1"}`,
+			wantCode:   http.StatusBadRequest,
+			wantFields: []string{"owner"},
+		},
+		{
+			name:       "empty",
+			body:       `{}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"id", "currency"},
+		},
+		{
+			name:       "pocket id",
+			body:       `{"id":"bob/USD","currency":"USD"}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"id"},
+		},
+		{
+			name:       "invalid id and negative balance",
+			body:       `{"id":"@suspense/USD","currency":"USD","balance":-10}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"id", "balance"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &TestDatabase{
+				CreateAccountData: testDatabaseData{dat: &model.Account{ID: "alice", Currency: currency.USD}},
+			}
+			h := MakeHTTPHandler(NewWalletService(db), log.NewNopLogger())
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/api/account", strings.NewReader(tt.body)))
+
+			checkValidationResponse(t, w, tt.wantCode, tt.wantFields)
+		})
+	}
+}
+
+func TestPostWalletValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantFields []string
+	}{
+		{
+			name:     "valid",
+			body:     `{"id":"alice","currencies":["USD"]}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "empty",
+			body:       `{}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"id", "currencies"},
+		},
+		{
+			name:       "invalid character in id",
+			body:       `{"id":"@alice","currencies":["USD"]}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"id"},
+		},
+		{
+			name:       "slash in id",
+			body:       `{"id":"alice/bob","currencies":["USD"]}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := MakeHTTPHandler(NewWalletService(&TestDatabase{}), log.NewNopLogger())
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/api/wallet", strings.NewReader(tt.body)))
+
+			checkValidationResponse(t, w, tt.wantCode, tt.wantFields)
+		})
+	}
+}
+
+// checkValidationResponse checks the status code and the invalid fields of the response
+func checkValidationResponse(t *testing.T, w *httptest.ResponseRecorder, wantCode int, wantFields []string) {
+	t.Helper()
+	if w.Code != wantCode {
+		t.Fatalf("wrong status code %d, want %d: %s", w.Code, wantCode, w.Body)
+	}
+	if wantCode < http.StatusBadRequest {
+		return
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, f := range resp.Error.Fields {
+		fields = append(fields, f.Field)
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("wrong invalid fields %v, want %v", fields, wantFields)
+	}
+}
+
+func TestServicePostPaymentInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   string
+		to     string
+		amount float64
+	}{
+		{"self-payment", "alice", "alice", 1},
+		{"zero amount", "alice", "bob", 0},
+		{"negative amount", "alice", "bob", -1},
+		{"NaN amount", "alice", "bob", math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWalletService(&TestDatabase{})
+			_, err := s.PostPayment(context.Background(), tt.from, tt.to, tt.amount, model.PaymentInfo{})
+			if code := errorCode(err); code != http.StatusUnprocessableEntity {
+				t.Errorf("wrong status code %v, want %v (%v)", code, http.StatusUnprocessableEntity, err)
+			}
+		})
+	}
+}
+
+func TestDecodeValidationError(t *testing.T) {
+	w := httptest.NewRecorder()
+	encodeError(context.Background(), NewErrHTTPStatusf(http.StatusUnprocessableEntity, ValidationError{
+		{Field: "amount", Error: "amount -1 should be positive"},
+	}, "invalid payment request"), w)
+
+	err := decodeError(w.Result())
+	var fields ValidationError
+	if !xerrors.As(err, &fields) {
+		t.Fatalf("error %v doesn't wrap validation error", err)
+	}
+	want := ValidationError{{Field: "amount", Error: "amount -1 should be positive"}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("wrong fields %v, want %v", fields, want)
+	}
+	if errorCode(err) != http.StatusUnprocessableEntity {
+		t.Errorf("wrong status code %v, want %v", errorCode(err), http.StatusUnprocessableEntity)
+	}
+}